@@ -19,20 +19,33 @@ package configuration
 
 import (
 	"bufio"
+	"encoding/csv"
 	"encoding/json"
 	"os"
 	"path/filepath"
 )
 
+// MinSummariseInterval is the shortest SummariseInterval in milliseconds. Interactions are
+// summarised as soon as they are notified, so the one second interval of older configuration
+// files would only poll the database for nothing.
+const MinSummariseInterval = 10000
+
 type Configuration struct {
 	// User interface parameters.
-	DBUserName        string // The name of the user with read/write privileges on DBName
-	DBPassword        string // The password of the user with read/write privileges on DBName.
-	DBName            string // The name of the database that holds the production data.
-	DBTestName        string // The name of the database that holds testing data.
-	Address           string // The address and port that the scout is accessible on.
-	StaticAssets      string // The path to the static assets rendered by the scout.
-	SummariseInterval int    // The number of milliseconds to wait between updating the interaction summaries.
+	DBUserName         string // The name of the user with read/write privileges on DBName
+	DBPassword         string // The password of the user with read/write privileges on DBName.
+	DBName             string // The name of the database that holds the production data.
+	DBTestName         string // The name of the database that holds testing data.
+	Address            string // The address and port that the scout is accessible on.
+	StaticAssets       string // The path to the static assets rendered by the scout.
+	SummariseInterval  int    // The number of milliseconds between checks for interactions to summarise that weren't notified.
+	CheckpointInterval int    // The number of milliseconds of footage between checkpoints of the scene being measured.
+
+	// Retention parameters, a retention of zero days keeps the measurements forever.
+	InteractionRetention int // The number of days to keep interactions once they have been summarised.
+	HealthRetention      int // The number of days to keep the health heartbeats of the scout.
+	LogRetention         int // The number of days to keep the logs of the scout.
+	PruneInterval        int // The number of milliseconds between removing measurements older than their retention.
 }
 
 func GetDataDir() string {
@@ -49,6 +62,16 @@ func GetDataDir() string {
 	return dir
 }
 
+// DBConnection returns the connection string for the database name.
+func DBConnection(c Configuration, name string) string {
+	connection := "user=" + c.DBUserName + " dbname=" + name
+	if c.DBPassword != "" {
+		connection = connection + " password=" + c.DBPassword
+	}
+
+	return connection
+}
+
 func SaveAsJSON(v interface{}, fileName string) error {
 	f, err := os.Create(fileName)
 	if os.IsNotExist(err) {
@@ -70,8 +93,19 @@ func SaveAsJSON(v interface{}, fileName string) error {
 	return w.Flush()
 }
 
+// SaveAsCSV writes the records to fileName as comma separated values, one line per record.
+func SaveAsCSV(records [][]string, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return csv.NewWriter(f).WriteAll(records)
+}
+
 func Parse(configFile string) (c Configuration, err error) {
-	c = Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 1000}
+	c = Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 60000, 5000, 0, 0, 0, 3600000}
 
 	// Open the configuration file.
 	file, err := os.Open(configFile)
@@ -83,5 +117,9 @@ func Parse(configFile string) (c Configuration, err error) {
 	// Parse JSON in the configuration file.
 	decoder := json.NewDecoder(file)
 	err = decoder.Decode(&c)
+	if c.SummariseInterval < MinSummariseInterval {
+		c.SummariseInterval = MinSummariseInterval
+	}
+
 	return c, err
 }
@@ -20,6 +20,8 @@ package configuration
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -35,6 +37,22 @@ var _ = Describe("Configuration", func() {
 			Ω(err).ShouldNot(BeNil())
 		})
 
+		It("should keep measurements forever when the retention isn't configured", func() {
+			c, err := Parse("../testdata/retention.json")
+			Ω(err).Should(BeNil())
+
+			Ω(c.InteractionRetention).Should(Equal(0))
+			Ω(c.HealthRetention).Should(Equal(0))
+			Ω(c.LogRetention).Should(Equal(0))
+			Ω(c.PruneInterval).Should(Equal(3600000))
+		})
+
+		It("should raise the summarise interval of older config files", func() {
+			c, err := Parse("../testdata/summarise.json")
+			Ω(err).Should(BeNil())
+			Ω(c.SummariseInterval).Should(Equal(MinSummariseInterval))
+		})
+
 		It("should be able to parse a valid config file", func() {
 			c, err := Parse("../scout.json_example")
 			Ω(err).Should(BeNil())
@@ -44,12 +62,27 @@ var _ = Describe("Configuration", func() {
 			Ω(c.DBTestName).Should(Equal("mothership_test"))
 			Ω(c.Address).Should(Equal(":80"))
 			Ω(c.StaticAssets).Should(Equal("public"))
+			Ω(c.CheckpointInterval).Should(Equal(5000))
+			Ω(c.InteractionRetention).Should(Equal(90))
+			Ω(c.HealthRetention).Should(Equal(30))
+			Ω(c.LogRetention).Should(Equal(30))
+			Ω(c.PruneInterval).Should(Equal(3600000))
+		})
+	})
+
+	Context("DBConnection", func() {
+		It("should only include the password when there is one", func() {
+			c := Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 60000, 5000, 90, 30, 30, 3600000}
+			Ω(DBConnection(c, c.DBTestName)).Should(Equal("user=mtf dbname=mothership_test"))
+
+			c.DBPassword = "foo"
+			Ω(DBConnection(c, c.DBName)).Should(Equal("user=mtf dbname=mothership password=foo"))
 		})
 	})
 
 	Context("Saving", func() {
 		It("should be able to save a config file", func() {
-			c := Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 1000}
+			c := Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 60000, 5000, 90, 30, 30, 3600000}
 			SaveAsJSON(c, "../testdata/foo.json")
 
 			a, err := Parse("../scout.json_example")
@@ -58,5 +91,16 @@ var _ = Describe("Configuration", func() {
 			Ω(err).Should(BeNil())
 			Ω(a).Should(Equal(b))
 		})
+
+		It("should be able to save records as CSV", func() {
+			records := [][]string{{"ScoutUUID", "Name"}, {"abc", "Front door, left"}}
+			err := SaveAsCSV(records, "../testdata/foo.csv")
+			Ω(err).Should(BeNil())
+
+			b, err := ioutil.ReadFile("../testdata/foo.csv")
+			Ω(err).Should(BeNil())
+			Ω(string(b)).Should(Equal("ScoutUUID,Name\nabc,\"Front door, left\"\n"))
+			os.Remove("../testdata/foo.csv")
+		})
 	})
 })
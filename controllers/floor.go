@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// getFloorCalibration fetches the floor calibration of the scout identified by :uuid.
+func getFloorCalibration(db *sql.DB, c echo.Context) (*models.FloorCalibration, error) {
+	f, err := models.GetFloorCalibration(db, c.Param("uuid"))
+	if err == sql.ErrNoRows {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "The scout has not been calibrated to the floor")
+	}
+
+	return f, err
+}
+
+func GetFloorCalibration(db *sql.DB, c echo.Context) error {
+	f, err := getFloorCalibration(db, c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, f)
+}
+
+// UpdateFloorCalibration maps four or more points marked on the calibration frame to their
+// positions on the floor, replacing any previous calibration of the scout.
+func UpdateFloorCalibration(db *sql.DB, c echo.Context) error {
+	s, err := models.GetScoutByUUID(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("ERROR: Unable to read floor calibration message")
+		log.Printf("%v", err)
+		return err
+	}
+
+	var nf models.FloorCalibration
+	err = json.Unmarshal(body, &nf)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal JSON.")
+		log.Printf("%v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid floor calibration")
+	}
+
+	f, err := models.NewFloorCalibration(s.UUID, nf.Points)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err = f.Save(db)
+	if err != nil {
+		log.Printf("ERROR: Unable to save floor calibration")
+		log.Printf("%v", err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, f)
+}
+
+func DeleteFloorCalibration(db *sql.DB, c echo.Context) error {
+	f, err := getFloorCalibration(db, c)
+	if err != nil {
+		return err
+	}
+
+	err = f.Delete(db)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetFloorInteractions returns the interactions of a scout with their length and speed in
+// metres, and their entry times rounded to the privacy of the scout.
+func GetFloorInteractions(db *sql.DB, c echo.Context) error {
+	f, err := getFloorCalibration(db, c)
+	if err != nil {
+		return err
+	}
+
+	si, err := models.GetScoutInteractions(db, f.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(db, f.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	result := []models.FloorInteraction{}
+	for _, i := range si {
+		fi := f.Interaction(i)
+		fi.EnteredAt = p.Round(fi.EnteredAt)
+		result = append(result, fi)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetFloorHeatmap returns the summary of a scout per square metre of floor, published under the
+// privacy of the scout.
+func GetFloorHeatmap(db *sql.DB, c echo.Context) error {
+	f, err := getFloorCalibration(db, c)
+	if err != nil {
+		return err
+	}
+
+	ss, err := models.GetScoutSummaryByUUID(db, f.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	fr, err := models.GetScoutFrame(db, f.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(db, f.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	err = ss.Publish(db, p, "floor heatmap")
+	if err == models.ErrBudgetSpent {
+		return echo.NewHTTPError(http.StatusForbidden, "The privacy budget of the scout has been spent")
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, f.Heatmap(ss, fr.Width, fr.Height))
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFloor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Floor controller Suite")
+}
+
+var _ = Describe("Floor controller", func() {
+	AfterEach(cleaner)
+
+	Context("UpdateFloorCalibration", func() {
+		It("should calibrate a scout to the floor", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.PUT, "/scouts/", strings.NewReader(`{"points": [
+				{"pixel": [0, 0], "floor": [0, 0]}, {"pixel": [1000, 0], "floor": [10, 0]},
+				{"pixel": [1000, 500], "floor": [10, 5]}, {"pixel": [0, 500], "floor": [0, 5]}]}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/floor")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = UpdateFloorCalibration(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			f, err := models.GetFloorCalibration(db, s.UUID)
+			Ω(err).Should(BeNil())
+			p, ok := f.Homography.Project(500, 250)
+			Ω(ok).Should(BeTrue())
+			Ω(p[0]).Should(BeNumerically("~", 5.0, 1e-6))
+			Ω(p[1]).Should(BeNumerically("~", 2.5, 1e-6))
+		})
+
+		It("should not calibrate a scout with less than four points", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.PUT, "/scouts/", strings.NewReader(`{"points": [
+				{"pixel": [0, 0], "floor": [0, 0]}, {"pixel": [1000, 0], "floor": [10, 0]}]}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/floor")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = UpdateFloorCalibration(db, c)
+			Ω(err).ShouldNot(BeNil())
+
+			_, err = models.GetFloorCalibration(db, s.UUID)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("GetFloorHeatmap", func() {
+		It("should return an error for a scout that has not been calibrated", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/floor/heatmap")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetFloorHeatmap(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should return the heatmap of a calibrated scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			f, err := models.NewFloorCalibration(s.UUID, models.FloorPoints{
+				models.FloorPoint{[2]float64{0, 0}, [2]float64{0, 0}},
+				models.FloorPoint{[2]float64{1000, 0}, [2]float64{10, 0}},
+				models.FloorPoint{[2]float64{1000, 500}, [2]float64{10, 5}},
+				models.FloorPoint{[2]float64{0, 500}, [2]float64{0, 5}}})
+			Ω(err).Should(BeNil())
+			Ω(f.Save(db)).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/floor/heatmap")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetFloorHeatmap(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var fh models.FloorHeatmap
+			err = json.Unmarshal(rec.Body.Bytes(), &fh)
+			Ω(err).Should(BeNil())
+			Ω(fh.CellArea[0][0]).Should(BeNumerically(">", 0.0))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/processes"
+	"github.com/labstack/echo"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// readHeatmapTime parses t as either an RFC3339 time or a date, which is taken as midnight
+// in the timezone loc.
+func readHeatmapTime(t string, loc *time.Location) (time.Time, error) {
+	result, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		result, err = time.ParseInLocation("2006-01-02", t, loc)
+	}
+
+	return result, err
+}
+
+// readHours parses a comma separated list of hours of the day, each either a single hour
+// ('9') or a range of hours ('9-17' for 9am until 5pm).
+func readHours(hours string) ([]int, error) {
+	var result []int
+
+	for _, h := range strings.Split(hours, ",") {
+		r := strings.SplitN(strings.TrimSpace(h), "-", 2)
+		start, err := strconv.Atoi(r[0])
+		if err != nil {
+			return result, err
+		}
+		end := start + 1
+
+		if len(r) == 2 {
+			end, err = strconv.Atoi(r[1])
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if start < 0 || end > 24 || end <= start {
+			return result, echo.NewHTTPError(http.StatusBadRequest, "Invalid hours")
+		}
+
+		for k := start; k < end; k++ {
+			result = append(result, k)
+		}
+	}
+
+	return result, nil
+}
+
+// readHeatmapQuery parses the tz, from, to, days and hours query parameters. The tz is an
+// IANA timezone name (defaulting to UTC) that days, hours and dates given to from and to are
+// in. Days are a comma separated list of three letter day names ('mon,tue').
+func readHeatmapQuery(c echo.Context) (models.HeatmapQuery, error) {
+	var q models.HeatmapQuery
+	var err error
+
+	q.Location = time.UTC
+	if tz := c.QueryParam("tz"); tz != "" {
+		q.Location, err = time.LoadLocation(tz)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid timezone")
+		}
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		q.From, err = readHeatmapTime(from, q.Location)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid from time")
+		}
+	}
+
+	if to := c.QueryParam("to"); to != "" {
+		q.To, err = readHeatmapTime(to, q.Location)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid to time")
+		}
+	}
+
+	if days := c.QueryParam("days"); days != "" {
+		for _, d := range strings.Split(days, ",") {
+			wd, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]
+			if !ok {
+				return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid days")
+			}
+			q.Days = append(q.Days, wd)
+		}
+	}
+
+	if hours := c.QueryParam("hours"); hours != "" {
+		q.Hours, err = readHours(hours)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid hours")
+		}
+	}
+
+	return q, nil
+}
+
+// GetHeatmap returns the summary of the interactions of a scout over the hours that match the
+// query parameters, published under the privacy of the scout.
+func GetHeatmap(db *sql.DB, c echo.Context) error {
+	q, err := readHeatmapQuery(c)
+	if err != nil {
+		return err
+	}
+
+	h, err := models.GetHeatmap(db, c.Param("uuid"), q)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	err = h.Publish(db, p, "heatmap?"+c.Request().URL.RawQuery)
+	if err == models.ErrBudgetSpent {
+		return echo.NewHTTPError(http.StatusForbidden, "The privacy budget of the scout has been spent")
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, h)
+}
+
+// readGrid parses the w_buckets and h_buckets query parameters, returning zero for those that
+// are missing.
+func readGrid(c echo.Context) (int, int, error) {
+	var result [2]int
+	for k, name := range []string{"w_buckets", "h_buckets"} {
+		v := c.QueryParam(name)
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid "+name)
+		}
+		result[k] = n
+	}
+
+	return result[0], result[1], nil
+}
+
+// Resummarise rebuilds the summaries of a scout from the interactions it has already measured,
+// limited to those that entered between the from and to query parameters when given. The
+// w_buckets and h_buckets query parameters rebuild the summaries on a different grid. The rebuilt
+// summary is shared in the response under the privacy of the scout.
+func Resummarise(db *sql.DB, c echo.Context) error {
+	q, err := readHeatmapQuery(c)
+	if err != nil {
+		return err
+	}
+
+	w, h, err := readGrid(c)
+	if err != nil {
+		return err
+	}
+
+	ss, n, err := processes.Resummarise(db, c.Param("uuid"), q.From, q.To, w, h)
+	if err == processes.ErrInvalidGrid {
+		return echo.NewHTTPError(http.StatusBadRequest, "The grid doesn't fit the frame of the scout")
+	} else if err == processes.ErrPruned {
+		return echo.NewHTTPError(http.StatusConflict, "The grid can't change once interactions have been pruned")
+	} else if err != nil {
+		log.Printf("ERROR: Unable to resummarise")
+		log.Printf("%v", err)
+		return err
+	}
+	log.Printf("INFO: Resummarised %d interactions", n)
+
+	p, err := models.GetScoutPrivacy(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, shareSummary(ss, p))
+}
@@ -0,0 +1,263 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatmap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Heatmap controller Suite")
+}
+
+var _ = Describe("Heatmap controller", func() {
+	AfterEach(cleaner)
+
+	get := func(query string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		req, err := http.NewRequest(echo.GET, "/scouts/?"+query, strings.NewReader(""))
+		Ω(err).Should(BeNil())
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/scouts/:uuid/heatmap")
+		c.SetParamNames("uuid")
+		c.SetParamValues("59ef7180-f6b2-4129-99bf-970eb4312b4b")
+
+		return c, rec
+	}
+
+	Context("readHours", func() {
+		It("should read single hours and ranges of hours", func() {
+			h, err := readHours("7,9-12")
+			Ω(err).Should(BeNil())
+			Ω(h).Should(Equal([]int{7, 9, 10, 11}))
+		})
+
+		It("should reject hours outside of the day", func() {
+			_, err := readHours("22-25")
+			Ω(err).ShouldNot(BeNil())
+
+			_, err = readHours("morning")
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("readHeatmapQuery", func() {
+		It("should read dates in the timezone of the query", func() {
+			c, _ := get("tz=Australia/Brisbane&from=2016-05-12&days=mon,Fri&hours=9-17")
+			q, err := readHeatmapQuery(c)
+			Ω(err).Should(BeNil())
+			Ω(q.From.UTC()).Should(Equal(time.Date(2016, 5, 11, 14, 0, 0, 0, time.UTC)))
+			Ω(q.Days).Should(Equal([]time.Weekday{time.Monday, time.Friday}))
+			Ω(len(q.Hours)).Should(Equal(8))
+		})
+
+		It("should reject an unknown timezone or day", func() {
+			c, _ := get("tz=Middle/Earth")
+			_, err := readHeatmapQuery(c)
+			Ω(err).ShouldNot(BeNil())
+
+			c, _ = get("days=someday")
+			_, err = readHeatmapQuery(c)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("GetHeatmap", func() {
+		It("should return the heatmap of the requested hours", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			for k := 0; k < 3; k++ {
+				hs := models.HourlySummary{*models.NewScoutSummary(s.UUID, s.Frame), t.Add(time.Duration(k) * time.Hour)}
+				hs.VisitorCount = 1
+				err = hs.Save(db)
+				Ω(err).Should(BeNil())
+			}
+
+			c, rec := get("from=2016-05-12T11:00:00Z")
+			err = GetHeatmap(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var h models.ScoutSummary
+			err = json.Unmarshal(rec.Body.Bytes(), &h)
+			Ω(err).Should(BeNil())
+			Ω(h.VisitorCount).Should(Equal(int64(2)))
+		})
+
+		It("should suppress buckets of fewer visitors than the privacy of the scout allows", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{3, 15, 0.0, 0.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			for k := 0; k < 3; k++ {
+				hs := models.HourlySummary{*models.NewScoutSummary(s.UUID, s.Frame), t.Add(time.Duration(k) * time.Hour)}
+				hs.VisitorCount = 1
+				hs.VisitorBuckets[4][5] = 1
+				hs.VisitTimeBuckets[4][5] = 2.0
+				if k > 0 {
+					hs.VisitorBuckets[2][3] = 1
+					hs.VisitTimeBuckets[2][3] = 1.0
+				}
+				err = hs.Save(db)
+				Ω(err).Should(BeNil())
+			}
+
+			c, rec := get("")
+			err = GetHeatmap(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var h models.ScoutSummary
+			err = json.Unmarshal(rec.Body.Bytes(), &h)
+			Ω(err).Should(BeNil())
+			Ω(h.VisitorCount).Should(Equal(int64(3)))
+			Ω(h.VisitorBuckets[4][5]).Should(Equal(3))
+			Ω(h.VisitTimeBuckets[4][5]).Should(Equal(float32(6.0)))
+			Ω(h.VisitorBuckets[2][3]).Should(Equal(0))
+			Ω(h.VisitTimeBuckets[2][3]).Should(Equal(float32(0.0)))
+		})
+
+		It("should refuse to publish once the privacy budget is spent", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{0, 15, 0.5, 1.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			// Each visitor changes the heatmap, so that every request is a fresh release.
+			hs := models.HourlySummary{*models.NewScoutSummary(s.UUID, s.Frame), time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)}
+			for k := 0; k < 2; k++ {
+				hs.VisitorCount++
+				err = hs.Save(db)
+				Ω(err).Should(BeNil())
+
+				c, rec := get("from=2016-05-12T11:00:00Z")
+				err = GetHeatmap(db, c)
+				Ω(err).Should(BeNil())
+				Ω(rec.Code).Should(Equal(200))
+			}
+
+			hs.VisitorCount++
+			err = hs.Save(db)
+			Ω(err).Should(BeNil())
+
+			c, _ := get("from=2016-05-12T11:00:00Z")
+			err = GetHeatmap(db, c)
+			Ω(err).Should(Equal(echo.NewHTTPError(http.StatusForbidden, "The privacy budget of the scout has been spent")))
+
+			sl, err := models.GetPrivacySpends(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(sl)).Should(Equal(2))
+			Ω(sl[0].Release).Should(Equal("heatmap?from=2016-05-12T11:00:00Z"))
+		})
+
+		It("should serve an identical heatmap again without spending the budget twice", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{0, 15, 0.5, 1.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			hs := models.HourlySummary{*models.NewScoutSummary(s.UUID, s.Frame), time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)}
+			hs.VisitorCount = 100
+			err = hs.Save(db)
+			Ω(err).Should(BeNil())
+
+			bodies := []string{}
+			for k := 0; k < 3; k++ {
+				c, rec := get("from=2016-05-12T11:00:00Z")
+				err = GetHeatmap(db, c)
+				Ω(err).Should(BeNil())
+				Ω(rec.Code).Should(Equal(200))
+				bodies = append(bodies, rec.Body.String())
+			}
+
+			Ω(bodies[1]).Should(Equal(bodies[0]))
+			Ω(bodies[2]).Should(Equal(bodies[0]))
+
+			sl, err := models.GetPrivacySpends(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(sl)).Should(Equal(1))
+		})
+	})
+
+	Context("Resummarise", func() {
+		It("should reject an invalid period", func() {
+			c, _ := get("from=last-tuesday")
+			err := Resummarise(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should leave out the rebuilt summary of a scout that adds noise", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{0, 15, 0.5, 1.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			c, rec := get("")
+			err = Resummarise(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+			Ω(strings.TrimSpace(rec.Body.String())).Should(Equal("null"))
+		})
+	})
+})
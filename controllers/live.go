@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	"net/http"
+)
+
+// checkLive makes sure the :uuid parameter of c is the scout being measured.
+func checkLive(live *models.LiveScene, c echo.Context) error {
+	if c.Param("uuid") != live.UUID {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown scout")
+	}
+
+	return nil
+}
+
+func GetLive(live *models.LiveScene, c echo.Context) error {
+	err := checkLive(live, c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, live.Snapshot())
+}
+
+// writeEvent sends the snapshot s to the client as a Server-Sent Event.
+func writeEvent(c echo.Context, s models.LiveSnapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Response(), "data: %s\n\n", b)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Flush()
+	return nil
+}
+
+// StreamLive sends the current snapshot of the scene, followed by every new snapshot as a
+// Server-Sent Event until the client disconnects.
+func StreamLive(live *models.LiveScene, c echo.Context) error {
+	err := checkLive(live, c)
+	if err != nil {
+		return err
+	}
+
+	sub := live.Subscribe()
+	defer live.Unsubscribe(sub)
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	err = writeEvent(c, live.Snapshot())
+	if err != nil {
+		return err
+	}
+
+	done := c.Request().Context().Done()
+	for {
+		select {
+		case s := <-sub:
+			err = writeEvent(c, s)
+			if err != nil {
+				return err
+			}
+
+		case <-done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Live controller Suite")
+}
+
+var _ = Describe("Live controller", func() {
+	s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+		Port: 8080, Authorised: true, Name: "foo", State: "measuring", Summary: &models.ScoutSummary{},
+		MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+		MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1,
+		MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0,
+		MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5,
+		DwellSqDistance: 400, DwellDuration: 5.0, Frame: models.DefaultFrame,
+		Privacy: models.DefaultPrivacy}
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	Context("GetLive", func() {
+		It("should return the latest snapshot of the scene", func() {
+			si := models.InitScene(&s)
+			si.Update(nil, []models.Waypoint{models.Waypoint{100, 100, 20, 20, 0.0}}, t)
+			live := models.NewLiveScene(s.UUID)
+			live.Publish(si, t)
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/live")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetLive(live, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var ls models.LiveSnapshot
+			err = json.Unmarshal(rec.Body.Bytes(), &ls)
+			Ω(err).Should(BeNil())
+			Ω(ls.Measuring).Should(BeTrue())
+			Ω(ls.Active).Should(Equal(1))
+			Ω(ls.Waypoints[0].Waypoint.XPixels).Should(Equal(100))
+		})
+
+		It("should not return the scene of another scout", func() {
+			live := models.NewLiveScene(s.UUID)
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/live")
+			c.SetParamNames("uuid")
+			c.SetParamValues("eeef7180-f6b2-4129-99bf-970eb4312b4b")
+
+			err = GetLive(live, c)
+			Ω(err).Should(Equal(echo.NewHTTPError(http.StatusNotFound, "Unknown scout")))
+		})
+	})
+
+	Context("StreamLive", func() {
+		It("should stream snapshots until the client disconnects", func() {
+			live := models.NewLiveScene(s.UUID)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			req = req.WithContext(ctx)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/live/stream")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			cancel()
+			err = StreamLive(live, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Header().Get(echo.HeaderContentType)).Should(Equal("text/event-stream"))
+			Ω(rec.Body.String()).Should(HavePrefix("data: {\"measuring\":false"))
+		})
+
+		It("should not stream the scene of another scout", func() {
+			live := models.NewLiveScene(s.UUID)
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/live/stream")
+			c.SetParamNames("uuid")
+			c.SetParamValues("eeef7180-f6b2-4129-99bf-970eb4312b4b")
+
+			err = StreamLive(live, c)
+			Ω(err).Should(Equal(echo.NewHTTPError(http.StatusNotFound, "Unknown scout")))
+			Ω(rec.Body.String()).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// readMetricsQuery parses the from, to, entry_edge, exit_edge and min_dwell query parameters.
+// Times are in RFC3339 format.
+func readMetricsQuery(c echo.Context) (models.MetricsQuery, error) {
+	var q models.MetricsQuery
+	var err error
+
+	if from := c.QueryParam("from"); from != "" {
+		q.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid from time")
+		}
+	}
+
+	if to := c.QueryParam("to"); to != "" {
+		q.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid to time")
+		}
+	}
+
+	if minDwell := c.QueryParam("min_dwell"); minDwell != "" {
+		d, err := strconv.ParseFloat(minDwell, 32)
+		if err != nil {
+			return q, echo.NewHTTPError(http.StatusBadRequest, "Invalid minimum dwell")
+		}
+		q.MinDwell = float32(d)
+	}
+
+	q.EntryEdge = models.Edge(c.QueryParam("entry_edge"))
+	q.ExitEdge = models.Edge(c.QueryParam("exit_edge"))
+
+	return q, nil
+}
+
+func GetInteractionMetrics(db *sql.DB, c echo.Context) error {
+	q, err := readMetricsQuery(c)
+	if err != nil {
+		return err
+	}
+
+	m, err := models.GetInteractionMetrics(db, c.Param("uuid"), q)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	if m == nil {
+		m = []*models.InteractionMetrics{}
+	}
+
+	for _, im := range m {
+		im.EnteredAt = p.Round(im.EnteredAt)
+	}
+
+	return c.JSON(http.StatusOK, m)
+}
+
+func GetDwells(db *sql.DB, c echo.Context) error {
+	d, err := models.GetDwells(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, d)
+}
+
+func GetSummariseFailures(db *sql.DB, c echo.Context) error {
+	f, err := models.GetSummariseFailures(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, f)
+}
+
+func GetPrunes(db *sql.DB, c echo.Context) error {
+	p, err := models.GetPrunes(db)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, p)
+}
+
+func GetPrivacySpends(db *sql.DB, c echo.Context) error {
+	s, err := models.GetPrivacySpends(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics controller Suite")
+}
+
+var _ = Describe("Metrics controller", func() {
+	AfterEach(cleaner)
+
+	Context("GetInteractionMetrics", func() {
+		It("should reject an invalid time range", func() {
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/?from=yesterday", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/metrics")
+			c.SetParamNames("uuid")
+			c.SetParamValues("59ef7180-f6b2-4129-99bf-970eb4312b4b")
+
+			err = GetInteractionMetrics(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should return the metrics of the interactions that match the query", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			for k, edge := range []models.Edge{models.LEFT, models.TOP} {
+				si := models.ScoutInteraction{-1, s.UUID, 2.0, models.Path{[2]int{0, 0}, [2]int{10, 10}},
+					models.Path{[2]int{1, 1}, [2]int{1, 1}}, models.RealArray{0.0, 2.0}, true, t.Add(time.Duration(k) * time.Hour)}
+				err = si.Insert(db)
+				Ω(err).Should(BeNil())
+
+				m := models.InteractionMetrics{si.Id, s.UUID, si.EnteredAt, 14.1, 7.0, 7.0, 0.0, [2]int{0, 0},
+					models.Path{[2]int{0, 0}, [2]int{10, 10}}, edge, models.INTERIOR}
+				err = m.Save(db)
+				Ω(err).Should(BeNil())
+			}
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/?entry_edge=top&from=2016-05-12T10:30:00Z", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/metrics")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetInteractionMetrics(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var ml []models.InteractionMetrics
+			err = json.Unmarshal(rec.Body.Bytes(), &ml)
+			Ω(err).Should(BeNil())
+			Ω(len(ml)).Should(Equal(1))
+			Ω(ml[0].EntryEdge).Should(Equal(models.TOP))
+		})
+	})
+})
@@ -22,8 +22,15 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"github.com/MeasureTheFuture/scout/configuration"
 	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/processes"
+	"github.com/MeasureTheFuture/scout/vec"
 	"github.com/labstack/echo"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"io"
 	"io/ioutil"
 	"log"
@@ -32,36 +39,62 @@ import (
 	"path"
 )
 
+// export is one file of the data download.
+type export struct {
+	name string                           // What the file holds, for logging.
+	save func(db *sql.DB) (string, error) // Writes the file, returning where it was written.
+}
+
+// jsonExports are the files of the data download when it is requested as JSON.
+var jsonExports = []export{
+	{"scout healths as JSON", models.ScoutHealthsAsJSON},
+	{"scout interactions as JSON", models.ScoutInteractionsAsJSON},
+	{"scout summaries as JSON", models.ScoutSummariesAsJSON},
+	{"hourly summaries as JSON", models.HourlySummariesAsJSON},
+	{"interaction metrics as JSON", models.InteractionMetricsAsJSON},
+	{"dwells as JSON", models.DwellsAsJSON},
+	{"tripwires as JSON", models.TripwiresAsJSON},
+	{"tripwire counts as JSON", models.TripwireCountsAsJSON},
+	{"floor calibrations as JSON", models.FloorCalibrationsAsJSON},
+	{"floor interactions as JSON", models.FloorInteractionsAsJSON},
+	{"floor heatmaps as JSON", models.FloorHeatmapsAsJSON},
+}
+
+// csvExports are the files of the data download when it is requested as CSV.
+var csvExports = []export{
+	{"scout healths as CSV", models.ScoutHealthsAsCSV},
+	{"scout interactions as CSV", models.ScoutInteractionsAsCSV},
+	{"scout summaries as CSV", models.ScoutSummariesAsCSV},
+	{"hourly summaries as CSV", models.HourlySummariesAsCSV},
+}
+
+// DownloadData zips up the measurements of every scout, as JSON files unless the format query
+// parameter asks for CSV.
 func DownloadData(db *sql.DB, c echo.Context) error {
 	var files []string
 
-	sh, err := models.ScoutHealthsAsJSON(db)
-	if err != nil {
-		log.Printf("ERROR: Downloading, unable to get scout healths as JSON.")
-		log.Printf("%v", err)
-		return err
+	exports, scouts, format := jsonExports, models.ScoutsAsJSON, "JSON"
+	switch c.QueryParam("format") {
+	case "", "json":
+	case "csv":
+		exports, scouts, format = csvExports, models.ScoutsAsCSV, "CSV"
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid format")
 	}
-	files = append(files, sh)
 
-	si, err := models.ScoutInteractionsAsJSON(db)
-	if err != nil {
-		log.Printf("ERROR: Downloading, unable to get scout interactions as JSON.")
-		log.Printf("%v", err)
-		return err
-	}
-	files = append(files, si)
-
-	ss, err := models.ScoutSummariesAsJSON(db)
-	if err != nil {
-		log.Printf("ERROR: Downloading, unable to get scout summaries as JSON.")
-		log.Printf("%v", err)
-		return err
+	for _, e := range exports {
+		f, err := e.save(db)
+		if err != nil {
+			log.Printf("ERROR: Downloading, unable to get %s.", e.name)
+			log.Printf("%v", err)
+			return err
+		}
+		files = append(files, f)
 	}
-	files = append(files, ss)
 
-	sa, err := models.ScoutsAsJSON(db)
+	sa, err := scouts(db)
 	if err != nil {
-		log.Printf("ERROR: Downloading, unable to get scouts as JSON.")
+		log.Printf("ERROR: Downloading, unable to get scouts as %s.", format)
 		log.Printf("%v", err)
 		return err
 	}
@@ -101,7 +134,7 @@ func DownloadData(db *sql.DB, c echo.Context) error {
 	}
 
 	// Write the zip to disk.
-	zipFile := path.Dir(sh) + "/download.zip"
+	zipFile := configuration.GetDataDir() + "/download.zip"
 	err = ioutil.WriteFile(zipFile, buf.Bytes(), 0644)
 	if err != nil {
 		log.Printf("ERROR: Downloading, unable to write file.")
@@ -113,21 +146,81 @@ func DownloadData(db *sql.DB, c echo.Context) error {
 	return c.File(zipFile)
 }
 
+// shareSummary prepares the summary ss of a scout to be shared under the privacy p of the scout.
+// Summaries that need noise are only published through the heatmaps and the data download, where
+// they spend the privacy budget of the scout, so nil is shared in their place.
+func shareSummary(ss *models.ScoutSummary, p models.Privacy) *models.ScoutSummary {
+	if p.Epsilon > 0.0 {
+		return nil
+	}
+
+	ss.Suppress(p.MinVisitors)
+	return ss
+}
+
 func GetScouts(db *sql.DB, c echo.Context) error {
 	s, err := models.GetAllScouts(db)
 	if err != nil {
 		return err
 	}
 
+	for _, sc := range s {
+		sc.Summary = shareSummary(sc.Summary, sc.Privacy)
+	}
+
 	return c.JSON(http.StatusOK, s)
 }
 
+// drawMasks shades the exclusion masks of a scout over the supplied JPEG frame.
+func drawMasks(frame []byte, masks models.Masks) ([]byte, error) {
+	src, err := jpeg.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return frame, err
+	}
+
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	for _, m := range masks {
+		p := vec.PolygonFromPath(m)
+		b := p.Bounds()
+
+		for y := b.Min[1]; y <= b.Max[1]; y++ {
+			for x := b.Min[0]; x <= b.Max[0]; x++ {
+				if p.Contains(vec.Vec{x, y}) {
+					c := img.RGBAAt(x, y)
+					img.SetRGBA(x, y, color.RGBA{uint8((int(c.R) + 255) / 2), c.G / 2, c.B / 2, 255})
+				}
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err = jpeg.Encode(buf, img, nil)
+	return buf.Bytes(), err
+}
+
 func GetScoutFrame(db *sql.DB, c echo.Context) error {
 	frame, err := ioutil.ReadFile("calibrationFrame.jpg")
 	if err != nil {
 		return err
 	}
 
+	// Preview the exclusion masks over the calibration frame.
+	if c.QueryParam("masks") == "true" {
+		s, err := models.GetScoutByUUID(db, c.Param("uuid"))
+		if err != nil {
+			return err
+		}
+
+		frame, err = drawMasks(frame, s.Masks)
+		if err != nil {
+			log.Printf("ERROR: Unable to draw masks over the calibration frame.")
+			log.Printf("%v", err)
+			return err
+		}
+	}
+
 	c.Response().Header().Set(echo.HeaderContentType, "image/jpeg")
 	c.Response().WriteHeader(http.StatusOK)
 	_, err = c.Response().Write(frame)
@@ -140,6 +233,7 @@ func GetScout(db *sql.DB, c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	s.Summary = shareSummary(s.Summary, s.Privacy)
 
 	return c.JSON(http.StatusOK, s)
 }
@@ -175,6 +269,22 @@ func ClearMeasurements(db *sql.DB, c echo.Context) error {
 		return err
 	}
 
+	err = models.DeleteHourlySummaries(db, s.UUID)
+	if err != nil {
+		return err
+	}
+
+	err = models.ClearZones(db, s.UUID)
+	if err != nil {
+		return err
+	}
+
+	err = models.ClearTripwires(db, s.UUID)
+	if err != nil {
+		return err
+	}
+	s.Summary = shareSummary(s.Summary, s.Privacy)
+
 	return c.JSON(http.StatusOK, s)
 }
 
@@ -194,6 +304,24 @@ func UpdateScout(db *sql.DB, c echo.Context, deltaC chan models.Command) error {
 		return err
 	}
 
+	for _, m := range ns.Masks {
+		if len(m) < 3 {
+			return echo.NewHTTPError(http.StatusBadRequest, "A mask needs at least three vertices")
+		}
+	}
+
+	if !ns.MatchStrategy.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown match strategy")
+	}
+
+	if ns.Frame.Width <= 0 || ns.Frame.Height <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "A frame needs a width and a height")
+	}
+
+	if !ns.Privacy.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Times must be rounded to a multiple of 15 minutes, and noise can't be negative")
+	}
+
 	// If the scout is de-authorised/deactivated - clear it all out.
 	if !ns.Authorised {
 		ns.State = models.IDLE
@@ -211,6 +339,16 @@ func UpdateScout(db *sql.DB, c echo.Context, deltaC chan models.Command) error {
 		deltaC <- models.STOP_MEASURE
 	}
 
+	// A privacy that clips interactions differently rebuilds the summaries of the scout.
+	err = processes.UpdatePrivacy(db, ns.UUID, ns.Privacy)
+	if err == processes.ErrPrunedClip {
+		return echo.NewHTTPError(http.StatusConflict, "The noise can't change how visitors are clipped once interactions have been pruned")
+	} else if err != nil {
+		log.Printf("ERROR: Unable to update the privacy of the scout")
+		log.Printf("%v", err)
+		return err
+	}
+
 	err = ns.Update(db)
 	if err != nil {
 		log.Printf("ERROR: Unable to update scout")
@@ -18,6 +18,7 @@
 package controllers
 
 import (
+	"archive/zip"
 	"bytes"
 	"database/sql"
 	"encoding/json"
@@ -26,6 +27,10 @@ import (
 	"github.com/labstack/echo"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -51,6 +56,18 @@ func cleaner() {
 	_, err = db.Exec(`DELETE FROM scout_healths`)
 	Ω(err).Should(BeNil())
 
+	_, err = db.Exec(`DELETE FROM tripwire_counts`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM tripwires`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zone_visits`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zones`)
+	Ω(err).Should(BeNil())
+
 	_, err = db.Exec(`DELETE FROM scouts`)
 	Ω(err).Should(BeNil())
 }
@@ -92,15 +109,25 @@ var _ = Describe("Scout controller", func() {
 		})
 
 		It("should return a list of all the attached scouts", func() {
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1",
-				8080, true, "foo", "calibrating", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
-			s2 := models.Scout{"eeef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.2",
-				8080, true, "foop", "calibrating", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s2 := models.Scout{UUID: "eeef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.2", Port: 8080, Authorised: true, Name: "foop", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err = s2.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -124,9 +151,14 @@ var _ = Describe("Scout controller", func() {
 		})
 
 		It("should return a single scout", func() {
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1",
-				8080, true, "foo", "calibrated", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -149,10 +181,67 @@ var _ = Describe("Scout controller", func() {
 			Ω(ns).Should(Equal(s))
 		})
 
+		It("should leave out the exact summary of scouts that add noise", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "measuring",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{0, 15, 0.5, 1.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			s.Summary.VisitorCount = 7
+			s.Summary.VisitorBuckets[4][5] = 7
+			s.Summary.VisitTimeBuckets[4][5] = 21.0
+			err = s.Summary.Update(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err = GetScouts(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var sl []models.Scout
+			err = json.Unmarshal(rec.Body.Bytes(), &sl)
+			Ω(err).Should(BeNil())
+			Ω(len(sl)).Should(Equal(1))
+			Ω(sl[0].Summary).Should(BeNil())
+
+			req, err = http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec = httptest.NewRecorder()
+			c = e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetScout(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var ns models.Scout
+			err = json.Unmarshal(rec.Body.Bytes(), &ns)
+			Ω(err).Should(BeNil())
+			Ω(ns.Summary).Should(BeNil())
+		})
+
 		It("should be able to update a single scout", func() {
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1",
-				8080, true, "foo", "calibrated", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -181,5 +270,181 @@ var _ = Describe("Scout controller", func() {
 			Ω(err).Should(BeNil())
 			Ω(ns).Should(Equal(&s))
 		})
+		It("should be able to update the masks of a scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			s.Masks = models.Masks{models.Path{[2]int{0, 0}, [2]int{100, 0}, [2]int{100, 100}}}
+			s.MaskCoverage = 0.75
+
+			e := echo.New()
+			b, err := json.Marshal(s)
+			Ω(err).Should(BeNil())
+			req, err := http.NewRequest(echo.PUT, "/scouts/", bytes.NewReader(b))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			deltaC := make(chan models.Command)
+			err = UpdateScout(db, c, deltaC)
+			Ω(err).Should(BeNil())
+
+			ns, err := models.GetScoutByUUID(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(ns.Masks).Should(Equal(s.Masks))
+			Ω(ns.MaskCoverage).Should(Equal(0.75))
+		})
+
+		It("should not update a scout with a mask of less than three vertices", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.PUT, "/scouts/",
+				strings.NewReader(`{"uuid": "59ef7180-f6b2-4129-99bf-970eb4312b4b", "Masks": [[[0, 0], [10, 10]]]}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			deltaC := make(chan models.Command)
+			err = UpdateScout(db, c, deltaC)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should not update a scout with times rounded to part of 15 minutes", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.PUT, "/scouts/",
+				strings.NewReader(`{"uuid": "59ef7180-f6b2-4129-99bf-970eb4312b4b", "Frame": {"Width": 1280, "Height": 720}, "Privacy": {"MinVisitors": 5, "TimeRounding": 10}}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			deltaC := make(chan models.Command)
+			err = UpdateScout(db, c, deltaC)
+			Ω(err).ShouldNot(BeNil())
+
+			ns, err := models.GetScoutByUUID(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(ns.Privacy).Should(Equal(models.DefaultPrivacy))
+		})
+
+		It("should not update a scout with an unknown match strategy", func() {
+			e := echo.New()
+			req, err := http.NewRequest(echo.PUT, "/scouts/",
+				strings.NewReader(`{"uuid": "59ef7180-f6b2-4129-99bf-970eb4312b4b", "MatchStrategy": "nearest", "Frame": {"Width": 1280, "Height": 720}}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid")
+			c.SetParamNames("uuid")
+			c.SetParamValues("59ef7180-f6b2-4129-99bf-970eb4312b4b")
+
+			err = UpdateScout(db, c, make(chan models.Command))
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.(*echo.HTTPError).Code).Should(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("DownloadData", func() {
+		It("should zip up the CSV exports when asked for CSV", func() {
+			s := models.Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "calibrated", Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/download.zip?format=csv", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err = DownloadData(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			z, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+			Ω(err).Should(BeNil())
+
+			var names []string
+			for _, f := range z.File {
+				names = append(names, f.Name)
+			}
+			Ω(names).Should(Equal([]string{"scout_healths.csv", "scout_interactions.csv",
+				"scout_summaries.csv", "hourly_summaries.csv", "scouts.csv"}))
+		})
+
+		It("should not download an unknown format", func() {
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/download.zip?format=xls", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err = DownloadData(db, c)
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.(*echo.HTTPError).Code).Should(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("drawMasks", func() {
+		It("should shade the masks over the frame", func() {
+			img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+			draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 200, 255}), image.ZP, draw.Src)
+			buf := new(bytes.Buffer)
+			Ω(jpeg.Encode(buf, img, nil)).Should(BeNil())
+
+			frame, err := drawMasks(buf.Bytes(), models.Masks{models.Path{[2]int{0, 0}, [2]int{20, 0}, [2]int{20, 20}, [2]int{0, 20}}})
+			Ω(err).Should(BeNil())
+
+			res, err := jpeg.Decode(bytes.NewReader(frame))
+			Ω(err).Should(BeNil())
+
+			r, _, b, _ := res.At(10, 10).RGBA()
+			Ω(r).Should(BeNumerically(">", b))
+
+			r, _, b, _ = res.At(30, 30).RGBA()
+			Ω(r).Should(BeNumerically("<", b))
+		})
 	})
 })
@@ -0,0 +1,160 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// getTripwire fetches the tripwire identified by the :uuid and :id parameters of c.
+func getTripwire(db *sql.DB, c echo.Context) (*models.Tripwire, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid tripwire id")
+	}
+
+	t, err := models.GetTripwireById(db, id)
+	if err == sql.ErrNoRows || (err == nil && t.ScoutUUID != c.Param("uuid")) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Unknown tripwire")
+	}
+
+	return t, err
+}
+
+// readTripwire parses the name and segment of a tripwire from the body of the request.
+func readTripwire(c echo.Context, t *models.Tripwire) error {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("ERROR: Unable to read tripwire message")
+		log.Printf("%v", err)
+		return err
+	}
+
+	var nt models.Tripwire
+	err = json.Unmarshal(body, &nt)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal JSON.")
+		log.Printf("%v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tripwire")
+	}
+
+	if nt.Name == "" || len(nt.Segment) != 2 || nt.Segment[0] == nt.Segment[1] {
+		return echo.NewHTTPError(http.StatusBadRequest, "A tripwire needs a name and two different ends")
+	}
+
+	t.Name = nt.Name
+	t.Segment = nt.Segment
+	return nil
+}
+
+func GetTripwires(db *sql.DB, c echo.Context) error {
+	t, err := models.GetTripwires(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func CreateTripwire(db *sql.DB, c echo.Context) error {
+	s, err := models.GetScoutByUUID(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	var t models.Tripwire
+	err = readTripwire(c, &t)
+	if err != nil {
+		return err
+	}
+
+	t.ScoutUUID = s.UUID
+	err = t.Insert(db)
+	if err != nil {
+		log.Printf("ERROR: Unable to insert tripwire")
+		log.Printf("%v", err)
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, t)
+}
+
+func GetTripwire(db *sql.DB, c echo.Context) error {
+	t, err := getTripwire(db, c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+// UpdateTripwire renames or moves a tripwire. Crossings that have already been counted are kept.
+func UpdateTripwire(db *sql.DB, c echo.Context) error {
+	t, err := getTripwire(db, c)
+	if err != nil {
+		return err
+	}
+
+	err = readTripwire(c, t)
+	if err != nil {
+		return err
+	}
+
+	err = t.Update(db)
+	if err != nil {
+		log.Printf("ERROR: Unable to update tripwire")
+		log.Printf("%v", err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func DeleteTripwire(db *sql.DB, c echo.Context) error {
+	t, err := getTripwire(db, c)
+	if err != nil {
+		return err
+	}
+
+	err = t.Delete(db)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func GetTripwireCounts(db *sql.DB, c echo.Context) error {
+	t, err := getTripwire(db, c)
+	if err != nil {
+		return err
+	}
+
+	tc, err := models.GetTripwireCounts(db, t.Id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tc)
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTripwire(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tripwire controller Suite")
+}
+
+var _ = Describe("Tripwire controller", func() {
+	AfterEach(cleaner)
+
+	door := models.Path{[2]int{100, 200}, [2]int{300, 200}}
+
+	Context("CreateTripwire", func() {
+		It("should create a tripwire for a scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			b, err := json.Marshal(models.Tripwire{0, "", "Front door", door, 0, 0})
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.POST, "/scouts/", bytes.NewReader(b))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/tripwires")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = CreateTripwire(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(201))
+
+			tl, err := models.GetTripwires(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(tl)).Should(Equal(1))
+			Ω(tl[0].Segment).Should(Equal(door))
+		})
+
+		It("should not create a tripwire without two ends", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.POST, "/scouts/",
+				strings.NewReader(`{"name": "foo", "segment": [[0, 0]]}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/tripwires")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = CreateTripwire(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("GetTripwireCounts", func() {
+		It("should return the hourly counts of a tripwire", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := models.Tripwire{0, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			Ω(tw.AddCrossing(db, t, true)).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/tripwires/:id/counts")
+			c.SetParamNames("uuid", "id")
+			c.SetParamValues(s.UUID, strconv.FormatInt(tw.Id, 10))
+
+			err = GetTripwireCounts(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var tc []models.TripwireCount
+			err = json.Unmarshal(rec.Body.Bytes(), &tc)
+			Ω(err).Should(BeNil())
+			Ω(tc).Should(Equal([]models.TripwireCount{models.TripwireCount{tw.Id, t, 1, 0}}))
+		})
+	})
+})
@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// getZone fetches the zone identified by the :uuid and :id parameters of c.
+func getZone(db *sql.DB, c echo.Context) (*models.Zone, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid zone id")
+	}
+
+	z, err := models.GetZoneById(db, id)
+	if err == sql.ErrNoRows || (err == nil && z.ScoutUUID != c.Param("uuid")) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Unknown zone")
+	}
+
+	return z, err
+}
+
+// readZone parses the name and polygon of a zone from the body of the request.
+func readZone(c echo.Context, z *models.Zone) error {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("ERROR: Unable to read zone message")
+		log.Printf("%v", err)
+		return err
+	}
+
+	var nz models.Zone
+	err = json.Unmarshal(body, &nz)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal JSON.")
+		log.Printf("%v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid zone")
+	}
+
+	if nz.Name == "" || len(nz.Polygon) < 3 {
+		return echo.NewHTTPError(http.StatusBadRequest, "A zone needs a name and at least three vertices")
+	}
+
+	z.Name = nz.Name
+	z.Polygon = nz.Polygon
+	return nil
+}
+
+func GetZones(db *sql.DB, c echo.Context) error {
+	z, err := models.GetZones(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, z)
+}
+
+func CreateZone(db *sql.DB, c echo.Context) error {
+	s, err := models.GetScoutByUUID(db, c.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	var z models.Zone
+	err = readZone(c, &z)
+	if err != nil {
+		return err
+	}
+
+	z.ScoutUUID = s.UUID
+	err = z.Insert(db)
+	if err != nil {
+		log.Printf("ERROR: Unable to insert zone")
+		log.Printf("%v", err)
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, z)
+}
+
+func GetZone(db *sql.DB, c echo.Context) error {
+	z, err := getZone(db, c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, z)
+}
+
+// UpdateZone renames or reshapes a zone. Visits that have already been counted are kept.
+func UpdateZone(db *sql.DB, c echo.Context) error {
+	z, err := getZone(db, c)
+	if err != nil {
+		return err
+	}
+
+	err = readZone(c, z)
+	if err != nil {
+		return err
+	}
+
+	err = z.Update(db)
+	if err != nil {
+		log.Printf("ERROR: Unable to update zone")
+		log.Printf("%v", err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, z)
+}
+
+func DeleteZone(db *sql.DB, c echo.Context) error {
+	z, err := getZone(db, c)
+	if err != nil {
+		return err
+	}
+
+	err = z.Delete(db)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func GetZoneVisits(db *sql.DB, c echo.Context) error {
+	z, err := getZone(db, c)
+	if err != nil {
+		return err
+	}
+
+	v, err := models.GetZoneVisits(db, z.Id)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(db, z.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, zv := range v {
+		zv.FirstEnteredAt = p.Round(zv.FirstEnteredAt)
+		zv.LastEnteredAt = p.Round(zv.LastEnteredAt)
+	}
+
+	return c.JSON(http.StatusOK, v)
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/labstack/echo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZone(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zone controller Suite")
+}
+
+var _ = Describe("Zone controller", func() {
+	AfterEach(cleaner)
+
+	square := models.Path{[2]int{0, 0}, [2]int{10, 0}, [2]int{10, 10}, [2]int{0, 10}}
+
+	Context("CreateZone", func() {
+		It("should create a zone for a scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			b, err := json.Marshal(models.Zone{0, "", "Help desk", square, 0, 0.0, 0.0})
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.POST, "/scouts/", bytes.NewReader(b))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/zones")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = CreateZone(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(201))
+
+			zl, err := models.GetZones(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(zl)).Should(Equal(1))
+			Ω(zl[0].Name).Should(Equal("Help desk"))
+			Ω(zl[0].Polygon).Should(Equal(square))
+		})
+
+		It("should not create a zone without enough vertices", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.POST, "/scouts/",
+				strings.NewReader(`{"name": "foo", "polygon": [[0, 0], [1, 1]]}`))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/zones")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = CreateZone(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("GetZone", func() {
+		It("should list the zones of a scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := models.Zone{0, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/zones")
+			c.SetParamNames("uuid")
+			c.SetParamValues(s.UUID)
+
+			err = GetZones(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var zl []models.Zone
+			err = json.Unmarshal(rec.Body.Bytes(), &zl)
+			Ω(err).Should(BeNil())
+			Ω(zl).Should(Equal([]models.Zone{z}))
+		})
+
+		It("should round the times of zone visits to the privacy of the scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.Privacy{0, 60, 0.0, 0.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := models.Zone{0, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			entered := time.Date(2016, 5, 12, 10, 53, 21, 0, time.UTC)
+			err = z.AddVisit(db, &models.ZoneVisit{0, 1, 2.0, entered, entered.Add(time.Minute)})
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/zones/:id/visits")
+			c.SetParamNames("uuid", "id")
+			c.SetParamValues(s.UUID, strconv.FormatInt(z.Id, 10))
+
+			err = GetZoneVisits(db, c)
+			Ω(err).Should(BeNil())
+			Ω(rec.Code).Should(Equal(200))
+
+			var vl []models.ZoneVisit
+			err = json.Unmarshal(rec.Body.Bytes(), &vl)
+			Ω(err).Should(BeNil())
+			Ω(len(vl)).Should(Equal(1))
+			Ω(vl[0].FirstEnteredAt).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+			Ω(vl[0].LastEnteredAt).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+		})
+
+		It("should not return zones that belong to another scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrated",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := models.Zone{0, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			e := echo.New()
+			req, err := http.NewRequest(echo.GET, "/scouts/", strings.NewReader(""))
+			Ω(err).Should(BeNil())
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/scouts/:uuid/zones/:id")
+			c.SetParamNames("uuid", "id")
+			c.SetParamValues("eeef7180-f6b2-4129-99bf-970eb4312b4b", strconv.FormatInt(z.Id, 10))
+
+			err = GetZone(db, c)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+})
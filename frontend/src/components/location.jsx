@@ -168,8 +168,19 @@ var Heatmap = React.createClass({
   render: function() {
     const { store } = this.context;
     var url = 'scouts/'+ActiveLocation(store).id+'/frame.jpg?d=' + new Date().getTime();
-    var buckets = ActiveLocation(store).summary.VisitTimeBuckets;
-    var vBuckets = ActiveLocation(store).summary.VisitorBuckets;
+    var summary = ActiveLocation(store).summary;
+    if (!summary) {
+      // Scouts that publish with differential privacy don't share their summary here.
+      return (
+        <div id="heatmap">
+        <h3>AVERAGE LOITER TIME</h3>
+        <img src={url} width="100%" />
+        </div>
+      )
+    }
+
+    var buckets = summary.VisitTimeBuckets;
+    var vBuckets = summary.VisitorBuckets;
     var w = 1280;
     var h = 720;
     var iBuckets = buckets.length;
@@ -218,7 +229,17 @@ Heatmap.contextTypes = {
 var Analysis = React.createClass({
   render: function() {
     const { store } = this.context;
-    var count = ActiveLocation(store).summary.VisitorCount;
+    var summary = ActiveLocation(store).summary;
+    if (!summary) {
+      return (
+        <div id="analysis">
+        <h3>INTERACTION REPORT</h3>
+        <p>Visitor counts are only published through the heatmap, with differential privacy.</p>
+        </div>
+      )
+    }
+
+    var count = summary.VisitorCount;
     var vUpper = Math.ceil((count + 1) / 10) * 10;
     var vLower = vUpper - 10;
 
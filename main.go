@@ -29,16 +29,33 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func main() {
+	// Recorded footage is processed offline with 'scout process'.
+	if len(os.Args) > 1 && os.Args[1] == "process" {
+		processRecording(os.Args[2:])
+		return
+	}
+
+	// Summaries are rebuilt from the interactions already measured with 'scout resummarise'.
+	if len(os.Args) > 1 && os.Args[1] == "resummarise" {
+		resummarise(os.Args[2:])
+		return
+	}
+
 	var configFile string
 	var videoFile string
+	var replayFile string
+	var frameRate float64
 	var logFile string
 	var debug bool
 
 	flag.StringVar(&configFile, "configFile", "scout.json", "The path to the configuration file")
 	flag.StringVar(&videoFile, "videoFile", "", "The path to a video file to detect motion from instead of a webcam")
+	flag.Float64Var(&frameRate, "frameRate", 30.0, "The number of frames per second in the video file, when the file doesn't record it")
+	flag.StringVar(&replayFile, "replayFile", "", "The path to a JSON file of recorded detections to replay instead of detecting motion")
 	flag.StringVar(&logFile, "logFile", "scout.log", "The output path for log files.")
 	flag.BoolVar(&debug, "debug", false, "Should we run scout in debug mode, and render frames of detected materials")
 	flag.Parse()
@@ -65,12 +82,7 @@ func main() {
 	}
 
 	// Open a connection to the database.
-	connection := "user=" + config.DBUserName + " dbname=" + config.DBName
-	if config.DBPassword != "" {
-		connection = connection + " password=" + config.DBPassword
-	}
-
-	db, err := sql.Open("postgres", connection)
+	db, err := openDB(config, config.DBName)
 	if err != nil {
 		log.Fatalf("ERROR: Can't open database - %s", err)
 	}
@@ -82,20 +94,36 @@ func main() {
 		log.Fatalf("ERROR: Unable to cound scouts in DB - %s", err)
 	}
 	if c == 0 {
-		ns := models.Scout{"", "0.0.0.0", 8080, false, "Location " + strconv.FormatInt(c+1, 10), "idle", &models.ScoutSummary{},
-			6160.0, 10, 128, 5, 500, 30.0, 0, 5.0, 2.0, 1.0, 200, 115000.0}
+		ns := models.Scout{IpAddress: "0.0.0.0", Port: 8080,
+			Name: "Location " + strconv.FormatInt(c+1, 10), State: "idle", Summary: &models.ScoutSummary{},
+			MinArea: 6160.0, DilationIterations: 10, ForegroundThresh: 128, GaussianSmooth: 5,
+			MogHistoryLength: 500, MogThreshold: 30.0, SimplifyEpsilon: 5.0, MinDuration: 2.0,
+			IdleDuration: 1.0, ResumeSqDistance: 200, MaxArea: 115000.0, MatchStrategy: models.OPTIMAL,
+			GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0,
+			Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0,
+			Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 		err = ns.Insert(db)
 		if err != nil {
 			log.Fatalf("ERROR: Unable to add initial scout to DB.")
 		}
 	}
 
+	// Scouts upgraded from before hourly summaries were kept need them for heatmaps.
+	n, err := processes.BackfillHourly(db, models.GetScoutUUID(db))
+	if err != nil {
+		log.Printf("ERROR: Unable to backfill hourly summaries - %s", err)
+	} else if n > 0 {
+		log.Printf("INFO: Backfilled hourly summaries from %d interactions", n)
+	}
+
 	// Start the background processes.
 	go processes.SaveLogToDB(tmpLog, db)
 	go processes.HealthHeartbeat(db)
 	go processes.Summarise(db, config)
+	go processes.Prune(db, config)
 
 	deltaC := make(chan models.Command)
+	live := models.NewLiveScene(models.GetScoutUUID(db))
 	// Test to see if the scout is still in measurement mode on boot and resume if necessary.
 	go func() {
 		if _, err := os.Stat(".mtf-measure"); err == nil {
@@ -103,7 +131,16 @@ func main() {
 			deltaC <- models.START_MEASURE
 		}
 	}()
-	go processes.Monitor(db, deltaC, videoFile, debug)
+
+	// Detect motion with OpenCV, unless we have been given recorded detections to replay.
+	var d processes.Detector = &processes.CVDetector{VideoFile: videoFile, FrameRate: frameRate, Debug: debug}
+	if replayFile != "" {
+		d, err = processes.LoadReplayDetector(replayFile, true)
+		if err != nil {
+			log.Fatalf("ERROR: Unable to load recorded detections - %s", err)
+		}
+	}
+	go processes.Monitor(db, config, deltaC, d, live)
 
 	// Start the user interface.
 	e := echo.New()
@@ -129,6 +166,110 @@ func main() {
 		return controllers.UpdateScout(db, c, deltaC)
 	})
 
+	e.GET("/scouts/:uuid/live", func(c echo.Context) error {
+		return controllers.GetLive(live, c)
+	})
+
+	e.GET("/scouts/:uuid/live/stream", func(c echo.Context) error {
+		return controllers.StreamLive(live, c)
+	})
+
+	e.GET("/scouts/:uuid/floor", func(c echo.Context) error {
+		return controllers.GetFloorCalibration(db, c)
+	})
+
+	e.PUT("/scouts/:uuid/floor", func(c echo.Context) error {
+		return controllers.UpdateFloorCalibration(db, c)
+	})
+
+	e.DELETE("/scouts/:uuid/floor", func(c echo.Context) error {
+		return controllers.DeleteFloorCalibration(db, c)
+	})
+
+	e.GET("/scouts/:uuid/floor/interactions", func(c echo.Context) error {
+		return controllers.GetFloorInteractions(db, c)
+	})
+
+	e.GET("/scouts/:uuid/floor/heatmap", func(c echo.Context) error {
+		return controllers.GetFloorHeatmap(db, c)
+	})
+
+	e.POST("/scouts/:uuid/resummarise", func(c echo.Context) error {
+		return controllers.Resummarise(db, c)
+	})
+
+	e.GET("/scouts/:uuid/resummarise/failures", func(c echo.Context) error {
+		return controllers.GetSummariseFailures(db, c)
+	})
+
+	e.GET("/scouts/:uuid/privacy/spends", func(c echo.Context) error {
+		return controllers.GetPrivacySpends(db, c)
+	})
+
+	e.GET("/prunes", func(c echo.Context) error {
+		return controllers.GetPrunes(db, c)
+	})
+
+	e.GET("/scouts/:uuid/heatmap", func(c echo.Context) error {
+		return controllers.GetHeatmap(db, c)
+	})
+
+	e.GET("/scouts/:uuid/metrics", func(c echo.Context) error {
+		return controllers.GetInteractionMetrics(db, c)
+	})
+
+	e.GET("/scouts/:uuid/dwells", func(c echo.Context) error {
+		return controllers.GetDwells(db, c)
+	})
+
+	e.GET("/scouts/:uuid/zones", func(c echo.Context) error {
+		return controllers.GetZones(db, c)
+	})
+
+	e.POST("/scouts/:uuid/zones", func(c echo.Context) error {
+		return controllers.CreateZone(db, c)
+	})
+
+	e.GET("/scouts/:uuid/zones/:id", func(c echo.Context) error {
+		return controllers.GetZone(db, c)
+	})
+
+	e.PUT("/scouts/:uuid/zones/:id", func(c echo.Context) error {
+		return controllers.UpdateZone(db, c)
+	})
+
+	e.DELETE("/scouts/:uuid/zones/:id", func(c echo.Context) error {
+		return controllers.DeleteZone(db, c)
+	})
+
+	e.GET("/scouts/:uuid/zones/:id/visits", func(c echo.Context) error {
+		return controllers.GetZoneVisits(db, c)
+	})
+
+	e.GET("/scouts/:uuid/tripwires", func(c echo.Context) error {
+		return controllers.GetTripwires(db, c)
+	})
+
+	e.POST("/scouts/:uuid/tripwires", func(c echo.Context) error {
+		return controllers.CreateTripwire(db, c)
+	})
+
+	e.GET("/scouts/:uuid/tripwires/:id", func(c echo.Context) error {
+		return controllers.GetTripwire(db, c)
+	})
+
+	e.PUT("/scouts/:uuid/tripwires/:id", func(c echo.Context) error {
+		return controllers.UpdateTripwire(db, c)
+	})
+
+	e.DELETE("/scouts/:uuid/tripwires/:id", func(c echo.Context) error {
+		return controllers.DeleteTripwire(db, c)
+	})
+
+	e.GET("/scouts/:uuid/tripwires/:id/counts", func(c echo.Context) error {
+		return controllers.GetTripwireCounts(db, c)
+	})
+
 	e.GET("/scouts/:uuid/clearMeasurements", func(c echo.Context) error {
 		log.Printf("clearing meaasurements")
 		return controllers.ClearMeasurements(db, c)
@@ -143,3 +284,159 @@ func main() {
 		e.Logger.Fatal(err)
 	}
 }
+
+// openDB opens a connection to the database called name.
+func openDB(config configuration.Configuration, name string) (*sql.DB, error) {
+	return sql.Open("postgres", configuration.DBConnection(config, name))
+}
+
+// processRecording runs detection and tracking over a recording as fast as possible, using the
+// settings of the live scout. The resulting interactions and summary are written to a separate
+// dataset (a database with the scout schema) or to a directory of JSON files, leaving the tables
+// of the live scout untouched.
+func processRecording(args []string) {
+	var configFile string
+	var videoFile string
+	var replayFile string
+	var frameRate float64
+	var outputDB string
+	var outputDir string
+
+	fs := flag.NewFlagSet("process", flag.ExitOnError)
+	fs.StringVar(&configFile, "configFile", "scout.json", "The path to the configuration file")
+	fs.StringVar(&videoFile, "videoFile", "", "The path to the video file to process")
+	fs.Float64Var(&frameRate, "frameRate", 30.0, "The number of frames per second in the video file, when the file doesn't record it")
+	fs.StringVar(&replayFile, "replayFile", "", "The path to a JSON file of recorded detections to process instead of a video file")
+	fs.StringVar(&outputDB, "outputDB", "", "The name of a separate database to write the interactions and summary to")
+	fs.StringVar(&outputDir, "outputDir", "", "The path to a directory to write the interactions and summary to as JSON")
+	fs.Parse(args)
+
+	if videoFile == "" && replayFile == "" {
+		log.Fatalf("ERROR: Nothing to process, supply a -videoFile or -replayFile")
+	}
+	if (outputDB == "") == (outputDir == "") {
+		log.Fatalf("ERROR: Supply either an -outputDB or an -outputDir")
+	}
+
+	config, err := configuration.Parse(configFile)
+	if err != nil {
+		log.Fatalf("ERROR: Can't parse configuration - %s", err)
+	}
+	if outputDB == config.DBName {
+		log.Fatalf("ERROR: The output database can't be the database of the live scout")
+	}
+
+	// The live scout is only read, to get the settings used for detection and tracking.
+	db, err := openDB(config, config.DBName)
+	if err != nil {
+		log.Fatalf("ERROR: Can't open database - %s", err)
+	}
+	defer db.Close()
+	s := models.GetScout(db)
+
+	var d processes.Detector = &processes.CVDetector{VideoFile: videoFile, FrameRate: frameRate}
+	if replayFile != "" {
+		d, err = processes.LoadReplayDetector(replayFile, false)
+		if err != nil {
+			log.Fatalf("ERROR: Unable to load recorded detections - %s", err)
+		}
+	}
+
+	if outputDir != "" {
+		sink := processes.NewSummarySink(s)
+		err = processes.Process(s, d, sink)
+		if err != nil {
+			log.Fatalf("ERROR: Unable to process recording - %s", err)
+		}
+
+		err = sink.WriteJSON(outputDir)
+		if err != nil {
+			log.Fatalf("ERROR: Unable to write results - %s", err)
+		}
+
+		log.Printf("INFO: Wrote %d interactions to %s", len(sink.Interactions), outputDir)
+		return
+	}
+
+	out, err := openDB(config, outputDB)
+	if err != nil {
+		log.Fatalf("ERROR: Can't open output database - %s", err)
+	}
+	defer out.Close()
+
+	// Results in the dataset belong to a copy of the live scout.
+	c, err := models.NumScouts(out)
+	if err != nil {
+		log.Fatalf("ERROR: Unable to count scouts in output database - %s", err)
+	}
+	if c == 0 {
+		ns := *s
+		ns.Summary = &models.ScoutSummary{}
+		err = ns.Insert(out)
+		if err != nil {
+			log.Fatalf("ERROR: Unable to add scout to output database - %s", err)
+		}
+	}
+	// Track with the settings of the live scout, but record the results against the copy.
+	ds := *s
+	ds.UUID = models.GetScoutUUID(out)
+
+	err = processes.Process(&ds, d, models.DBSink{out})
+	if err != nil {
+		log.Fatalf("ERROR: Unable to process recording - %s", err)
+	}
+	processes.SummariseDataset(out)
+
+	log.Printf("INFO: Wrote results to the %s database", outputDB)
+}
+
+// resummarise rebuilds the summaries of the live scout from the interactions it has already
+// measured, optionally limited to those that entered between two dates or onto a new grid.
+func resummarise(args []string) {
+	var configFile string
+	var from string
+	var to string
+	var wBuckets int
+	var hBuckets int
+
+	fs := flag.NewFlagSet("resummarise", flag.ExitOnError)
+	fs.StringVar(&configFile, "configFile", "scout.json", "The path to the configuration file")
+	fs.StringVar(&from, "from", "", "Only resummarise interactions that entered on or after this date (YYYY-MM-DD, UTC)")
+	fs.StringVar(&to, "to", "", "Only resummarise interactions that entered before this date (YYYY-MM-DD, UTC)")
+	fs.IntVar(&wBuckets, "wBuckets", 0, "Rebuild every summary with this many buckets across the frame")
+	fs.IntVar(&hBuckets, "hBuckets", 0, "Rebuild every summary with this many buckets down the frame")
+	fs.Parse(args)
+
+	var fromT, toT time.Time
+	var err error
+	if from != "" {
+		fromT, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			log.Fatalf("ERROR: Invalid -from date - %s", err)
+		}
+	}
+	if to != "" {
+		toT, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			log.Fatalf("ERROR: Invalid -to date - %s", err)
+		}
+	}
+
+	config, err := configuration.Parse(configFile)
+	if err != nil {
+		log.Fatalf("ERROR: Can't parse configuration - %s", err)
+	}
+
+	db, err := openDB(config, config.DBName)
+	if err != nil {
+		log.Fatalf("ERROR: Can't open database - %s", err)
+	}
+	defer db.Close()
+
+	_, n, err := processes.Resummarise(db, models.GetScoutUUID(db), fromT, toT, wBuckets, hBuckets)
+	if err != nil {
+		log.Fatalf("ERROR: Unable to resummarise - %s", err)
+	}
+
+	log.Printf("INFO: Resummarised %d interactions", n)
+}
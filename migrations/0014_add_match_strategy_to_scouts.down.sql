@@ -0,0 +1,3 @@
+ALTER TABLE scouts DROP COLUMN gate_sq_distance;
+ALTER TABLE scouts DROP COLUMN match_strategy;
+DROP TYPE match_strategy;
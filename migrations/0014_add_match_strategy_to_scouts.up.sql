@@ -0,0 +1,4 @@
+CREATE TYPE match_strategy AS ENUM ('greedy', 'optimal');
+-- Existing scouts keep matching greedily, new scouts are created matching optimally.
+ALTER TABLE scouts ADD COLUMN match_strategy match_strategy NOT NULL DEFAULT 'greedy';
+ALTER TABLE scouts ADD COLUMN gate_sq_distance int NOT NULL DEFAULT 40000;
@@ -0,0 +1,2 @@
+ALTER TABLE scouts DROP COLUMN measurement_noise;
+ALTER TABLE scouts DROP COLUMN process_noise;
@@ -0,0 +1,2 @@
+ALTER TABLE scouts ADD COLUMN process_noise double precision NOT NULL DEFAULT 10000.0;
+ALTER TABLE scouts ADD COLUMN measurement_noise double precision NOT NULL DEFAULT 100.0;
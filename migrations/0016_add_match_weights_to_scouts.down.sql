@@ -0,0 +1,3 @@
+ALTER TABLE scouts DROP COLUMN size_weight;
+ALTER TABLE scouts DROP COLUMN iou_weight;
+ALTER TABLE scouts DROP COLUMN centroid_weight;
@@ -0,0 +1,3 @@
+ALTER TABLE scouts ADD COLUMN centroid_weight double precision NOT NULL DEFAULT 1.0;
+ALTER TABLE scouts ADD COLUMN iou_weight double precision NOT NULL DEFAULT 0.0;
+ALTER TABLE scouts ADD COLUMN size_weight double precision NOT NULL DEFAULT 0.0;
@@ -0,0 +1,4 @@
+DROP INDEX zone_visits_idx;
+DROP TABLE zone_visits;
+DROP INDEX zones_idx;
+DROP TABLE zones;
@@ -0,0 +1,20 @@
+CREATE SEQUENCE zone_id_seq;
+CREATE TABLE zones (
+	id int PRIMARY KEY DEFAULT nextval('zone_id_seq'),
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	name text NOT NULL,
+	polygon path NOT NULL,
+	visitor_count int NOT NULL DEFAULT 0,
+	dwell_time real NOT NULL DEFAULT 0.0
+);
+ALTER SEQUENCE zone_id_seq OWNED BY zones.id;
+CREATE INDEX zones_idx ON zones (scout_uuid);
+
+CREATE TABLE zone_visits (
+	zone_id int NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+	interaction_id int NOT NULL,
+	dwell_time real NOT NULL,
+	first_entered_at timestamp NOT NULL,
+	last_entered_at timestamp NOT NULL
+);
+CREATE INDEX zone_visits_idx ON zone_visits (zone_id);
@@ -0,0 +1,3 @@
+DROP TABLE tripwire_counts;
+DROP INDEX tripwires_idx;
+DROP TABLE tripwires;
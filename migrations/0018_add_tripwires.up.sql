@@ -0,0 +1,19 @@
+CREATE SEQUENCE tripwire_id_seq;
+CREATE TABLE tripwires (
+	id int PRIMARY KEY DEFAULT nextval('tripwire_id_seq'),
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	name text NOT NULL,
+	segment path NOT NULL,
+	in_count int NOT NULL DEFAULT 0,
+	out_count int NOT NULL DEFAULT 0
+);
+ALTER SEQUENCE tripwire_id_seq OWNED BY tripwires.id;
+CREATE INDEX tripwires_idx ON tripwires (scout_uuid);
+
+CREATE TABLE tripwire_counts (
+	tripwire_id int NOT NULL REFERENCES tripwires(id) ON DELETE CASCADE,
+	hour timestamp NOT NULL,
+	in_count int NOT NULL DEFAULT 0,
+	out_count int NOT NULL DEFAULT 0,
+	PRIMARY KEY (tripwire_id, hour)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE scouts DROP COLUMN mask_coverage;
+ALTER TABLE scouts DROP COLUMN masks;
@@ -0,0 +1,2 @@
+ALTER TABLE scouts ADD COLUMN masks jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE scouts ADD COLUMN mask_coverage double precision NOT NULL DEFAULT 0.5;
@@ -0,0 +1 @@
+DROP TABLE floor_calibrations;
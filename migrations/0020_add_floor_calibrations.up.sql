@@ -0,0 +1,5 @@
+CREATE TABLE floor_calibrations (
+	scout_uuid uuid PRIMARY KEY REFERENCES scouts(uuid) ON DELETE CASCADE,
+	points jsonb NOT NULL,
+	homography double precision[] NOT NULL
+);
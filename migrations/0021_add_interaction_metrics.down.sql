@@ -0,0 +1,2 @@
+DROP INDEX interaction_metrics_idx;
+DROP TABLE interaction_metrics;
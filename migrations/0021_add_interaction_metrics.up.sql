@@ -0,0 +1,15 @@
+CREATE TABLE interaction_metrics (
+	interaction_id int PRIMARY KEY REFERENCES scout_interactions(id) ON DELETE CASCADE,
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	entered_at timestamp NOT NULL,
+	path_length real NOT NULL,
+	mean_speed real NOT NULL,
+	max_speed real NOT NULL,
+	longest_dwell real NOT NULL,
+	dwell_x int NOT NULL,
+	dwell_y int NOT NULL,
+	bounds path NOT NULL,
+	entry_edge text NOT NULL,
+	exit_edge text NOT NULL
+);
+CREATE INDEX interaction_metrics_idx ON interaction_metrics (scout_uuid, entered_at);
@@ -0,0 +1,4 @@
+DROP INDEX dwells_idx;
+DROP TABLE dwells;
+ALTER TABLE scouts DROP COLUMN dwell_duration;
+ALTER TABLE scouts DROP COLUMN dwell_sq_distance;
@@ -0,0 +1,12 @@
+ALTER TABLE scouts ADD COLUMN dwell_sq_distance int NOT NULL DEFAULT 400;
+ALTER TABLE scouts ADD COLUMN dwell_duration real NOT NULL DEFAULT 5.0;
+CREATE TABLE dwells (
+	id serial PRIMARY KEY,
+	interaction_id int NOT NULL REFERENCES scout_interactions(id) ON DELETE CASCADE,
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	x int NOT NULL,
+	y int NOT NULL,
+	start_offset real NOT NULL,
+	duration real NOT NULL
+);
+CREATE INDEX dwells_idx ON dwells (interaction_id);
@@ -0,0 +1 @@
+DROP TABLE hourly_summaries;
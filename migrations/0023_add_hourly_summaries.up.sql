@@ -0,0 +1,8 @@
+CREATE TABLE hourly_summaries (
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	hour timestamp NOT NULL,
+	visitor_count int NOT NULL DEFAULT 0,
+	visit_time_buckets real[20][20] NOT NULL,
+	visitor_buckets int[20][20] NOT NULL,
+	PRIMARY KEY (scout_uuid, hour)
+);
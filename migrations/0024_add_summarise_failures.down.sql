@@ -0,0 +1 @@
+DROP TABLE summarise_failures;
@@ -0,0 +1,7 @@
+CREATE TABLE summarise_failures (
+	interaction_id int PRIMARY KEY REFERENCES scout_interactions(id) ON DELETE CASCADE,
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	error text NOT NULL,
+	attempts int NOT NULL DEFAULT 1,
+	failed_at timestamp NOT NULL
+);
@@ -0,0 +1,9 @@
+ALTER TABLE hourly_summaries ALTER COLUMN visitor_buckets TYPE int[20][20];
+ALTER TABLE hourly_summaries ALTER COLUMN visit_time_buckets TYPE real[20][20];
+ALTER TABLE scout_summaries ALTER COLUMN visitor_buckets TYPE int[20][20];
+ALTER TABLE scout_summaries ALTER COLUMN visit_time_buckets TYPE real[20][20];
+ALTER TABLE scouts DROP CONSTRAINT scouts_grid_check;
+ALTER TABLE scouts DROP COLUMN h_buckets;
+ALTER TABLE scouts DROP COLUMN w_buckets;
+ALTER TABLE scouts DROP COLUMN frame_height;
+ALTER TABLE scouts DROP COLUMN frame_width;
@@ -0,0 +1,14 @@
+ALTER TABLE scouts ADD COLUMN frame_width int NOT NULL DEFAULT 1280;
+ALTER TABLE scouts ADD COLUMN frame_height int NOT NULL DEFAULT 720;
+ALTER TABLE scouts ADD COLUMN w_buckets int NOT NULL DEFAULT 20;
+ALTER TABLE scouts ADD COLUMN h_buckets int NOT NULL DEFAULT 20;
+ALTER TABLE scouts ADD CONSTRAINT scouts_grid_check
+	CHECK (w_buckets > 0 AND h_buckets > 0 AND w_buckets <= frame_width AND h_buckets <= frame_height);
+
+-- Existing summaries were all summarised on a 20x20 grid of 1280x720 frames, the defaults above.
+-- Their arrays are kept as they are, but are no longer declared as fixed at 20x20.
+ALTER TABLE scout_summaries ALTER COLUMN visit_time_buckets TYPE real[][];
+ALTER TABLE scout_summaries ALTER COLUMN visitor_buckets DROP DEFAULT;
+ALTER TABLE scout_summaries ALTER COLUMN visitor_buckets TYPE int[][];
+ALTER TABLE hourly_summaries ALTER COLUMN visit_time_buckets TYPE real[][];
+ALTER TABLE hourly_summaries ALTER COLUMN visitor_buckets TYPE int[][];
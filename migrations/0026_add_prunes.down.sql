@@ -0,0 +1,5 @@
+DELETE FROM dwells WHERE interaction_id NOT IN (SELECT id FROM scout_interactions);
+DELETE FROM interaction_metrics WHERE interaction_id NOT IN (SELECT id FROM scout_interactions);
+ALTER TABLE dwells ADD CONSTRAINT dwells_interaction_id_fkey FOREIGN KEY (interaction_id) REFERENCES scout_interactions(id) ON DELETE CASCADE;
+ALTER TABLE interaction_metrics ADD CONSTRAINT interaction_metrics_interaction_id_fkey FOREIGN KEY (interaction_id) REFERENCES scout_interactions(id) ON DELETE CASCADE;
+DROP TABLE prunes;
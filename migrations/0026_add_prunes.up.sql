@@ -0,0 +1,12 @@
+CREATE TABLE prunes (
+	id serial PRIMARY KEY,
+	pruned_at timestamp NOT NULL,
+	interactions_before timestamp,
+	interactions int NOT NULL,
+	healths_before timestamp,
+	healths int NOT NULL,
+	logs_before timestamp,
+	logs int NOT NULL
+);
+ALTER TABLE interaction_metrics DROP CONSTRAINT interaction_metrics_interaction_id_fkey;
+ALTER TABLE dwells DROP CONSTRAINT dwells_interaction_id_fkey;
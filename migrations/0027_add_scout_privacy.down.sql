@@ -0,0 +1,3 @@
+ALTER TABLE scouts DROP CONSTRAINT scouts_privacy_check;
+ALTER TABLE scouts DROP COLUMN time_rounding;
+ALTER TABLE scouts DROP COLUMN min_visitors;
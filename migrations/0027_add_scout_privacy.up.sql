@@ -0,0 +1,4 @@
+ALTER TABLE scouts ADD COLUMN min_visitors int NOT NULL DEFAULT 0;
+ALTER TABLE scouts ADD COLUMN time_rounding int NOT NULL DEFAULT 15;
+ALTER TABLE scouts ADD CONSTRAINT scouts_privacy_check
+	CHECK (min_visitors >= 0 AND time_rounding > 0 AND time_rounding % 15 = 0);
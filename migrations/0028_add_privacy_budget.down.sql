@@ -0,0 +1,7 @@
+DROP TABLE privacy_spends;
+ALTER TABLE scouts DROP CONSTRAINT scouts_privacy_check;
+ALTER TABLE scouts ADD CONSTRAINT scouts_privacy_check
+	CHECK (min_visitors >= 0 AND time_rounding > 0 AND time_rounding % 15 = 0);
+ALTER TABLE scouts DROP COLUMN time_sensitivity;
+ALTER TABLE scouts DROP COLUMN privacy_budget;
+ALTER TABLE scouts DROP COLUMN epsilon;
@@ -0,0 +1,15 @@
+ALTER TABLE scouts ADD COLUMN epsilon double precision NOT NULL DEFAULT 0.0;
+ALTER TABLE scouts ADD COLUMN privacy_budget double precision NOT NULL DEFAULT 0.0;
+ALTER TABLE scouts ADD COLUMN time_sensitivity double precision NOT NULL DEFAULT 60.0;
+ALTER TABLE scouts DROP CONSTRAINT scouts_privacy_check;
+ALTER TABLE scouts ADD CONSTRAINT scouts_privacy_check
+	CHECK (min_visitors >= 0 AND time_rounding > 0 AND time_rounding % 15 = 0 AND epsilon >= 0
+	AND privacy_budget >= 0 AND time_sensitivity > 0);
+CREATE TABLE privacy_spends (
+	id serial PRIMARY KEY,
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	release text NOT NULL,
+	epsilon double precision NOT NULL,
+	spent_at timestamp NOT NULL
+);
+CREATE INDEX privacy_spends_idx ON privacy_spends (scout_uuid);
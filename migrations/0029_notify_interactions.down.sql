@@ -0,0 +1,2 @@
+DROP TRIGGER scout_interactions_notify ON scout_interactions;
+DROP FUNCTION notify_scout_interaction();
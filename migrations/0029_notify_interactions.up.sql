@@ -0,0 +1,8 @@
+CREATE FUNCTION notify_scout_interaction() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('scout_interactions', NEW.id::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER scout_interactions_notify AFTER INSERT ON scout_interactions
+	FOR EACH ROW WHEN (NOT NEW.processed) EXECUTE PROCEDURE notify_scout_interaction();
@@ -0,0 +1,5 @@
+ALTER TABLE scouts DROP CONSTRAINT scouts_privacy_check;
+ALTER TABLE scouts ADD CONSTRAINT scouts_privacy_check
+	CHECK (min_visitors >= 0 AND time_rounding > 0 AND time_rounding % 15 = 0 AND epsilon >= 0
+	AND privacy_budget >= 0 AND time_sensitivity > 0);
+ALTER TABLE scouts DROP COLUMN bucket_sensitivity;
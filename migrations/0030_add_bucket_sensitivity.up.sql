@@ -0,0 +1,5 @@
+ALTER TABLE scouts ADD COLUMN bucket_sensitivity int NOT NULL DEFAULT 20;
+ALTER TABLE scouts DROP CONSTRAINT scouts_privacy_check;
+ALTER TABLE scouts ADD CONSTRAINT scouts_privacy_check
+	CHECK (min_visitors >= 0 AND time_rounding > 0 AND time_rounding % 15 = 0 AND epsilon >= 0
+	AND privacy_budget >= 0 AND time_sensitivity > 0 AND bucket_sensitivity > 0);
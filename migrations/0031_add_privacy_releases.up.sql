@@ -0,0 +1,9 @@
+CREATE TABLE privacy_releases (
+	scout_uuid uuid NOT NULL REFERENCES scouts(uuid) ON DELETE CASCADE,
+	digest text NOT NULL,
+	visitor_count int NOT NULL,
+	visit_time_buckets real[][] NOT NULL,
+	visitor_buckets int[][] NOT NULL,
+	released_at timestamp NOT NULL,
+	PRIMARY KEY (scout_uuid, digest)
+);
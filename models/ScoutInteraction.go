@@ -173,7 +173,34 @@ func GetLastScoutInteraction(db *sql.DB, scoutUUID string) (*ScoutInteraction, e
 	return &result, err
 }
 
-func (si *ScoutInteraction) MarkProcessed(db *sql.DB) error {
+func GetScoutInteractions(db *sql.DB, scoutUUID string) ([]*ScoutInteraction, error) {
+	const query = `SELECT id, duration, waypoints, waypoint_widths, waypoint_times, processed, entered_at
+		FROM scout_interactions WHERE scout_uuid = $1 ORDER BY id`
+	var result []*ScoutInteraction
+
+	rows, err := db.Query(query, scoutUUID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var si ScoutInteraction
+		var et time.Time
+		err = rows.Scan(&si.Id, &si.Duration, &si.Waypoints, &si.WaypointWidths,
+			&si.WaypointTimes, &si.Processed, &et)
+		if err != nil {
+			return result, err
+		}
+		si.ScoutUUID = scoutUUID
+		si.EnteredAt = et.UTC()
+		result = append(result, &si)
+	}
+
+	return result, rows.Err()
+}
+
+func (si *ScoutInteraction) MarkProcessed(db Queryer) error {
 	const query = `UPDATE scout_interactions SET processed = true WHERE id = $1`
 	_, err := db.Exec(query, si.Id)
 	si.Processed = true
@@ -205,6 +232,57 @@ func GetUnprocessed(db *sql.DB) ([]*ScoutInteraction, error) {
 	return result, rows.Err()
 }
 
+// NextUnprocessed gets the oldest unprocessed interaction, locking it until the end of the
+// transaction db. Interactions locked by other transactions are skipped, as are those that have
+// failed to be summarised maxAttempts times, or have failed since the time since. It returns
+// sql.ErrNoRows when there are no interactions left to summarise.
+func NextUnprocessed(db Queryer, maxAttempts int, since time.Time) (*ScoutInteraction, error) {
+	const query = `SELECT id, scout_uuid, duration, waypoints, waypoint_widths, waypoint_times,
+		processed, entered_at FROM scout_interactions si WHERE processed = false
+		AND NOT EXISTS (SELECT 1 FROM summarise_failures f WHERE f.interaction_id = si.id
+		AND (f.attempts >= $1 OR f.failed_at >= $2))
+		ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	var result ScoutInteraction
+	var et time.Time
+	err := db.QueryRow(query, maxAttempts, since.UTC()).Scan(&result.Id, &result.ScoutUUID,
+		&result.Duration, &result.Waypoints, &result.WaypointWidths, &result.WaypointTimes,
+		&result.Processed, &et)
+	result.EnteredAt = et.UTC()
+
+	return &result, err
+}
+
+// GetSummarisedInteractions returns the processed interactions of a scout that were in view at
+// any time between from and to, in the order they entered.
+func GetSummarisedInteractions(db Queryer, scoutUUID string, from time.Time, to time.Time) ([]*ScoutInteraction, error) {
+	const query = `SELECT id, duration, waypoints, waypoint_widths, waypoint_times, processed, entered_at
+		FROM scout_interactions WHERE scout_uuid = $1 AND processed = true AND entered_at < $3
+		AND entered_at + duration * interval '1 second' >= $2 ORDER BY entered_at, id`
+	var result []*ScoutInteraction
+
+	rows, err := db.Query(query, scoutUUID, from.UTC(), to.UTC())
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var si ScoutInteraction
+		var et time.Time
+		err = rows.Scan(&si.Id, &si.Duration, &si.Waypoints, &si.WaypointWidths,
+			&si.WaypointTimes, &si.Processed, &et)
+		if err != nil {
+			return result, err
+		}
+		si.ScoutUUID = scoutUUID
+		si.EnteredAt = et.UTC()
+		result = append(result, &si)
+	}
+
+	return result, rows.Err()
+}
+
 func NumScoutInteractions(db *sql.DB) (int64, error) {
 	const query = `SELECT COUNT(*) FROM scout_interactions`
 	var result int64
@@ -213,29 +291,59 @@ func NumScoutInteractions(db *sql.DB) (int64, error) {
 	return result, err
 }
 
+// DeleteScoutInteractions deletes the interactions of a scout, along with their metrics and dwells.
 func DeleteScoutInteractions(db *sql.DB, scoutUUID string) error {
-	const query = `DELETE FROM scout_interactions WHERE scout_uuid = $1`
-	_, err := db.Exec(query, scoutUUID)
-	return err
+	for _, table := range []string{"dwells", "interaction_metrics", "scout_interactions"} {
+		_, err := db.Exec(`DELETE FROM `+table+` WHERE scout_uuid = $1`, scoutUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (si *ScoutInteraction) Insert(db *sql.DB) error {
+// PruneInteractions deletes the summarised interactions of every scout that were last in view
+// before the time before, returning the number deleted. Interactions that have yet to be
+// summarised are always kept. The metrics and dwells of the interactions are kept, as they
+// can't be measured again without the path.
+func PruneInteractions(db Queryer, before time.Time) (int64, error) {
+	const query = `DELETE FROM scout_interactions WHERE processed = true
+		AND entered_at + duration * interval '1 second' < $1`
+	r, err := db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return r.RowsAffected()
+}
+
+// InteractionsChannel is notified with the id of each unprocessed interaction as it is inserted,
+// by a trigger on scout_interactions. The notification is only sent once the insert commits.
+const InteractionsChannel = "scout_interactions"
+
+func (si *ScoutInteraction) Insert(db Queryer) error {
 	const query = `INSERT INTO scout_interactions (scout_uuid, duration, waypoints,
 		waypoint_widths, waypoint_times, processed, entered_at) VALUES
 		($1, $2, $3, $4, $5, $6, $7) RETURNING id`
-	return db.QueryRow(query, si.ScoutUUID, si.Duration, si.Waypoints, si.WaypointWidths,
+	err := db.QueryRow(query, si.ScoutUUID, si.Duration, si.Waypoints, si.WaypointWidths,
 		si.WaypointTimes, si.Processed, si.EnteredAt).Scan(&si.Id)
+
+	return err
 }
 
-func ScoutInteractionsAsJSON(db *sql.DB) (string, error) {
-	file := configuration.GetDataDir() + "/scout_interactions.json"
+// exportScoutInteractions returns the interactions of every scout for the data download, with
+// their entry times rounded to the privacy of the scout.
+func exportScoutInteractions(db *sql.DB) ([]ScoutInteraction, error) {
+	privacies, err := GetScoutPrivacies(db)
+	if err != nil {
+		return nil, err
+	}
 
 	const query = `SELECT * FROM scout_interactions`
 	rows, err := db.Query(query)
-	if err == sql.ErrNoRows {
-		return file, nil
-	} else if err != nil {
-		return file, err
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -245,11 +353,55 @@ func ScoutInteractionsAsJSON(db *sql.DB) (string, error) {
 		err = rows.Scan(&si.Id, &si.Duration, &si.Waypoints, &si.WaypointWidths,
 			&si.WaypointTimes, &si.Processed, &si.EnteredAt, &si.ScoutUUID)
 		if err != nil {
-			return file, err
+			return nil, err
 		}
+		si.EnteredAt = privacies[si.ScoutUUID].Round(si.EnteredAt.UTC())
 
 		result = append(result, si)
 	}
 
+	return result, rows.Err()
+}
+
+// ScoutInteractionsAsJSON exports the interactions of every scout, with their entry times rounded
+// to the privacy of the scout.
+func ScoutInteractionsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_interactions.json"
+	result, err := exportScoutInteractions(db)
+	if err != nil {
+		return file, err
+	}
+
 	return file, configuration.SaveAsJSON(result, file)
 }
+
+// ScoutInteractionsAsCSV exports the interactions of every scout, one row per waypoint. Each
+// waypoint carries the id of its interaction, its half width and half height (pixels) and the
+// time (seconds) it was reached after the interaction entered the view of the scout.
+func ScoutInteractionsAsCSV(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_interactions.csv"
+	interactions, err := exportScoutInteractions(db)
+	if err != nil {
+		return file, err
+	}
+
+	records := [][]string{{"InteractionId", "ScoutUUID", "EnteredAt", "Duration", "Processed", "X", "Y",
+		"HalfWidth", "HalfHeight", "TimeOffset"}}
+	for _, si := range interactions {
+		for i, wp := range si.Waypoints {
+			r := []string{formatInt(si.Id), si.ScoutUUID, formatTime(si.EnteredAt),
+				formatFloat32(si.Duration), strconv.FormatBool(si.Processed), strconv.Itoa(wp[0]),
+				strconv.Itoa(wp[1]), "", "", ""}
+			if i < len(si.WaypointWidths) {
+				r[7], r[8] = strconv.Itoa(si.WaypointWidths[i][0]), strconv.Itoa(si.WaypointWidths[i][1])
+			}
+			if i < len(si.WaypointTimes) {
+				r[9] = formatFloat32(si.WaypointTimes[i])
+			}
+
+			records = append(records, r)
+		}
+	}
+
+	return file, configuration.SaveAsCSV(records, file)
+}
@@ -18,10 +18,15 @@
 package models
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"io/ioutil"
+	"os"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -39,11 +44,16 @@ var _ = Describe("Scout Interaction Model", func() {
 			t := time.Now().UTC()
 
 			wp := []Waypoint{Waypoint{1, 2, 3, 4, 0.1}}
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
-			i := Interaction{"abc", "0.1", t, t, 0.1, wp, 1, &s}
+			i := Interaction{"abc", "0.1", t, t, 0.1, wp, 1, &s, [2]kalman{}}
 
 			si := CreateScoutInteraction(&i)
 			Ω(si.ScoutUUID).Should(Equal(""))
@@ -57,8 +67,13 @@ var _ = Describe("Scout Interaction Model", func() {
 
 	Context("Get", func() {
 		It("should be able to get scout interactions as json", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -78,12 +93,79 @@ var _ = Describe("Scout Interaction Model", func() {
 			Ω(err).Should(BeNil())
 			Ω(result).Should(Equal([]ScoutInteraction{si}))
 		})
+
+		It("should round the entry times of exported interactions to the privacy of the scout", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: Privacy{0, 120, 0.0, 0.0, 60.0, 20}}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			et := time.Date(2016, 5, 12, 11, 45, 0, 0, time.UTC)
+			si := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}, [2]int{5, 6}}, Path{[2]int{3, 4}}, RealArray{0.1}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			jsonF, err := ScoutInteractionsAsJSON(db)
+			Ω(err).Should(BeNil())
+
+			jsonB, err := ioutil.ReadFile(jsonF)
+			Ω(err).Should(BeNil())
+
+			var result []ScoutInteraction
+			err = json.Unmarshal(jsonB, &result)
+			Ω(err).Should(BeNil())
+			Ω(len(result)).Should(Equal(1))
+			Ω(result[0].EnteredAt).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+		})
+
+		It("should be able to get scout interactions as csv, one row per waypoint", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			et := time.Date(2016, 5, 12, 11, 45, 0, 0, time.UTC)
+			si := ScoutInteraction{-1, s.UUID, 0.5, Path{[2]int{1, 2}, [2]int{5, 6}}, Path{[2]int{3, 4}, [2]int{7, 8}},
+				RealArray{0.0, 0.25}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			csvF, err := ScoutInteractionsAsCSV(db)
+			Ω(err).Should(BeNil())
+
+			f, err := os.Open(csvF)
+			Ω(err).Should(BeNil())
+			defer f.Close()
+
+			id := strconv.FormatInt(si.Id, 10)
+			result, err := csv.NewReader(f).ReadAll()
+			Ω(err).Should(BeNil())
+			Ω(result).Should(Equal([][]string{
+				{"InteractionId", "ScoutUUID", "EnteredAt", "Duration", "Processed", "X", "Y", "HalfWidth", "HalfHeight", "TimeOffset"},
+				{id, s.UUID, "2016-05-12T11:45:00Z", "0.5", "false", "1", "2", "3", "4", "0"},
+				{id, s.UUID, "2016-05-12T11:45:00Z", "0.5", "false", "5", "6", "7", "8", "0.25"}}))
+		})
 	})
 
 	Context("Insert", func() {
 		It("Should be able to insert a scout interaction", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -96,12 +178,91 @@ var _ = Describe("Scout Interaction Model", func() {
 			Ω(err).Should(BeNil())
 			Ω(si2).Should(Equal(&si))
 		})
+		It("should notify listeners of unprocessed interactions", func() {
+			config, err := configuration.Parse(os.Getenv("GOPATH") + "/scout.json")
+			Ω(err).Should(BeNil())
+			l := pq.NewListener(configuration.DBConnection(config, config.DBTestName), time.Second, time.Second, nil)
+			defer l.Close()
+			err = l.Listen(InteractionsChannel)
+			Ω(err).Should(BeNil())
+
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err = s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1}, true, time.Now()}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si2 := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1}, false, time.Now()}
+			err = si2.Insert(db)
+			Ω(err).Should(BeNil())
+
+			select {
+			case n := <-l.Notify:
+				Ω(n.Extra).Should(Equal(strconv.FormatInt(si2.Id, 10)))
+			case <-time.After(5 * time.Second):
+				Fail("no notification received")
+			}
+		})
+
+		It("should only notify listeners once the interaction is committed", func() {
+			config, err := configuration.Parse(os.Getenv("GOPATH") + "/scout.json")
+			Ω(err).Should(BeNil())
+			l := pq.NewListener(configuration.DBConnection(config, config.DBTestName), time.Second, time.Second, nil)
+			defer l.Close()
+			err = l.Listen(InteractionsChannel)
+			Ω(err).Should(BeNil())
+
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err = s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tx, err := db.Begin()
+			Ω(err).Should(BeNil())
+			si := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1}, false, time.Now()}
+			err = si.Insert(tx)
+			Ω(err).Should(BeNil())
+
+			select {
+			case <-l.Notify:
+				Fail("notified before the interaction was committed")
+			case <-time.After(500 * time.Millisecond):
+			}
+
+			err = tx.Commit()
+			Ω(err).Should(BeNil())
+
+			select {
+			case n := <-l.Notify:
+				Ω(n.Extra).Should(Equal(strconv.FormatInt(si.Id, 10)))
+			case <-time.After(5 * time.Second):
+				Fail("no notification received")
+			}
+		})
 	})
 
 	Context("Delete", func() {
 		It("Should be able to delete interactions for a specified scout", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -124,8 +285,13 @@ var _ = Describe("Scout Interaction Model", func() {
 
 	Context("Unprocessed", func() {
 		It("Should be able to get unproccessed interactions", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -150,8 +316,13 @@ var _ = Describe("Scout Interaction Model", func() {
 
 	Context("MarkProcessed", func() {
 		It("Should be able to mark interactions as processed", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -172,12 +343,17 @@ var _ = Describe("Scout Interaction Model", func() {
 
 	Context("Init scene", func() {
 		It("should be able to init an empty scene", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 			si := InitScene(&s)
-			Ω(*si).Should(Equal(Scene{[]Interaction{}, []Interaction{}, 0, &s}))
+			Ω(*si).Should(Equal(Scene{[]Interaction{}, []Interaction{}, 0, &s, nil, nil}))
 		})
 	})
 
@@ -230,20 +406,89 @@ var _ = Describe("Scout Interaction Model", func() {
 		})
 	})
 
+	Context("timeDouglasPeucker", func() {
+		It("should keep waypoints where the interaction stood still", func() {
+			a := Waypoint{0, 0, 0, 0, 0.0}
+			b := Waypoint{5, 0, 0, 0, 1.0}
+			c := Waypoint{5, 0, 0, 0, 9.0}
+			d := Waypoint{10, 0, 0, 0, 10.0}
+
+			Ω(douglasPeucker([]Waypoint{a, b, c, d}, 1)).Should(Equal([]Waypoint{a, d}))
+			Ω(timeDouglasPeucker([]Waypoint{a, b, c, d}, 1)).Should(Equal([]Waypoint{a, b, c, d}))
+		})
+
+		It("should remove waypoints travelled at a constant speed", func() {
+			a := Waypoint{0, 0, 0, 0, 0.0}
+			b := Waypoint{5, 0, 0, 0, 5.0}
+			c := Waypoint{10, 1, 0, 0, 10.0}
+			d := Waypoint{20, 0, 0, 0, 20.0}
+
+			Ω(timeDouglasPeucker([]Waypoint{a, b, c, d}, 2)).Should(Equal([]Waypoint{a, d}))
+		})
+	})
+
+	Context("dwellSpans", func() {
+		path := []Waypoint{Waypoint{0, 0, 5, 5, 0.0}, Waypoint{50, 0, 5, 5, 1.0},
+			Waypoint{52, 1, 5, 5, 2.0}, Waypoint{49, 2, 5, 5, 6.0}, Waypoint{51, 0, 5, 5, 8.0},
+			Waypoint{100, 0, 5, 5, 9.0}, Waypoint{101, 0, 5, 5, 10.0}}
+
+		It("should find where the interaction stood still", func() {
+			Ω(dwellSpans(path, 25, 5.0)).Should(Equal([][2]int{[2]int{1, 4}}))
+			Ω(dwellSpans(path, 25, 1.0)).Should(Equal([][2]int{[2]int{1, 4}, [2]int{5, 6}}))
+			Ω(dwellSpans(path, 25, 10.0)).Should(Equal([][2]int{}))
+		})
+
+		It("should not find dwells when they are disabled", func() {
+			Ω(dwellSpans(path, 25, 0.0)).Should(Equal([][2]int{}))
+		})
+
+		It("should locate the dwell at the mean position of the interaction", func() {
+			Ω(newDwell(path, [2]int{1, 4})).Should(Equal(Dwell{-1, -1, "", 50, 0, 1.0, 7.0}))
+		})
+	})
+
+	Context("simplify", func() {
+		It("should keep the start and end of each dwell", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			i := Interaction{s.UUID, "0.1", time.Time{}, time.Time{}, 20.0, []Waypoint{
+				Waypoint{0, 0, 5, 5, 0.0}, Waypoint{30, 0, 5, 5, 1.0}, Waypoint{60, 0, 5, 5, 2.0},
+				Waypoint{61, 0, 5, 5, 6.0}, Waypoint{60, 1, 5, 5, 12.0}, Waypoint{90, 0, 5, 5, 13.0},
+				Waypoint{120, 0, 5, 5, 14.0}}, 0, &s, [2]kalman{}}
+
+			spans := dwellSpans(i.Path, s.DwellSqDistance, s.DwellDuration)
+			Ω(spans).Should(Equal([][2]int{[2]int{2, 4}}))
+
+			i.simplify(spans)
+			Ω(i.Path).Should(Equal([]Waypoint{Waypoint{0, 0, 5, 5, 0.0}, Waypoint{60, 0, 5, 5, 2.0},
+				Waypoint{60, 1, 5, 5, 12.0}, Waypoint{120, 0, 5, 5, 14.0}}))
+		})
+	})
+
 	Context("NewInteraction", func() {
 		It("should create a new interaction", func() {
 			a := Waypoint{0, 0, 0, 0, 0.0}
-			tr := time.Now().UTC().Round(15 * time.Minute)
-
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			t := time.Date(2016, 5, 12, 10, 20, 0, 0, time.UTC)
+
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
-			i := NewInteraction(a, 0, &s)
+			i := NewInteraction(a, 0, &s, t)
 			Ω(i.UUID).Should(Equal(s.UUID))
 			Ω(i.Version).Should(Equal("0.1"))
-			Ω(i.Entered).Should(Equal(tr))
+			Ω(i.Entered).Should(Equal(t))
 			Ω(i.Duration).Should(BeNumerically("~", float32(0.0), 0.007))
 			Ω(i.Equal([]Waypoint{a})).Should(BeTrue())
 		})
@@ -252,14 +497,19 @@ var _ = Describe("Scout Interaction Model", func() {
 			a := Waypoint{0, 0, 0, 0, 0.0}
 			b := Waypoint{1, 1, 1, 1, 0.005}
 
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
-			i := NewInteraction(a, 0, &s)
-			time.Sleep(50 * time.Millisecond)
-			i.addWaypoint(b)
+			t := time.Now().UTC()
+			i := NewInteraction(a, 0, &s, t)
+			i.addWaypoint(b, t.Add(50*time.Millisecond))
 			Ω(i.Duration).Should(BeNumerically("~", float32(0.05), 0.007))
 			Ω(len(i.Path)).Should(Equal(2))
 		})
@@ -272,57 +522,77 @@ var _ = Describe("Scout Interaction Model", func() {
 		wpB := Waypoint{50, 50, 20, 20, 0.0}
 		wpBA := Waypoint{55, 53, 20, 20, 0.0}
 		wpC := Waypoint{150, 150, 20, 20, 0.0}
+		t := time.Now().UTC()
 
 		It("should be able to add an interaction to an empty scene", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA})
+			si.addInteraction([]Waypoint{wpA}, t)
 
 			Ω(len(si.Interactions)).Should(Equal(1))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpA})).Should(BeTrue())
 		})
 
 		It("should be able to add multiple interactions to an empty scene,", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA, wpB})
+			si.addInteraction([]Waypoint{wpA, wpB}, t)
 
 			Ω(len(si.Interactions)).Should(Equal(2))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpA})).Should(BeTrue())
 			Ω(si.Interactions[1].Equal([]Waypoint{wpB})).Should(BeTrue())
 		})
 
-		It("should list the interaction start time truncated to 30 mins", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+		It("should keep the exact start time of the interaction", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA})
+			si.addInteraction([]Waypoint{wpA}, t)
 
-			Ω(si.Interactions[0].Entered).Should(Equal(time.Now().UTC().Round(15 * time.Minute)))
+			Ω(si.Interactions[0].Entered).Should(Equal(t.UTC()))
 		})
 
 		It("should be able to add an interaction to a scene with stuff already going on", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA})
+			si.addInteraction([]Waypoint{wpA}, t)
 
-			time.Sleep(100 * time.Millisecond)
-			si.addInteraction([]Waypoint{wpAA, wpB})
+			si.addInteraction([]Waypoint{wpAA, wpB}, t.Add(100*time.Millisecond))
 
 			Ω(len(si.Interactions)).Should(Equal(2))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpA, wpAAT})).Should(BeTrue())
@@ -330,15 +600,20 @@ var _ = Describe("Scout Interaction Model", func() {
 		})
 
 		It("should be able to add multiple interactions to a scene with stuff already going on", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA})
-			si.addInteraction([]Waypoint{wpAA, wpB})
-			si.addInteraction([]Waypoint{wpAA, wpBA, wpC})
+			si.addInteraction([]Waypoint{wpA}, t)
+			si.addInteraction([]Waypoint{wpAA, wpB}, t)
+			si.addInteraction([]Waypoint{wpAA, wpBA, wpC}, t)
 
 			Ω(len(si.Interactions)).Should(Equal(3))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpA, wpAA, wpAA})).Should(BeTrue())
@@ -347,28 +622,38 @@ var _ = Describe("Scout Interaction Model", func() {
 		})
 
 		It("should be able to remove interactions when a person leaves the scene", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA, wpB})
-			si.removeInteraction([]Waypoint{wpAA})
+			si.addInteraction([]Waypoint{wpA, wpB}, t)
+			si.removeInteraction([]Waypoint{wpAA}, t)
 
 			Ω(len(si.Interactions)).Should(Equal(1))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpA, wpAA})).Should(BeTrue())
 		})
 
 		It("should be able to remove multiple interactions when more than one person leaves the scene", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			si := InitScene(&s)
-			si.addInteraction([]Waypoint{wpA, wpB, wpC})
-			si.removeInteraction([]Waypoint{wpBA})
+			si.addInteraction([]Waypoint{wpA, wpB, wpC}, t)
+			si.removeInteraction([]Waypoint{wpBA}, t)
 
 			Ω(len(si.Interactions)).Should(Equal(1))
 			Ω(si.Interactions[0].Equal([]Waypoint{wpB, wpBA})).Should(BeTrue())
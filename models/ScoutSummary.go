@@ -21,14 +21,36 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"github.com/MeasureTheFuture/mothership/configuration"
+	"github.com/MeasureTheFuture/scout/configuration"
 	_ "github.com/lib/pq"
+	"math"
 	"strconv"
-	"strings"
 )
 
-type Buckets [configuration.HBuckets][configuration.WBuckets]float32
-type IntBuckets [configuration.HBuckets][configuration.WBuckets]int
+// Buckets holds a value for each bucket of the grid a frame is broken into, indexed by column
+// then row.
+type Buckets [][]float32
+type IntBuckets [][]int
+
+// NewBuckets creates empty buckets for a grid w buckets wide and h buckets high.
+func NewBuckets(w int, h int) Buckets {
+	result := make(Buckets, w)
+	for i := range result {
+		result[i] = make([]float32, h)
+	}
+
+	return result
+}
+
+// NewIntBuckets creates empty buckets for a grid w buckets wide and h buckets high.
+func NewIntBuckets(w int, h int) IntBuckets {
+	result := make(IntBuckets, w)
+	for i := range result {
+		result[i] = make([]int, h)
+	}
+
+	return result
+}
 
 type ScoutSummary struct {
 	ScoutUUID        string
@@ -37,72 +59,107 @@ type ScoutSummary struct {
 	VisitorBuckets   IntBuckets
 }
 
-func (b IntBuckets) Value() (driver.Value, error) {
-	res := "{"
-	for i, r := range b {
-		res = res + "{"
+// NewScoutSummary creates an empty summary for the scout with the supplied UUID, with buckets
+// for the grid of frame f.
+func NewScoutSummary(scoutUUID string, f Frame) *ScoutSummary {
+	return &ScoutSummary{scoutUUID, 0, NewBuckets(f.WBuckets, f.HBuckets),
+		NewIntBuckets(f.WBuckets, f.HBuckets)}
+}
 
-		for j, v := range r {
-			res = res + strconv.Itoa(v)
+// Grid returns the number of buckets across and down the summary.
+func (ss *ScoutSummary) Grid() (int, int) {
+	if len(ss.VisitTimeBuckets) == 0 {
+		return 0, 0
+	}
 
-			if j < len(r)-1 {
-				res = res + ","
-			}
-		}
+	return len(ss.VisitTimeBuckets), len(ss.VisitTimeBuckets[0])
+}
 
-		res = res + "}"
-		if i < len(r)-1 {
-			res = res + ","
+// Add adds the summary o to ss. Both summaries must be broken into the same grid.
+func (ss *ScoutSummary) Add(o *ScoutSummary) error {
+	w, h := ss.Grid()
+	ow, oh := o.Grid()
+	if w != ow || h != oh || len(o.VisitorBuckets) != ow {
+		return errors.New("Unable to add summaries with different grids")
+	}
+
+	ss.VisitorCount += o.VisitorCount
+	for i := range o.VisitTimeBuckets {
+		for j := range o.VisitTimeBuckets[i] {
+			ss.VisitTimeBuckets[i][j] += o.VisitTimeBuckets[i][j]
+			ss.VisitorBuckets[i][j] += o.VisitorBuckets[i][j]
 		}
 	}
-	res = res + "}"
 
-	return res, nil
+	return nil
 }
 
-func (b Buckets) Value() (driver.Value, error) {
-	res := "{"
-	for i, r := range b {
-		res = res + "{"
-
-		for j, v := range r {
-			res = res + strconv.FormatFloat(float64(v), 'f', -1, 32)
+// Suppress zeroes the counts of fewer than k visitors, along with the time spent in the buckets
+// they were counted in, so that the summary can be shared without singling anyone out.
+func (ss *ScoutSummary) Suppress(k int) {
+	if ss.VisitorCount < int64(k) {
+		ss.VisitorCount = 0
+	}
 
-			if j < len(r)-1 {
-				res = res + ","
+	for i := range ss.VisitorBuckets {
+		for j := range ss.VisitorBuckets[i] {
+			if ss.VisitorBuckets[i][j] < k {
+				ss.VisitorBuckets[i][j] = 0
+				ss.VisitTimeBuckets[i][j] = 0.0
 			}
 		}
+	}
+}
 
-		res = res + "}"
-		if i < len(r)-1 {
-			res = res + ","
-		}
+func (b IntBuckets) Value() (driver.Value, error) {
+	cols := 0
+	if len(b) > 0 {
+		cols = len(b[0])
 	}
-	res = res + "}"
 
-	return res, nil
+	return formatArray(len(b), cols, func(i int, j int) string {
+		return strconv.Itoa(b[i][j])
+	}), nil
 }
 
-func (b *IntBuckets) Scan(value interface{}) error {
-	asBytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("Unable to deserialise IntBuckets")
+func (b Buckets) Value() (driver.Value, error) {
+	cols := 0
+	if len(b) > 0 {
+		cols = len(b[0])
 	}
 
-	asString := string(asBytes)
-	asString = asString[2 : len(asString)-2]
-	elements := strings.Split(asString, "},{")
+	return formatArray(len(b), cols, func(i int, j int) string {
+		v := float64(b[i][j])
+		switch {
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+
+		return strconv.FormatFloat(v, 'g', -1, 32)
+	}), nil
+}
 
-	var res IntBuckets
+func (b *IntBuckets) Scan(value interface{}) error {
+	elements, err := parseArray(value)
+	if err != nil {
+		return err
+	}
+
+	res := make(IntBuckets, len(elements))
 	for i, r := range elements {
-		wp := strings.Split(r, ",")
-		for j, v := range wp {
-			bv, err := strconv.Atoi(v)
+		res[i] = make([]int, len(r))
+
+		for j, v := range r {
+			if v == "" {
+				continue
+			}
+
+			res[i][j], err = strconv.Atoi(v)
 			if err != nil {
 				return err
 			}
-
-			res[i][j] = bv
 		}
 	}
 
@@ -111,24 +168,24 @@ func (b *IntBuckets) Scan(value interface{}) error {
 }
 
 func (b *Buckets) Scan(value interface{}) error {
-	asBytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("Unable to deserialise Buckets")
+	elements, err := parseArray(value)
+	if err != nil {
+		return err
 	}
 
-	asString := string(asBytes)
-	asString = asString[2 : len(asString)-2]
-	elements := strings.Split(asString, "},{")
-
-	var res Buckets
+	res := make(Buckets, len(elements))
 	for i, r := range elements {
-		wp := strings.Split(r, ",")
-		for j, v := range wp {
+		res[i] = make([]float32, len(r))
+
+		for j, v := range r {
+			if v == "" {
+				continue
+			}
+
 			bv, err := strconv.ParseFloat(v, 32)
 			if err != nil {
 				return err
 			}
-
 			res[i][j] = float32(bv)
 		}
 	}
@@ -137,7 +194,7 @@ func (b *Buckets) Scan(value interface{}) error {
 	return nil
 }
 
-func GetScoutSummaryByUUID(db *sql.DB, scoutUUID string) (*ScoutSummary, error) {
+func GetScoutSummaryByUUID(db Queryer, scoutUUID string) (*ScoutSummary, error) {
 	const query = `SELECT visitor_count, visit_time_buckets, visitor_buckets FROM scout_summaries WHERE scout_uuid = $1`
 
 	var result ScoutSummary
@@ -147,10 +204,24 @@ func GetScoutSummaryByUUID(db *sql.DB, scoutUUID string) (*ScoutSummary, error)
 	return &result, err
 }
 
+// GetScoutSummaryForUpdate gets the summary of a scout, locking it until the end of the
+// transaction db so that nothing else can update it in the meantime.
+func GetScoutSummaryForUpdate(db Queryer, scoutUUID string) (*ScoutSummary, error) {
+	const query = `SELECT visitor_count, visit_time_buckets, visitor_buckets FROM scout_summaries
+				   WHERE scout_uuid = $1 FOR UPDATE`
+
+	var result ScoutSummary
+	err := db.QueryRow(query, scoutUUID).Scan(&result.VisitorCount, &result.VisitTimeBuckets, &result.VisitorBuckets)
+	result.ScoutUUID = scoutUUID
+
+	return &result, err
+}
+
 func (si *ScoutSummary) Clear(db *sql.DB) error {
+	w, h := si.Grid()
 	si.VisitorCount = 0
-	si.VisitTimeBuckets = Buckets{}
-	si.VisitorBuckets = IntBuckets{}
+	si.VisitTimeBuckets = NewBuckets(w, h)
+	si.VisitorBuckets = NewIntBuckets(w, h)
 
 	return si.Update(db)
 }
@@ -162,22 +233,26 @@ func (si *ScoutSummary) Insert(db *sql.DB) error {
 	return err
 }
 
-func (si *ScoutSummary) Update(db *sql.DB) error {
+func (si *ScoutSummary) Update(db Queryer) error {
 	const query = `UPDATE scout_summaries SET visitor_count = $1, visit_time_buckets = $2, visitor_buckets = $3 WHERE scout_uuid = $4`
 	_, err := db.Exec(query, si.VisitorCount, si.VisitTimeBuckets, si.VisitorBuckets, si.ScoutUUID)
 
 	return err
 }
 
-func ScoutSummariesAsJSON(db *sql.DB) (string, error) {
-	file := configuration.GetDataDir() + "/scout_summaries.json"
+// exportScoutSummaries publishes the summary of every scout for the data download under the
+// privacy of the scout, spending the budget of the scout on release. The summaries of scouts that
+// have spent their privacy budget are left out.
+func exportScoutSummaries(db *sql.DB, release string) ([]ScoutSummary, error) {
+	privacies, err := GetScoutPrivacies(db)
+	if err != nil {
+		return nil, err
+	}
 
 	const query = `SELECT scout_uuid, visitor_count, visit_time_buckets, visitor_buckets FROM scout_summaries`
 	rows, err := db.Query(query)
-	if err == sql.ErrNoRows {
-		return file, nil
-	} else if err != nil {
-		return file, err
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -186,11 +261,47 @@ func ScoutSummariesAsJSON(db *sql.DB) (string, error) {
 		var ss ScoutSummary
 		err = rows.Scan(&ss.ScoutUUID, &ss.VisitorCount, &ss.VisitTimeBuckets, &ss.VisitorBuckets)
 		if err != nil {
-			return file, err
+			return nil, err
+		}
+
+		err = ss.Publish(db, privacies[ss.ScoutUUID], release)
+		if err == ErrBudgetSpent {
+			continue
+		} else if err != nil {
+			return nil, err
 		}
 
 		result = append(result, ss)
 	}
 
+	return result, rows.Err()
+}
+
+// ScoutSummariesAsJSON publishes the summary of every scout under the privacy of the scout. The
+// summaries of scouts that have spent their privacy budget are left out.
+func ScoutSummariesAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_summaries.json"
+	result, err := exportScoutSummaries(db, "scout_summaries.json")
+	if err != nil {
+		return file, err
+	}
+
 	return file, configuration.SaveAsJSON(result, file)
 }
+
+// ScoutSummariesAsCSV publishes the summary of every scout under the privacy of the scout, one
+// row per bucket. The summaries of scouts that have spent their privacy budget are left out.
+func ScoutSummariesAsCSV(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_summaries.csv"
+	summaries, err := exportScoutSummaries(db, "scout_summaries.csv")
+	if err != nil {
+		return file, err
+	}
+
+	records := [][]string{{"ScoutUUID", "VisitorCount", "X", "Y", "VisitTime", "Visitors"}}
+	for i := range summaries {
+		records = bucketRecords(records, []string{summaries[i].ScoutUUID}, &summaries[i])
+	}
+
+	return file, configuration.SaveAsCSV(records, file)
+}
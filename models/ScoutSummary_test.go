@@ -18,10 +18,12 @@
 package models
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -33,21 +35,117 @@ func TestScoutSummary(t *testing.T) {
 var _ = Describe("Scout Summary Model", func() {
 	AfterEach(cleaner)
 
+	Context("Buckets", func() {
+		It("should write and read back a grid of any size", func() {
+			b := NewBuckets(3, 2)
+			b[0][1] = 0.5
+			b[2][0] = 1e6
+			v, err := b.Value()
+			Ω(err).Should(BeNil())
+			Ω(v).Should(Equal("{{0,0.5},{0,0},{1e+06,0}}"))
+
+			var b2 Buckets
+			err = b2.Scan([]byte(v.(string)))
+			Ω(err).Should(BeNil())
+			Ω(b2).Should(Equal(b))
+
+			ib := NewIntBuckets(1, 3)
+			ib[0][2] = 7
+			v, err = ib.Value()
+			Ω(err).Should(BeNil())
+			Ω(v).Should(Equal("{{0,0,7}}"))
+
+			var ib2 IntBuckets
+			err = ib2.Scan(v)
+			Ω(err).Should(BeNil())
+			Ω(ib2).Should(Equal(ib))
+		})
+
+		It("should read arrays with whitespace, quotes, NULLs and explicit bounds", func() {
+			var b IntBuckets
+			err := b.Scan([]byte(`[0:1][1:2]={ {1, "2"} , {NULL,4} }`))
+			Ω(err).Should(BeNil())
+			Ω(b).Should(Equal(IntBuckets{[]int{1, 2}, []int{0, 4}}))
+
+			err = b.Scan([]byte("{}"))
+			Ω(err).Should(BeNil())
+			Ω(len(b)).Should(Equal(0))
+		})
+
+		It("should reject arrays that aren't a grid", func() {
+			var b Buckets
+			Ω(b.Scan([]byte("{{1,2},{3}}"))).ShouldNot(BeNil())
+			Ω(b.Scan([]byte("{1,2}"))).ShouldNot(BeNil())
+			Ω(b.Scan([]byte("{{1,2}"))).ShouldNot(BeNil())
+			Ω(b.Scan([]byte("{{1,a}}"))).ShouldNot(BeNil())
+			Ω(b.Scan(12)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Add", func() {
+		It("should only add summaries with the same grid", func() {
+			a := NewScoutSummary("", Frame{640, 480, 4, 3})
+			b := NewScoutSummary("", Frame{640, 480, 4, 3})
+			b.VisitorCount = 2
+			b.VisitorBuckets[3][2] = 2
+			b.VisitTimeBuckets[3][2] = 1.5
+
+			Ω(a.Add(b)).Should(BeNil())
+			Ω(a.Add(b)).Should(BeNil())
+			Ω(a.VisitorCount).Should(Equal(int64(4)))
+			Ω(a.VisitorBuckets[3][2]).Should(Equal(4))
+			Ω(a.VisitTimeBuckets[3][2]).Should(Equal(float32(3.0)))
+
+			Ω(a.Add(NewScoutSummary("", Frame{640, 480, 3, 4}))).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Suppress", func() {
+		It("should zero the buckets of fewer than k visitors", func() {
+			ss := NewScoutSummary("", Frame{640, 480, 2, 2})
+			ss.VisitorCount = 4
+			ss.VisitorBuckets = IntBuckets{{1, 3}, {0, 2}}
+			ss.VisitTimeBuckets = Buckets{{0.5, 6.0}, {0.25, 2.0}}
+
+			ss.Suppress(2)
+			Ω(ss.VisitorCount).Should(Equal(int64(4)))
+			Ω(ss.VisitorBuckets).Should(Equal(IntBuckets{{0, 3}, {0, 2}}))
+			Ω(ss.VisitTimeBuckets).Should(Equal(Buckets{{0.0, 6.0}, {0.0, 2.0}}))
+
+			ss.Suppress(5)
+			Ω(ss.VisitorCount).Should(Equal(int64(0)))
+			Ω(ss.VisitorBuckets).Should(Equal(NewIntBuckets(2, 2)))
+			Ω(ss.VisitTimeBuckets).Should(Equal(NewBuckets(2, 2)))
+		})
+	})
+
 	Context("Insert", func() {
 		It("Scout insert should create matching scout summary", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			ss, err := GetScoutSummaryByUUID(db, s.UUID)
 			Ω(err).Should(BeNil())
-			Ω(ss).Should(Equal(&ScoutSummary{s.UUID, 0, Buckets{}, IntBuckets{}}))
+			Ω(ss).Should(Equal(NewScoutSummary(s.UUID, DefaultFrame)))
+			Ω(len(ss.VisitTimeBuckets)).Should(Equal(20))
+			Ω(len(ss.VisitorBuckets[0])).Should(Equal(20))
 		})
 
 		It("Should be able to update existing scout summary.", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -68,8 +166,13 @@ var _ = Describe("Scout Summary Model", func() {
 	Context("Get", func() {
 		It("should be able to get scout healths as json", func() {
 			ss := ScoutSummary{}
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ss,
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ss, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			ss.ScoutUUID = s.UUID
 			Ω(err).Should(BeNil())
@@ -85,12 +188,49 @@ var _ = Describe("Scout Summary Model", func() {
 			Ω(err).Should(BeNil())
 			Ω(result).Should(Equal([]ScoutSummary{ss}))
 		})
+
+		It("should be able to get scout summaries as csv, one row per bucket", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			ss := ScoutSummary{s.UUID, 3, Buckets{{1.5, 0.0}, {2.0, 0.5}}, IntBuckets{{2, 0}, {1, 1}}}
+			err = ss.Update(db)
+			Ω(err).Should(BeNil())
+
+			csvF, err := ScoutSummariesAsCSV(db)
+			Ω(err).Should(BeNil())
+
+			f, err := os.Open(csvF)
+			Ω(err).Should(BeNil())
+			defer f.Close()
+
+			result, err := csv.NewReader(f).ReadAll()
+			Ω(err).Should(BeNil())
+			Ω(result).Should(Equal([][]string{
+				{"ScoutUUID", "VisitorCount", "X", "Y", "VisitTime", "Visitors"},
+				{s.UUID, "3", "0", "0", "1.5", "2"},
+				{s.UUID, "3", "0", "1", "0", "0"},
+				{s.UUID, "3", "1", "0", "2", "1"},
+				{s.UUID, "3", "1", "1", "0.5", "1"}}))
+		})
 	})
 
 	Context("Clear", func() {
 		It("Should be able to clear an existing scout summary", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -0,0 +1,190 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// formatArray writes a two dimensional array with rows of cols elements in the Postgres array
+// format, the element at i, j is formatted by element. Postgres has no empty nested arrays, so
+// an array without any elements is written as an empty array.
+func formatArray(rows int, cols int, element func(i int, j int) string) string {
+	if rows == 0 || cols == 0 {
+		return "{}"
+	}
+
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		b.WriteString("{")
+		for j := 0; j < cols; j++ {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(element(i, j))
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("}")
+
+	return b.String()
+}
+
+// arrayParser reads the Postgres array format.
+type arrayParser struct {
+	s string
+	i int
+}
+
+func (p *arrayParser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *arrayParser) expect(c byte) error {
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != c {
+		return errors.New("Unable to deserialise array, expected '" + string(c) + "'")
+	}
+	p.i++
+
+	return nil
+}
+
+// element reads a single, possibly quoted, element. Unquoted NULLs are read as empty strings.
+func (p *arrayParser) element() (string, error) {
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '"' {
+		var b strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			switch p.s[p.i] {
+			case '\\':
+				p.i++
+				if p.i < len(p.s) {
+					b.WriteByte(p.s[p.i])
+				}
+			case '"':
+				p.i++
+				return b.String(), nil
+			default:
+				b.WriteByte(p.s[p.i])
+			}
+		}
+
+		return "", errors.New("Unable to deserialise array, unterminated quote")
+	}
+
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] != ',' && p.s[p.i] != '}' {
+		p.i++
+	}
+
+	e := strings.TrimSpace(p.s[start:p.i])
+	if e == "" {
+		return "", errors.New("Unable to deserialise array, missing element")
+	} else if strings.EqualFold(e, "NULL") {
+		return "", nil
+	}
+
+	return e, nil
+}
+
+// list reads the comma separated items between a pair of braces, calling item for each of them.
+func (p *arrayParser) list(item func() error) error {
+	err := p.expect('{')
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '}' {
+		p.i++
+		return nil
+	}
+
+	for {
+		err = item()
+		if err != nil {
+			return err
+		}
+
+		p.skipSpace()
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+
+		return p.expect('}')
+	}
+}
+
+// parseArray reads a two dimensional array in the Postgres array format, returning the elements
+// of each row. Any dimension decoration (e.g. '[0:1][0:1]=') is ignored, elements may be quoted
+// and NULL elements are returned as empty strings. Every row must be the same length.
+func parseArray(value interface{}) ([][]string, error) {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return nil, errors.New("Unable to deserialise array")
+	}
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		s = s[strings.Index(s, "=")+1:]
+	}
+
+	result := [][]string{}
+	p := arrayParser{s, 0}
+	err := p.list(func() error {
+		row := []string{}
+		err := p.list(func() error {
+			e, err := p.element()
+			row = append(row, e)
+			return err
+		})
+
+		result = append(result, row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.i != len(p.s) {
+		return nil, errors.New("Unable to deserialise array, unexpected trailing characters")
+	}
+
+	for _, r := range result {
+		if len(r) != len(result[0]) {
+			return nil, errors.New("Unable to deserialise array, rows differ in length")
+		}
+	}
+
+	return result, nil
+}
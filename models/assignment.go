@@ -0,0 +1,145 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"math"
+)
+
+// forbidden is the cost used for pairings that must never be selected.
+const forbidden = 1.0e12
+
+// hungarian solves the square assignment problem for the supplied cost matrix,
+// returning the column assigned to each row such that the total cost is minimal.
+func hungarian(costs [][]float64) []int {
+	n := len(costs)
+
+	// Potentials and the matching are 1-indexed, with index 0 used as a sentinel.
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	p := make([]int, n+1)   // The row matched to each column.
+	way := make([]int, n+1) // The previous column in the augmenting path.
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minV := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minV {
+			minV[j] = math.Inf(1)
+		}
+
+		for p[j0] != 0 {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+
+			for j := 1; j <= n; j++ {
+				if !used[j] {
+					cur := costs[i0-1][j-1] - u[i0] - v[j]
+					if cur < minV[j] {
+						minV[j] = cur
+						way[j] = j0
+					}
+
+					if minV[j] < delta {
+						delta = minV[j]
+						j1 = j
+					}
+				}
+			}
+
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minV[j] -= delta
+				}
+			}
+
+			j0 = j1
+		}
+
+		// Unwind the augmenting path.
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	result := make([]int, n)
+	for j := 1; j <= n; j++ {
+		result[p[j]-1] = j - 1
+	}
+
+	return result
+}
+
+// assign pairs rows with columns in the supplied (rows x cols) cost matrix such
+// that the total cost is minimal. Leaving a row or column unpaired costs half of
+// gate, so only pairings cheaper than gate are ever made. The result contains the
+// column paired with each row, or -1 when the row is left unpaired.
+func assign(costs [][]float64, gate float64) []int {
+	rows := len(costs)
+	if rows == 0 {
+		return []int{}
+	}
+	cols := len(costs[0])
+
+	// Build an augmented square matrix, where each row and each column has a
+	// private 'unpaired' option:
+	//
+	//   | costs          | row unpaired |
+	//   | column unpaired| 0            |
+	n := rows + cols
+	m := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		m[i] = make([]float64, n)
+
+		for j := 0; j < n; j++ {
+			switch {
+			case i < rows && j < cols:
+				m[i][j] = math.Min(costs[i][j], forbidden)
+			case i < rows && j >= cols:
+				m[i][j] = forbidden
+				if j-cols == i {
+					m[i][j] = gate / 2.0
+				}
+			case i >= rows && j < cols:
+				m[i][j] = forbidden
+				if i-rows == j {
+					m[i][j] = gate / 2.0
+				}
+			}
+		}
+	}
+
+	h := hungarian(m)
+	result := make([]int, rows)
+	for i := 0; i < rows; i++ {
+		result[i] = -1
+		if h[i] < cols && costs[i][h[i]] < gate {
+			result[i] = h[i]
+		}
+	}
+
+	return result
+}
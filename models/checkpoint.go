@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// interactionCheckpoint is an interaction along with the private state needed to keep
+// tracking it after a restart.
+type interactionCheckpoint struct {
+	Entered  time.Time
+	Started  time.Time
+	Duration float32
+	Path     []Waypoint
+	SceneID  int
+	Motion   [2]kalman
+}
+
+// sceneCheckpoint is a snapshot of everything being tracked within a scene.
+type sceneCheckpoint struct {
+	ScoutUUID        string
+	SId              int
+	Interactions     []interactionCheckpoint
+	IdleInteractions []interactionCheckpoint
+	SavedAt          time.Time
+}
+
+func checkpointInteractions(interactions []Interaction) []interactionCheckpoint {
+	result := make([]interactionCheckpoint, len(interactions))
+	for k, i := range interactions {
+		result[k] = interactionCheckpoint{i.Entered, i.started, i.Duration, i.Path, i.SceneID, i.motion}
+	}
+
+	return result
+}
+
+func (s *Scene) restoreInteractions(checkpoints []interactionCheckpoint) []Interaction {
+	result := make([]Interaction, len(checkpoints))
+	for k, c := range checkpoints {
+		result[k] = Interaction{s.dScout.UUID, "0.1", c.Entered, c.Started, c.Duration, c.Path,
+			c.SceneID, s.dScout, c.Motion}
+	}
+
+	return result
+}
+
+// writeSynced replaces filename with b atomically. The new file is flushed to disk before it
+// replaces the old one, and the directory after, so that a power cut leaves one or the other
+// rather than an empty or truncated file.
+func writeSynced(filename string, b []byte) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	cErr := f.Close()
+	if err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, filename)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// Checkpoint writes the interactions being tracked at time t to filename, so that they can be
+// restored if the scout stops without closing the scene. The file is replaced atomically, a
+// crash or power cut part way through leaves the previous checkpoint in place.
+func (s *Scene) Checkpoint(filename string, t time.Time) error {
+	c := sceneCheckpoint{s.dScout.UUID, s.sId, checkpointInteractions(s.Interactions),
+		checkpointInteractions(s.IdleInteractions), t.UTC()}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return writeSynced(filename, b)
+}
+
+// Restore resumes tracking the interactions in the checkpoint filename. Interactions that
+// have not been seen for longer than the idle duration at time t are finished and saved to db
+// rather than restored, so a long outage doesn't join visitors on either side of it.
+func (s *Scene) Restore(db *sql.DB, filename string, t time.Time) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var c sceneCheckpoint
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return err
+	}
+
+	if c.ScoutUUID != s.dScout.UUID {
+		return errors.New("Checkpoint was written by a different scout")
+	}
+
+	s.sId = c.SId
+	s.Interactions = s.expire(db, s.restoreInteractions(c.Interactions), t)
+	s.IdleInteractions = s.expire(db, s.restoreInteractions(c.IdleInteractions), t)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Checkpoint Suite")
+}
+
+// memorySink keeps the interactions saved by a scene in memory.
+type memorySink struct {
+	saved []*ScoutInteraction
+}
+
+func (m *memorySink) Save(si *ScoutInteraction, dwells []Dwell) error {
+	m.saved = append(m.saved, si)
+	return nil
+}
+
+var _ = Describe("Checkpoint", func() {
+	var dir string
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "checkpoint")
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should carry on tracking interactions from a checkpoint", func() {
+		s := Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+			Port: 8080, Authorised: true, Name: "foo", State: "measuring", Summary: &ScoutSummary{},
+			MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+			MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 2.0,
+			ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000,
+			ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: Masks{},
+			MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame,
+			Privacy: DefaultPrivacy}
+		uninterrupted := InitScene(&s)
+		si := InitScene(&s)
+
+		for k := 0; k < 4; k++ {
+			a := Waypoint{100 + k*50, 100, 20, 20, 0.0}
+			b := Waypoint{475 - k*50, 110, 20, 20, 0.0}
+			tk := t.Add(time.Duration(k) * 100 * time.Millisecond)
+			uninterrupted.Update(nil, []Waypoint{b, a}, tk)
+			si.Update(nil, []Waypoint{b, a}, tk)
+		}
+
+		file := filepath.Join(dir, ".mtf-scene")
+		Ω(si.Checkpoint(file, t.Add(300*time.Millisecond))).Should(BeNil())
+
+		restored := InitScene(&s)
+		Ω(restored.Restore(nil, file, t.Add(400*time.Millisecond))).Should(BeNil())
+		Ω(restored.sId).Should(Equal(si.sId))
+		Ω(restored.Interactions).Should(Equal(si.Interactions))
+
+		// The restored scene keeps identities through the crossing, just like the scene
+		// that was never interrupted.
+		for k := 4; k < 8; k++ {
+			a := Waypoint{100 + k*50, 100, 20, 20, 0.0}
+			b := Waypoint{475 - k*50, 110, 20, 20, 0.0}
+			tk := t.Add(time.Duration(k) * 100 * time.Millisecond)
+			uninterrupted.Update(nil, []Waypoint{b, a}, tk)
+			restored.Update(nil, []Waypoint{b, a}, tk)
+		}
+
+		Ω(restored.Interactions).Should(Equal(uninterrupted.Interactions))
+		Ω(restored.Interactions[0].started).Should(Equal(t))
+	})
+
+	It("should replace a previous checkpoint in place", func() {
+		file := filepath.Join(dir, ".mtf-scene")
+		Ω(ioutil.WriteFile(file, []byte("old"), 0644)).Should(BeNil())
+
+		Ω(writeSynced(file, []byte("new"))).Should(BeNil())
+
+		b, err := ioutil.ReadFile(file)
+		Ω(err).Should(BeNil())
+		Ω(string(b)).Should(Equal("new"))
+
+		_, err = os.Stat(file + ".tmp")
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+	})
+
+	It("should finish interactions that went idle while the scout was stopped", func() {
+		s := Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+			Port: 8080, Authorised: true, Name: "foo", State: "measuring", Summary: &ScoutSummary{},
+			MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+			MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 2.0,
+			ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000,
+			ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: Masks{},
+			MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame,
+			Privacy: DefaultPrivacy}
+		si := InitScene(&s)
+		si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t)
+		si.Update(nil, []Waypoint{Waypoint{120, 100, 20, 20, 0.0}}, t.Add(time.Second))
+
+		file := filepath.Join(dir, ".mtf-scene")
+		Ω(si.Checkpoint(file, t.Add(time.Second))).Should(BeNil())
+
+		sink := &memorySink{}
+		restored := InitScene(&s)
+		restored.SetSink(sink)
+		Ω(restored.Restore(nil, file, t.Add(time.Minute))).Should(BeNil())
+
+		Ω(len(restored.Interactions)).Should(Equal(0))
+		Ω(len(restored.IdleInteractions)).Should(Equal(0))
+		Ω(len(sink.saved)).Should(Equal(1))
+		Ω(sink.saved[0].Duration).Should(BeNumerically("~", float32(1.0), 0.001))
+	})
+
+	It("should not restore a checkpoint written by a different scout", func() {
+		s := Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+			Port: 8080, Authorised: true, Name: "foo", State: "measuring", Summary: &ScoutSummary{},
+			MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+			MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 2.0,
+			ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000,
+			ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: Masks{},
+			MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame,
+			Privacy: DefaultPrivacy}
+		si := InitScene(&s)
+		si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t)
+
+		file := filepath.Join(dir, ".mtf-scene")
+		Ω(si.Checkpoint(file, t)).Should(BeNil())
+
+		o := s
+		o.UUID = "7b2c0a9e-3f1d-4c8e-9a6b-2d5e8f1c4a70"
+		restored := InitScene(&o)
+		Ω(restored.Restore(nil, file, t)).ShouldNot(BeNil())
+		Ω(len(restored.Interactions)).Should(Equal(0))
+	})
+})
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// formatInt formats i as a CSV field.
+func formatInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+// formatFloat formats f as a CSV field, using the fewest digits that read back as f.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatFloat32 formats f as a CSV field, using the fewest digits that read back as f.
+func formatFloat32(f float32) string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 32)
+}
+
+// formatTime formats t as a CSV field, in the same RFC 3339 layout the JSON exports use.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// bucketRecords appends a CSV record to records for each bucket of the summary ss. Every record
+// starts with the fields in prefix and is followed by the visitor count of the summary, the
+// column and row of the bucket, the visit time and the number of visitors in the bucket.
+func bucketRecords(records [][]string, prefix []string, ss *ScoutSummary) [][]string {
+	w, h := ss.Grid()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			r := append(append([]string{}, prefix...), formatInt(ss.VisitorCount), strconv.Itoa(x),
+				strconv.Itoa(y), formatFloat32(ss.VisitTimeBuckets[x][y]),
+				strconv.Itoa(ss.VisitorBuckets[x][y]))
+			records = append(records, r)
+		}
+	}
+
+	return records
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestCSV(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CSV Suite")
+}
+
+var _ = Describe("CSV", func() {
+	It("should format numbers with the fewest digits that read back", func() {
+		Ω(formatInt(-42)).Should(Equal("-42"))
+		Ω(formatFloat(0.1)).Should(Equal("0.1"))
+		Ω(formatFloat32(0.1)).Should(Equal("0.1"))
+		Ω(formatFloat32(2.0)).Should(Equal("2"))
+	})
+
+	It("should format times as RFC 3339", func() {
+		t := time.Date(2016, 5, 12, 11, 45, 30, 500000000, time.UTC)
+		Ω(formatTime(t)).Should(Equal("2016-05-12T11:45:30.5Z"))
+	})
+
+	It("should write a record for each bucket of a summary", func() {
+		ss := ScoutSummary{"abc", 3, Buckets{{1.5}, {2.0}}, IntBuckets{{2}, {1}}}
+		records := bucketRecords([][]string{{"header"}}, []string{"abc", "2016-05-12T11:00:00Z"}, &ss)
+		Ω(records).Should(Equal([][]string{
+			{"header"},
+			{"abc", "2016-05-12T11:00:00Z", "3", "0", "0", "1.5", "2"},
+			{"abc", "2016-05-12T11:00:00Z", "3", "1", "0", "2", "1"}}))
+	})
+
+	It("should write nothing for a summary without buckets", func() {
+		ss := ScoutSummary{"abc", 0, Buckets{}, IntBuckets{}}
+		Ω(bucketRecords(nil, []string{"abc"}, &ss)).Should(BeEmpty())
+	})
+})
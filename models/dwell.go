@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/configuration"
+)
+
+// Dwell is a period that an interaction spent standing still. Dwells are detected on the full
+// path of an interaction, before it is simplified.
+type Dwell struct {
+	Id            int64   `json:"id"`
+	InteractionId int64   `json:"interaction_id"`
+	ScoutUUID     string  `json:"scout_uuid"`
+	XPixels       int     `json:"x"`        // The mean x-coordinate of the interaction while dwelling.
+	YPixels       int     `json:"y"`        // The mean y-coordinate of the interaction while dwelling.
+	Start         float32 `json:"start"`    // The number of seconds into the interaction the dwell started.
+	Duration      float32 `json:"duration"` // The number of seconds the interaction dwelled.
+}
+
+const dwellColumns = `id, interaction_id, scout_uuid, x, y, start_offset, duration`
+
+func scanDwell(rows *sql.Rows) (Dwell, error) {
+	var d Dwell
+	err := rows.Scan(&d.Id, &d.InteractionId, &d.ScoutUUID, &d.XPixels, &d.YPixels, &d.Start, &d.Duration)
+	return d, err
+}
+
+func (d *Dwell) Insert(db Queryer) error {
+	const query = `INSERT INTO dwells (interaction_id, scout_uuid, x, y, start_offset, duration)
+				   VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return db.QueryRow(query, d.InteractionId, d.ScoutUUID, d.XPixels, d.YPixels,
+		d.Start, d.Duration).Scan(&d.Id)
+}
+
+// GetDwells returns the dwells of all the interactions detected by a scout.
+func GetDwells(db *sql.DB, scoutUUID string) ([]*Dwell, error) {
+	const query = `SELECT ` + dwellColumns + ` FROM dwells WHERE scout_uuid = $1
+				   ORDER BY interaction_id, start_offset`
+
+	result := []*Dwell{}
+	rows, err := db.Query(query, scoutUUID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDwell(rows)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, &d)
+	}
+
+	return result, rows.Err()
+}
+
+// GetInteractionDwells returns the dwells of the interaction with the id interactionId, in the
+// order they happened.
+func GetInteractionDwells(db Queryer, interactionId int64) ([]Dwell, error) {
+	const query = `SELECT ` + dwellColumns + ` FROM dwells WHERE interaction_id = $1 ORDER BY start_offset`
+
+	result := []Dwell{}
+	rows, err := db.Query(query, interactionId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDwell(rows)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func DwellsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/dwells.json"
+
+	const query = `SELECT ` + dwellColumns + ` FROM dwells`
+	rows, err := db.Query(query)
+	if err == sql.ErrNoRows {
+		return file, nil
+	} else if err != nil {
+		return file, err
+	}
+	defer rows.Close()
+
+	var result []Dwell
+	for rows.Next() {
+		d, err := scanDwell(rows)
+		if err != nil {
+			return file, err
+		}
+
+		result = append(result, d)
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDwell(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dwell Suite")
+}
+
+var _ = Describe("Dwell Model", func() {
+	AfterEach(cleaner)
+
+	Context("DBSink", func() {
+		It("should save the dwells of an interaction", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si := ScoutInteraction{-1, s.UUID, 14.0, Path{[2]int{0, 0}, [2]int{60, 0}, [2]int{60, 1}, [2]int{120, 0}},
+				Path{[2]int{5, 5}, [2]int{5, 5}, [2]int{5, 5}, [2]int{5, 5}}, RealArray{0.0, 2.0, 12.0, 14.0},
+				false, time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)}
+			d := Dwell{-1, -1, "", 60, 0, 2.0, 10.0}
+			err = DBSink{db}.Save(&si, []Dwell{d})
+			Ω(err).Should(BeNil())
+
+			dl, err := GetDwells(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(dl)).Should(Equal(1))
+
+			d.Id = dl[0].Id
+			d.InteractionId = si.Id
+			d.ScoutUUID = s.UUID
+			Ω(dl[0]).Should(Equal(&d))
+		})
+
+		It("should not save an interaction when one of its dwells can't be saved", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si := ScoutInteraction{-1, s.UUID, 14.0, Path{[2]int{0, 0}, [2]int{120, 0}}, Path{[2]int{5, 5}, [2]int{5, 5}},
+				RealArray{0.0, 14.0}, false, time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)}
+
+			// The position of the dwell is too large for the dwells table.
+			x := int64(math.MaxInt32) + 1
+			d := Dwell{-1, -1, "", int(x), 0, 2.0, 10.0}
+			err = DBSink{db}.Save(&si, []Dwell{d})
+			Ω(err).ShouldNot(BeNil())
+
+			n, err := NumScoutInteractions(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(0)))
+		})
+	})
+
+	Context("GetInteractionDwells", func() {
+		It("should only return the dwells of the interaction", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			var interactions []ScoutInteraction
+			for k := 0; k < 2; k++ {
+				si := ScoutInteraction{-1, s.UUID, 14.0, Path{[2]int{0, 0}, [2]int{120, 0}}, Path{[2]int{5, 5}, [2]int{5, 5}},
+					RealArray{0.0, 14.0}, false, time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)}
+				dwells := []Dwell{Dwell{-1, -1, "", 60, k, 8.0, 5.0}, Dwell{-1, -1, "", 30, k, 1.0, 6.0}}
+				err = DBSink{db}.Save(&si, dwells)
+				Ω(err).Should(BeNil())
+				interactions = append(interactions, si)
+			}
+
+			dl, err := GetInteractionDwells(db, interactions[1].Id)
+			Ω(err).Should(BeNil())
+			Ω(len(dl)).Should(Equal(2))
+			Ω(dl[0]).Should(Equal(Dwell{dl[0].Id, interactions[1].Id, s.UUID, 30, 1, 1.0, 6.0}))
+			Ω(dl[1]).Should(Equal(Dwell{dl[1].Id, interactions[1].Id, s.UUID, 60, 1, 8.0, 5.0}))
+		})
+	})
+
+	Context("save", func() {
+		It("should find dwells on the path of an interaction before simplifying it", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			i := Interaction{s.UUID, "0.1", time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC), time.Time{}, 14.0, []Waypoint{
+				Waypoint{0, 0, 5, 5, 0.0}, Waypoint{30, 0, 5, 5, 1.0}, Waypoint{60, 0, 5, 5, 2.0},
+				Waypoint{61, 0, 5, 5, 6.0}, Waypoint{60, 1, 5, 5, 12.0}, Waypoint{90, 0, 5, 5, 13.0},
+				Waypoint{120, 0, 5, 5, 14.0}}, 0, &s, [2]kalman{}}
+			i.saveToDB(db)
+
+			si, err := GetLastScoutInteraction(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(si.Waypoints).Should(Equal(Path{[2]int{0, 0}, [2]int{60, 0}, [2]int{60, 1}, [2]int{120, 0}}))
+
+			dl, err := GetDwells(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(dl).Should(Equal([]*Dwell{&Dwell{dl[0].Id, si.Id, s.UUID, 60, 0, 2.0, 10.0}}))
+		})
+	})
+})
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SummariseFailure records an interaction that could not be summarised, so that it doesn't hold
+// up the interactions after it.
+type SummariseFailure struct {
+	InteractionId int64     `json:"interaction_id"`
+	ScoutUUID     string    `json:"scout_uuid"`
+	Error         string    `json:"error"`     // Why the interaction could not be summarised.
+	Attempts      int64     `json:"attempts"`  // The number of times summarising has failed.
+	FailedAt      time.Time `json:"failed_at"` // When summarising last failed.
+}
+
+// RecordSummariseFailure records that the interaction si could not be summarised at time t,
+// because of err.
+func RecordSummariseFailure(db Queryer, si *ScoutInteraction, cause error, t time.Time) error {
+	const query = `INSERT INTO summarise_failures (interaction_id, scout_uuid, error, attempts, failed_at)
+				   VALUES ($1, $2, $3, 1, $4) ON CONFLICT (interaction_id) DO UPDATE SET
+				   error = EXCLUDED.error, attempts = summarise_failures.attempts + 1,
+				   failed_at = EXCLUDED.failed_at`
+	_, err := db.Exec(query, si.Id, si.ScoutUUID, cause.Error(), t.UTC())
+
+	return err
+}
+
+// GetSummariseFailures returns the interactions of a scout that could not be summarised.
+func GetSummariseFailures(db *sql.DB, scoutUUID string) ([]*SummariseFailure, error) {
+	const query = `SELECT interaction_id, error, attempts, failed_at FROM summarise_failures
+				   WHERE scout_uuid = $1 ORDER BY interaction_id`
+
+	result := []*SummariseFailure{}
+	rows, err := db.Query(query, scoutUUID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f SummariseFailure
+		f.ScoutUUID = scoutUUID
+		err = rows.Scan(&f.InteractionId, &f.Error, &f.Attempts, &f.FailedAt)
+		if err != nil {
+			return result, err
+		}
+		f.FailedAt = f.FailedAt.UTC()
+
+		result = append(result, &f)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestSummariseFailure(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SummariseFailure Suite")
+}
+
+var _ = Describe("SummariseFailure Model", func() {
+	AfterEach(cleaner)
+
+	Context("RecordSummariseFailure", func() {
+		It("should count the attempts at summarising an interaction", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1},
+				false, time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)
+			err = RecordSummariseFailure(db, &si, errors.New("foo"), t)
+			Ω(err).Should(BeNil())
+			err = RecordSummariseFailure(db, &si, errors.New("bar"), t.Add(time.Minute))
+			Ω(err).Should(BeNil())
+
+			fl, err := GetSummariseFailures(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(fl).Should(Equal([]*SummariseFailure{&SummariseFailure{si.Id, s.UUID, "bar", 2, t.Add(time.Minute)}}))
+		})
+	})
+
+	Context("NextUnprocessed", func() {
+		It("should skip interactions that have failed too often or too recently", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			et := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			si := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			si2 := ScoutInteraction{-1, s.UUID, 0.2, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.1}, false, et}
+			err = si2.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)
+			next, err := NextUnprocessed(db, 2, t)
+			Ω(err).Should(BeNil())
+			Ω(next).Should(Equal(&si))
+
+			err = RecordSummariseFailure(db, &si, errors.New("foo"), t)
+			Ω(err).Should(BeNil())
+			next, err = NextUnprocessed(db, 2, t)
+			Ω(err).Should(BeNil())
+			Ω(next).Should(Equal(&si2))
+
+			next, err = NextUnprocessed(db, 2, t.Add(time.Minute))
+			Ω(err).Should(BeNil())
+			Ω(next).Should(Equal(&si))
+
+			err = RecordSummariseFailure(db, &si, errors.New("foo"), t)
+			Ω(err).Should(BeNil())
+			next, err = NextUnprocessed(db, 2, t.Add(time.Minute))
+			Ω(err).Should(BeNil())
+			Ω(next).Should(Equal(&si2))
+		})
+	})
+})
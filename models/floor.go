@@ -0,0 +1,296 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"math"
+	"time"
+)
+
+// FloorPoint is a reference point marked on the calibration frame, along with where it lies
+// on the floor.
+type FloorPoint struct {
+	Pixel [2]float64 `json:"pixel"` // The position on the calibration frame in pixels.
+	Floor [2]float64 `json:"floor"` // The position on the floor in metres.
+}
+
+type FloorPoints []FloorPoint
+
+func (f *FloorPoints) Scan(value interface{}) error {
+	asBytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("Unable to deserialise FloorPoints")
+	}
+
+	return json.Unmarshal(asBytes, f)
+}
+
+func (f FloorPoints) Value() (driver.Value, error) {
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+// FloorCalibration maps the view of a scout onto the floor, so that measurements can be
+// reported in metres rather than pixels.
+type FloorCalibration struct {
+	ScoutUUID  string      `json:"scout_uuid"`
+	Points     FloorPoints `json:"points"`
+	Homography Homography  `json:"homography"`
+}
+
+// FloorInteraction is an interaction projected onto the floor.
+type FloorInteraction struct {
+	Id        int64        `json:"id"`
+	ScoutUUID string       `json:"scout_uuid"`
+	EnteredAt time.Time    `json:"entered_at"`
+	Duration  float32      `json:"duration"`
+	Path      [][2]float64 `json:"path"`       // The waypoints of the interaction in metres.
+	Length    float64      `json:"length"`     // The distance travelled in metres.
+	MeanSpeed float64      `json:"mean_speed"` // The mean speed in metres per second.
+	MaxSpeed  float64      `json:"max_speed"`  // The fastest speed between two waypoints in metres per second.
+}
+
+// FloorHeatmap is the summary of a scout normalised by the floor area of each bucket.
+type FloorHeatmap struct {
+	ScoutUUID string      `json:"scout_uuid"`
+	CellArea  [][]float64 `json:"cell_area"`  // The floor area of each bucket in square metres.
+	VisitTime [][]float64 `json:"visit_time"` // Seconds spent in each bucket per square metre.
+	Visitors  [][]float64 `json:"visitors"`   // Visitors passing through each bucket per square metre.
+}
+
+// NewFloorCalibration solves the homography for the reference points of the scout.
+func NewFloorCalibration(scoutUUID string, points FloorPoints) (*FloorCalibration, error) {
+	pixels := make([][2]float64, len(points))
+	floor := make([][2]float64, len(points))
+	for i, p := range points {
+		pixels[i] = p.Pixel
+		floor[i] = p.Floor
+	}
+
+	h, err := SolveHomography(pixels, floor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FloorCalibration{scoutUUID, points, h}, nil
+}
+
+func GetFloorCalibration(db *sql.DB, scoutUUID string) (*FloorCalibration, error) {
+	const query = `SELECT points, homography FROM floor_calibrations WHERE scout_uuid = $1`
+
+	var result FloorCalibration
+	err := db.QueryRow(query, scoutUUID).Scan(&result.Points, &result.Homography)
+	result.ScoutUUID = scoutUUID
+
+	return &result, err
+}
+
+func GetFloorCalibrations(db *sql.DB) ([]*FloorCalibration, error) {
+	const query = `SELECT scout_uuid, points, homography FROM floor_calibrations`
+	var result []*FloorCalibration
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f FloorCalibration
+		err = rows.Scan(&f.ScoutUUID, &f.Points, &f.Homography)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, &f)
+	}
+
+	return result, rows.Err()
+}
+
+// Save stores the calibration, replacing any previous calibration of the scout.
+func (f *FloorCalibration) Save(db *sql.DB) error {
+	const query = `INSERT INTO floor_calibrations (scout_uuid, points, homography) VALUES ($1, $2, $3)
+		ON CONFLICT (scout_uuid) DO UPDATE SET points = EXCLUDED.points, homography = EXCLUDED.homography`
+	_, err := db.Exec(query, f.ScoutUUID, f.Points, f.Homography)
+	return err
+}
+
+func (f *FloorCalibration) Delete(db *sql.DB) error {
+	const query = `DELETE FROM floor_calibrations WHERE scout_uuid = $1`
+	_, err := db.Exec(query, f.ScoutUUID)
+	return err
+}
+
+// Interaction projects the scout interaction si onto the floor. Waypoints that can't be
+// projected (above the horizon of the floor) are left out.
+func (f *FloorCalibration) Interaction(si *ScoutInteraction) FloorInteraction {
+	result := FloorInteraction{si.Id, si.ScoutUUID, si.EnteredAt, si.Duration, [][2]float64{}, 0.0, 0.0, 0.0}
+
+	var last [2]float64
+	var lastT float32
+	for k, w := range si.Waypoints {
+		p, ok := f.Homography.Project(float64(w[0]), float64(w[1]))
+		if !ok {
+			continue
+		}
+
+		if len(result.Path) > 0 {
+			d := math.Hypot(p[0]-last[0], p[1]-last[1])
+			result.Length += d
+
+			if dt := float64(si.WaypointTimes[k] - lastT); dt > 0.0 {
+				result.MaxSpeed = math.Max(result.MaxSpeed, d/dt)
+			}
+		}
+
+		result.Path = append(result.Path, p)
+		last = p
+		lastT = si.WaypointTimes[k]
+	}
+
+	if si.Duration > 0.0 {
+		result.MeanSpeed = result.Length / float64(si.Duration)
+	}
+
+	return result
+}
+
+// Heatmap normalises the buckets of the summary ss by the floor area they cover, where the
+// buckets break up a frame of fw by fh pixels. Buckets that can't be projected onto the floor
+// have an area of zero and are left empty.
+func (f *FloorCalibration) Heatmap(ss *ScoutSummary, fw int, fh int) FloorHeatmap {
+	result := FloorHeatmap{f.ScoutUUID, [][]float64{}, [][]float64{}, [][]float64{}}
+	gw, gh := ss.Grid()
+	if gw == 0 || gh == 0 {
+		return result
+	}
+	w := float64(fw) / float64(gw)
+	h := float64(fh) / float64(gh)
+
+	for i := range ss.VisitTimeBuckets {
+		area := make([]float64, len(ss.VisitTimeBuckets[i]))
+		visitTime := make([]float64, len(ss.VisitTimeBuckets[i]))
+		visitors := make([]float64, len(ss.VisitTimeBuckets[i]))
+
+		for j := range ss.VisitTimeBuckets[i] {
+			x, y := float64(i)*w, float64(j)*h
+			a, ok := f.Homography.Area([4][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+			if !ok || a <= 0.0 {
+				continue
+			}
+
+			area[j] = a
+			visitTime[j] = float64(ss.VisitTimeBuckets[i][j]) / a
+			visitors[j] = float64(ss.VisitorBuckets[i][j]) / a
+		}
+
+		result.CellArea = append(result.CellArea, area)
+		result.VisitTime = append(result.VisitTime, visitTime)
+		result.Visitors = append(result.Visitors, visitors)
+	}
+
+	return result
+}
+
+func FloorCalibrationsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/floor_calibrations.json"
+
+	fc, err := GetFloorCalibrations(db)
+	if err != nil {
+		return file, err
+	}
+
+	return file, configuration.SaveAsJSON(fc, file)
+}
+
+// FloorInteractionsAsJSON exports the interactions of every calibrated scout in metres, with
+// their entry times rounded to the privacy of the scout.
+func FloorInteractionsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/floor_interactions.json"
+
+	fc, err := GetFloorCalibrations(db)
+	if err != nil {
+		return file, err
+	}
+
+	var result []FloorInteraction
+	for _, f := range fc {
+		si, err := GetScoutInteractions(db, f.ScoutUUID)
+		if err != nil {
+			return file, err
+		}
+
+		p, err := GetScoutPrivacy(db, f.ScoutUUID)
+		if err != nil {
+			return file, err
+		}
+
+		for _, i := range si {
+			fi := f.Interaction(i)
+			fi.EnteredAt = p.Round(fi.EnteredAt)
+			result = append(result, fi)
+		}
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
+
+// FloorHeatmapsAsJSON publishes the summary of every calibrated scout per square metre, under the
+// privacy of the scout. The heatmaps of scouts that have spent their privacy budget are left out.
+func FloorHeatmapsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/floor_heatmaps.json"
+
+	fc, err := GetFloorCalibrations(db)
+	if err != nil {
+		return file, err
+	}
+
+	var result []FloorHeatmap
+	for _, f := range fc {
+		ss, err := GetScoutSummaryByUUID(db, f.ScoutUUID)
+		if err != nil {
+			return file, err
+		}
+
+		fr, err := GetScoutFrame(db, f.ScoutUUID)
+		if err != nil {
+			return file, err
+		}
+
+		p, err := GetScoutPrivacy(db, f.ScoutUUID)
+		if err != nil {
+			return file, err
+		}
+
+		err = ss.Publish(db, p, "floor_heatmaps.json")
+		if err == ErrBudgetSpent {
+			continue
+		} else if err != nil {
+			return file, err
+		}
+
+		result = append(result, f.Heatmap(ss, fr.Width, fr.Height))
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
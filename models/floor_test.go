@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestFloor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Floor Suite")
+}
+
+var _ = Describe("Floor Model", func() {
+	AfterEach(cleaner)
+
+	// One pixel is one centimetre on the floor.
+	points := FloorPoints{FloorPoint{[2]float64{0, 0}, [2]float64{0, 0}},
+		FloorPoint{[2]float64{1000, 0}, [2]float64{10, 0}},
+		FloorPoint{[2]float64{1000, 500}, [2]float64{10, 5}},
+		FloorPoint{[2]float64{0, 500}, [2]float64{0, 5}}}
+
+	Context("Interaction", func() {
+		It("should measure the length and speed of an interaction", func() {
+			f, err := NewFloorCalibration("", points)
+			Ω(err).Should(BeNil())
+
+			si := ScoutInteraction{1, "", 4.0, Path{[2]int{0, 0}, [2]int{300, 0}, [2]int{300, 400}},
+				Path{[2]int{10, 10}, [2]int{10, 10}, [2]int{10, 10}}, RealArray{0.0, 1.0, 4.0}, true, time.Now()}
+			fi := f.Interaction(&si)
+
+			Ω(len(fi.Path)).Should(Equal(3))
+			Ω(fi.Path[2][0]).Should(BeNumerically("~", 3.0, 1e-6))
+			Ω(fi.Path[2][1]).Should(BeNumerically("~", 4.0, 1e-6))
+			Ω(fi.Length).Should(BeNumerically("~", 7.0, 1e-6))
+			Ω(fi.MeanSpeed).Should(BeNumerically("~", 1.75, 1e-6))
+			Ω(fi.MaxSpeed).Should(BeNumerically("~", 3.0, 1e-6))
+		})
+	})
+
+	Context("Heatmap", func() {
+		It("should normalise the summary by the floor area of each bucket", func() {
+			f, err := NewFloorCalibration("", points)
+			Ω(err).Should(BeNil())
+
+			fr := Frame{1280, 720, 40, 10}
+			ss := NewScoutSummary("", fr)
+			ss.VisitorCount = 1
+			ss.VisitTimeBuckets[1][2] = 10.0
+			ss.VisitorBuckets[1][2] = 2
+
+			cell := float64(fr.BucketW()) * float64(fr.BucketH()) / 10000.0
+			fh := f.Heatmap(ss, fr.Width, fr.Height)
+			Ω(len(fh.CellArea)).Should(Equal(40))
+			Ω(len(fh.CellArea[0])).Should(Equal(10))
+			Ω(fh.CellArea[1][2]).Should(BeNumerically("~", cell, 1e-6))
+			Ω(fh.VisitTime[1][2]).Should(BeNumerically("~", 10.0/cell, 1e-6))
+			Ω(fh.Visitors[1][2]).Should(BeNumerically("~", 2.0/cell, 1e-6))
+			Ω(fh.VisitTime[0][0]).Should(Equal(0.0))
+		})
+	})
+
+	Context("Save", func() {
+		It("should be able to save and replace the calibration of a scout", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			f, err := NewFloorCalibration(s.UUID, points)
+			Ω(err).Should(BeNil())
+			Ω(f.Save(db)).Should(BeNil())
+
+			f2, err := GetFloorCalibration(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(f2.Points).Should(Equal(points))
+			for i := range f.Homography {
+				Ω(f2.Homography[i]).Should(BeNumerically("~", f.Homography[i], 1e-9))
+			}
+
+			f.Points[0].Floor = [2]float64{1, 1}
+			Ω(f.Save(db)).Should(BeNil())
+
+			fc, err := GetFloorCalibrations(db)
+			Ω(err).Should(BeNil())
+			Ω(len(fc)).Should(Equal(1))
+			Ω(fc[0].Points[0].Floor).Should(Equal([2]float64{1, 1}))
+		})
+	})
+})
@@ -0,0 +1,250 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Homography is a 3x3 projective transform, stored row by row, that maps points in the camera
+// frame (pixels) onto the floor (metres).
+type Homography [9]float64
+
+func (h *Homography) Scan(value interface{}) error {
+	asBytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("Unable to deserialise Homography")
+	}
+
+	asString := string(asBytes)
+	elements := strings.Split(asString[1:len(asString)-1], ",")
+	if len(elements) != len(h) {
+		return errors.New("Unable to deserialise Homography")
+	}
+
+	for i, v := range elements {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+
+		h[i] = f
+	}
+
+	return nil
+}
+
+func (h Homography) Value() (driver.Value, error) {
+	res := "{"
+	for i, v := range h {
+		res = res + strconv.FormatFloat(v, 'g', -1, 64)
+		if i < len(h)-1 {
+			res = res + ","
+		}
+	}
+	res = res + "}"
+
+	return res, nil
+}
+
+// Project maps the point (x, y) through the homography. It returns false if the point
+// projects to infinity, which happens to points on or above the horizon of the floor.
+func (h Homography) Project(x float64, y float64) ([2]float64, bool) {
+	w := h[6]*x + h[7]*y + h[8]
+	if math.Abs(w) < 1e-12 {
+		return [2]float64{}, false
+	}
+
+	return [2]float64{(h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w}, w > 0.0
+}
+
+// multiply returns the homography a followed by b.
+func (a Homography) multiply(b Homography) Homography {
+	var result Homography
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				result[r*3+c] += b[r*3+k] * a[k*3+c]
+			}
+		}
+	}
+
+	return result
+}
+
+// normalise returns the similarity transform that moves the centroid of pts to the origin
+// and scales them to an average distance of sqrt(2) from it, along with its inverse.
+func normalise(pts [][2]float64) (Homography, Homography) {
+	var cx, cy float64
+	for _, p := range pts {
+		cx += p[0]
+		cy += p[1]
+	}
+	cx /= float64(len(pts))
+	cy /= float64(len(pts))
+
+	d := 0.0
+	for _, p := range pts {
+		d += math.Hypot(p[0]-cx, p[1]-cy)
+	}
+	d /= float64(len(pts))
+
+	s := 1.0
+	if d > 0.0 {
+		s = math.Sqrt2 / d
+	}
+
+	return Homography{s, 0, -s * cx, 0, s, -s * cy, 0, 0, 1},
+		Homography{1 / s, 0, cx, 0, 1 / s, cy, 0, 0, 1}
+}
+
+// solve finds x in the system a.x = b with Gaussian elimination, returning false if the
+// system has no unique solution.
+func solve(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for c := 0; c < n; c++ {
+		// Use the largest remaining pivot to keep the elimination stable.
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-9 {
+			return nil, false
+		}
+		a[c], a[p] = a[p], a[c]
+		b[c], b[p] = b[p], b[c]
+
+		for r := c + 1; r < n; r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		x[r] = b[r]
+		for k := r + 1; k < n; k++ {
+			x[r] -= a[r][k] * x[k]
+		}
+		x[r] /= a[r][r]
+	}
+
+	return x, true
+}
+
+// SolveHomography finds the homography that best maps each of the pixel points onto the
+// matching floor point, using the normalised direct linear transform. It needs at least four
+// pairs of points, no three of which lie on a line; extra pairs are fitted by least squares.
+func SolveHomography(pixels [][2]float64, floor [][2]float64) (Homography, error) {
+	if len(pixels) != len(floor) || len(pixels) < 4 {
+		return Homography{}, errors.New("A homography needs at least four pairs of points")
+	}
+
+	tp, tpInv := normalise(pixels)
+	tf, tfInv := normalise(floor)
+
+	// Build the normal equations for the eight unknowns, fixing the last element at 1.
+	ata := make([][]float64, 8)
+	for i := range ata {
+		ata[i] = make([]float64, 8)
+	}
+	atb := make([]float64, 8)
+
+	for i := range pixels {
+		p, _ := tp.Project(pixels[i][0], pixels[i][1])
+		f, _ := tf.Project(floor[i][0], floor[i][1])
+		rows := [2][8]float64{
+			{p[0], p[1], 1, 0, 0, 0, -f[0] * p[0], -f[0] * p[1]},
+			{0, 0, 0, p[0], p[1], 1, -f[1] * p[0], -f[1] * p[1]},
+		}
+		rhs := [2]float64{f[0], f[1]}
+
+		for k, row := range rows {
+			for r := 0; r < 8; r++ {
+				for c := 0; c < 8; c++ {
+					ata[r][c] += row[r] * row[c]
+				}
+				atb[r] += row[r] * rhs[k]
+			}
+		}
+	}
+
+	x, ok := solve(ata, atb)
+	if !ok {
+		return Homography{}, errors.New("Unable to solve homography, the points are degenerate")
+	}
+
+	var hn Homography
+	copy(hn[:], x)
+	hn[8] = 1.0
+
+	// Undo the normalisation of both sets of points, and scale the result so that points
+	// in front of the camera (like the centre of the calibration points) project with a
+	// positive w.
+	h := tp.multiply(hn).multiply(tfInv)
+	w := h[6]*tpInv[2] + h[7]*tpInv[5] + h[8]
+	if math.Abs(w) < 1e-12 {
+		return Homography{}, errors.New("Unable to solve homography, the points are degenerate")
+	}
+	for i := range h {
+		h[i] /= w
+	}
+
+	return h, nil
+}
+
+// PathLength returns the distance along the path p once it has been projected onto the floor.
+func (h Homography) PathLength(p Path) float64 {
+	result := 0.0
+	for k := 1; k < len(p); k++ {
+		a, okA := h.Project(float64(p[k-1][0]), float64(p[k-1][1]))
+		b, okB := h.Project(float64(p[k][0]), float64(p[k][1]))
+		if okA && okB {
+			result += math.Hypot(b[0]-a[0], b[1]-a[1])
+		}
+	}
+
+	return result
+}
+
+// Area returns the floor area covered by the quadrilateral of pixel corners.
+func (h Homography) Area(corners [4][2]float64) (float64, bool) {
+	var pts [4][2]float64
+	for i, c := range corners {
+		p, ok := h.Project(c[0], c[1])
+		if !ok {
+			return 0.0, false
+		}
+		pts[i] = p
+	}
+
+	a := 0.0
+	for i, j := 0, 3; i < 4; j, i = i, i+1 {
+		a += pts[j][0]*pts[i][1] - pts[i][0]*pts[j][1]
+	}
+
+	return math.Abs(a) / 2.0, true
+}
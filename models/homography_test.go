@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestHomography(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Homography Suite")
+}
+
+var _ = Describe("Homography", func() {
+	// A camera looking down at an angle, so the far edge of the floor is squashed.
+	known := Homography{0.01, 0.0, -2.0, 0.0, 0.02, -1.0, 0.0, 0.001, 1.0}
+
+	project := func(h Homography, pts [][2]float64) [][2]float64 {
+		result := make([][2]float64, len(pts))
+		for i, p := range pts {
+			result[i], _ = h.Project(p[0], p[1])
+		}
+
+		return result
+	}
+
+	Context("SolveHomography", func() {
+		It("should recover a homography from four points", func() {
+			pixels := [][2]float64{{100, 100}, {1100, 120}, {1200, 700}, {50, 650}}
+			h, err := SolveHomography(pixels, project(known, pixels))
+			Ω(err).Should(BeNil())
+
+			for _, p := range [][2]float64{{640, 360}, {300, 500}, {900, 200}} {
+				a, ok := h.Project(p[0], p[1])
+				Ω(ok).Should(BeTrue())
+				b, _ := known.Project(p[0], p[1])
+				Ω(a[0]).Should(BeNumerically("~", b[0], 1e-6))
+				Ω(a[1]).Should(BeNumerically("~", b[1], 1e-6))
+			}
+		})
+
+		It("should fit more than four points", func() {
+			pixels := [][2]float64{{100, 100}, {1100, 120}, {1200, 700}, {50, 650}, {640, 360}, {320, 200}}
+			h, err := SolveHomography(pixels, project(known, pixels))
+			Ω(err).Should(BeNil())
+
+			a, _ := h.Project(800, 400)
+			b, _ := known.Project(800, 400)
+			Ω(a[0]).Should(BeNumerically("~", b[0], 1e-6))
+			Ω(a[1]).Should(BeNumerically("~", b[1], 1e-6))
+		})
+
+		It("should not solve with too few or degenerate points", func() {
+			_, err := SolveHomography([][2]float64{{0, 0}, {1, 0}, {0, 1}}, [][2]float64{{0, 0}, {1, 0}, {0, 1}})
+			Ω(err).ShouldNot(BeNil())
+
+			line := [][2]float64{{0, 0}, {10, 10}, {20, 20}, {30, 30}}
+			_, err = SolveHomography(line, line)
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Scan", func() {
+		It("should round trip through the database representation", func() {
+			v, err := known.Value()
+			Ω(err).Should(BeNil())
+
+			var h Homography
+			Ω(h.Scan([]byte(v.(string)))).Should(BeNil())
+			Ω(h).Should(Equal(known))
+		})
+	})
+
+	Context("PathLength", func() {
+		It("should measure the length of a path on the floor", func() {
+			scale := Homography{0.01, 0.0, 0.0, 0.0, 0.01, 0.0, 0.0, 0.0, 1.0}
+			Ω(scale.PathLength(Path{[2]int{0, 0}, [2]int{300, 0}, [2]int{300, 400}})).Should(BeNumerically("~", 7.0, 1e-9))
+		})
+	})
+
+	Context("Area", func() {
+		It("should measure the floor area of a quadrilateral", func() {
+			scale := Homography{0.01, 0.0, 0.0, 0.0, 0.02, 0.0, 0.0, 0.0, 1.0}
+			a, ok := scale.Area([4][2]float64{{0, 0}, {100, 0}, {100, 100}, {0, 100}})
+			Ω(ok).Should(BeTrue())
+			Ω(a).Should(BeNumerically("~", 2.0, 1e-9))
+		})
+
+		It("should not measure areas beyond the horizon", func() {
+			_, ok := known.Area([4][2]float64{{0, -2000}, {100, -2000}, {100, -900}, {0, -900}})
+			Ω(ok).Should(BeFalse())
+		})
+	})
+})
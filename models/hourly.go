@@ -0,0 +1,264 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"strconv"
+	"time"
+)
+
+// HourlySummary is the summary of the time interactions spent in the view of a scout within an
+// hour. Visitors are counted in the hour they entered, and in each bucket in the hour they first
+// reached it. Summing the hourly summaries over a period gives the heatmap of that period.
+type HourlySummary struct {
+	ScoutSummary
+	Hour time.Time // The start of the hour (UTC).
+}
+
+// HeatmapQuery selects the hourly summaries that make up a heatmap.
+type HeatmapQuery struct {
+	From     time.Time      // Only include hours starting at or after From (zero for no limit).
+	To       time.Time      // Only include hours starting before To (zero for no limit).
+	Location *time.Location // The timezone that Days and Hours are in, nil for UTC.
+	Days     []time.Weekday // Only include hours that fall on these days, empty for every day.
+	Hours    []int          // Only include hours that start at these hours of the day, empty for all.
+}
+
+// Matches returns true if the hour starting at h falls on one of the days and hours of q. The
+// start of the hour is converted to the timezone of q first.
+func (q HeatmapQuery) Matches(h time.Time) bool {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	lh := h.In(loc)
+
+	if len(q.Days) > 0 {
+		found := false
+		for _, d := range q.Days {
+			found = found || lh.Weekday() == d
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Hours) > 0 {
+		found := false
+		for _, hr := range q.Hours {
+			found = found || lh.Hour() == hr
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetHourlySummary returns the summary of a scout for the hour starting at hour. An empty
+// summary, with buckets for the grid of frame f, is returned if nothing was summarised for that
+// hour.
+func GetHourlySummary(db Queryer, scoutUUID string, hour time.Time, f Frame) (*HourlySummary, error) {
+	const query = `SELECT visitor_count, visit_time_buckets, visitor_buckets FROM hourly_summaries
+				   WHERE scout_uuid = $1 AND hour = $2`
+
+	result := HourlySummary{*NewScoutSummary(scoutUUID, f), hour.UTC()}
+	err := db.QueryRow(query, scoutUUID, result.Hour).Scan(&result.VisitorCount,
+		&result.VisitTimeBuckets, &result.VisitorBuckets)
+	if err == sql.ErrNoRows {
+		return &result, nil
+	}
+
+	return &result, err
+}
+
+// GetHourlySummaries returns the summaries of a scout for each hour starting between from and
+// to, in order. A zero from or to leaves that end of the period open.
+func GetHourlySummaries(db Queryer, scoutUUID string, from time.Time, to time.Time) ([]*HourlySummary, error) {
+	query := `SELECT hour, visitor_count, visit_time_buckets, visitor_buckets FROM hourly_summaries
+			  WHERE scout_uuid = $1`
+	args := []interface{}{scoutUUID}
+	where := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		query = query + " AND " + clause + " $" + strconv.Itoa(len(args))
+	}
+
+	if !from.IsZero() {
+		where("hour >=", from.UTC())
+	}
+	if !to.IsZero() {
+		where("hour <", to.UTC())
+	}
+	query = query + " ORDER BY hour"
+
+	var result []*HourlySummary
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hs HourlySummary
+		err = rows.Scan(&hs.Hour, &hs.VisitorCount, &hs.VisitTimeBuckets, &hs.VisitorBuckets)
+		if err != nil {
+			return result, err
+		}
+		hs.ScoutUUID = scoutUUID
+		hs.Hour = hs.Hour.UTC()
+
+		result = append(result, &hs)
+	}
+
+	return result, rows.Err()
+}
+
+// GetHeatmap sums the hourly summaries of a scout that match q into a single summary, on the
+// current grid of the scout.
+func GetHeatmap(db Queryer, scoutUUID string, q HeatmapQuery) (*ScoutSummary, error) {
+	f, err := GetScoutFrame(db, scoutUUID)
+	if err != nil {
+		return NewScoutSummary(scoutUUID, DefaultFrame), err
+	}
+	result := NewScoutSummary(scoutUUID, f)
+
+	hl, err := GetHourlySummaries(db, scoutUUID, q.From, q.To)
+	if err != nil {
+		return result, err
+	}
+
+	for _, hs := range hl {
+		if !q.Matches(hs.Hour) {
+			continue
+		}
+
+		err = result.Add(&hs.ScoutSummary)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Save stores the hourly summary, replacing any previous summary of the same hour.
+func (hs *HourlySummary) Save(db Queryer) error {
+	const query = `INSERT INTO hourly_summaries (scout_uuid, hour, visitor_count, visit_time_buckets,
+				   visitor_buckets) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (scout_uuid, hour) DO UPDATE SET
+				   visitor_count = EXCLUDED.visitor_count, visit_time_buckets = EXCLUDED.visit_time_buckets,
+				   visitor_buckets = EXCLUDED.visitor_buckets`
+	_, err := db.Exec(query, hs.ScoutUUID, hs.Hour.UTC(), hs.VisitorCount, hs.VisitTimeBuckets, hs.VisitorBuckets)
+
+	return err
+}
+
+// NumHourlySummaries returns the number of hours summarised for a scout.
+func NumHourlySummaries(db Queryer, scoutUUID string) (int64, error) {
+	const query = `SELECT COUNT(*) FROM hourly_summaries WHERE scout_uuid = $1`
+	var result int64
+	err := db.QueryRow(query, scoutUUID).Scan(&result)
+
+	return result, err
+}
+
+func DeleteHourlySummaries(db *sql.DB, scoutUUID string) error {
+	const query = `DELETE FROM hourly_summaries WHERE scout_uuid = $1`
+	_, err := db.Exec(query, scoutUUID)
+	return err
+}
+
+// DeleteHourlySummariesBetween removes the summaries of a scout for each hour starting between
+// from and to.
+func DeleteHourlySummariesBetween(db Queryer, scoutUUID string, from time.Time, to time.Time) error {
+	const query = `DELETE FROM hourly_summaries WHERE scout_uuid = $1 AND hour >= $2 AND hour < $3`
+	_, err := db.Exec(query, scoutUUID, from.UTC(), to.UTC())
+	return err
+}
+
+// exportHourlySummaries returns the hourly summaries of every scout for the data download,
+// suppressing the counts of fewer visitors than the privacy of the scout allows. Only the hours
+// that saw visitors are stored, which would give them away despite any noise, so the hourly
+// summaries of scouts that publish with differential privacy are left out.
+func exportHourlySummaries(db *sql.DB) ([]HourlySummary, error) {
+	privacies, err := GetScoutPrivacies(db)
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `SELECT scout_uuid, hour, visitor_count, visit_time_buckets, visitor_buckets
+				   FROM hourly_summaries ORDER BY scout_uuid, hour`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []HourlySummary
+	for rows.Next() {
+		var hs HourlySummary
+		err = rows.Scan(&hs.ScoutUUID, &hs.Hour, &hs.VisitorCount, &hs.VisitTimeBuckets, &hs.VisitorBuckets)
+		if err != nil {
+			return nil, err
+		}
+		hs.Hour = hs.Hour.UTC()
+
+		p := privacies[hs.ScoutUUID]
+		if p.Epsilon > 0.0 {
+			continue
+		}
+		hs.Suppress(p.MinVisitors)
+
+		result = append(result, hs)
+	}
+
+	return result, rows.Err()
+}
+
+// HourlySummariesAsJSON exports the hourly summaries of every scout, under the privacy of the
+// scout.
+func HourlySummariesAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/hourly_summaries.json"
+	result, err := exportHourlySummaries(db)
+	if err != nil {
+		return file, err
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
+
+// HourlySummariesAsCSV exports the hourly summaries of every scout under the privacy of the
+// scout, one row per bucket of each hour.
+func HourlySummariesAsCSV(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/hourly_summaries.csv"
+	summaries, err := exportHourlySummaries(db)
+	if err != nil {
+		return file, err
+	}
+
+	records := [][]string{{"ScoutUUID", "Hour", "VisitorCount", "X", "Y", "VisitTime", "Visitors"}}
+	for i := range summaries {
+		hs := &summaries[i]
+		records = bucketRecords(records, []string{hs.ScoutUUID, formatTime(hs.Hour)}, &hs.ScoutSummary)
+	}
+
+	return file, configuration.SaveAsCSV(records, file)
+}
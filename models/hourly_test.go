@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestHourly(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hourly Suite")
+}
+
+var _ = Describe("Hourly Summary Model", func() {
+	AfterEach(cleaner)
+
+	// Thursday 12 May 2016, 10am UTC.
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	Context("Matches", func() {
+		It("should match every hour when no days or hours are given", func() {
+			Ω(HeatmapQuery{}.Matches(t)).Should(BeTrue())
+		})
+
+		It("should match the days and hours of the query", func() {
+			q := HeatmapQuery{Days: []time.Weekday{time.Monday, time.Thursday}, Hours: []int{9, 10}}
+			Ω(q.Matches(t)).Should(BeTrue())
+			Ω(q.Matches(t.Add(time.Hour))).Should(BeFalse())
+			Ω(q.Matches(t.Add(24 * time.Hour))).Should(BeFalse())
+		})
+
+		It("should match days and hours in the timezone of the query", func() {
+			loc, err := time.LoadLocation("Australia/Brisbane")
+			Ω(err).Should(BeNil())
+
+			// 10am UTC on Thursday is 8pm on Thursday in Brisbane, 4pm UTC is 2am on Friday.
+			q := HeatmapQuery{Location: loc, Days: []time.Weekday{time.Thursday}, Hours: []int{20}}
+			Ω(q.Matches(t)).Should(BeTrue())
+			Ω(HeatmapQuery{Location: loc, Days: []time.Weekday{time.Friday}}.Matches(t.Add(6 * time.Hour))).Should(BeTrue())
+			Ω(HeatmapQuery{Days: []time.Weekday{time.Friday}}.Matches(t.Add(6 * time.Hour))).Should(BeFalse())
+		})
+	})
+
+	Context("Save", func() {
+		It("should be able to save and replace the summary of an hour", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			hs, err := GetHourlySummary(db, s.UUID, t, s.Frame)
+			Ω(err).Should(BeNil())
+			Ω(hs.VisitorCount).Should(Equal(int64(0)))
+
+			hs.VisitorCount = 2
+			hs.VisitorBuckets[1][2] = 2
+			hs.VisitTimeBuckets[1][2] = 3.5
+			err = hs.Save(db)
+			Ω(err).Should(BeNil())
+
+			hs.VisitorCount = 3
+			err = hs.Save(db)
+			Ω(err).Should(BeNil())
+
+			hs2, err := GetHourlySummary(db, s.UUID, t, s.Frame)
+			Ω(err).Should(BeNil())
+			Ω(hs2).Should(Equal(hs))
+
+			n, err := NumHourlySummaries(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+		})
+	})
+
+	Context("GetHeatmap", func() {
+		It("should sum the hours that match the query", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			for k, h := range []time.Time{t, t.Add(time.Hour), t.Add(24 * time.Hour)} {
+				hs := HourlySummary{*NewScoutSummary(s.UUID, s.Frame), h}
+				hs.VisitorCount = int64(k + 1)
+				hs.VisitorBuckets[0][0] = k + 1
+				hs.VisitTimeBuckets[0][0] = float32(k + 1)
+				err = hs.Save(db)
+				Ω(err).Should(BeNil())
+			}
+
+			h, err := GetHeatmap(db, s.UUID, HeatmapQuery{})
+			Ω(err).Should(BeNil())
+			Ω(h.VisitorCount).Should(Equal(int64(6)))
+			Ω(h.VisitorBuckets[0][0]).Should(Equal(6))
+
+			h, err = GetHeatmap(db, s.UUID, HeatmapQuery{From: t, To: t.Add(24 * time.Hour)})
+			Ω(err).Should(BeNil())
+			Ω(h.VisitorCount).Should(Equal(int64(3)))
+
+			h, err = GetHeatmap(db, s.UUID, HeatmapQuery{Hours: []int{10}})
+			Ω(err).Should(BeNil())
+			Ω(h.VisitorCount).Should(Equal(int64(4)))
+			Ω(h.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 4.0, 0.001))
+		})
+
+		It("should use the grid of the scout and refuse to mix grids", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: Frame{640, 480, 32, 24}, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			h, err := GetHeatmap(db, s.UUID, HeatmapQuery{})
+			Ω(err).Should(BeNil())
+			Ω(h).Should(Equal(NewScoutSummary(s.UUID, s.Frame)))
+
+			hs := HourlySummary{*NewScoutSummary(s.UUID, Frame{640, 480, 10, 10}), t}
+			err = hs.Save(db)
+			Ω(err).Should(BeNil())
+
+			_, err = GetHeatmap(db, s.UUID, HeatmapQuery{})
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+})
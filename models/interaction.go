@@ -20,18 +20,20 @@ package models
 import (
 	"database/sql"
 	"log"
+	"math"
 	"time"
 )
 
 type Interaction struct {
 	UUID     string     // The UUID for the scout that detected the interaction.
 	Version  string     // The Version of the protocol used for transmitting data to the mothership
-	Entered  time.Time  // The time the interaction started (rounded to nearest half hour)
-	started  time.Time  // The actual time the interaction started. Private. Not to be transmitted for privacy concerns
+	Entered  time.Time  // The time the interaction started. Rounded to the privacy of the scout whenever it is shared.
+	started  time.Time  // The time the waypoint times are measured from.
 	Duration float32    // The total duration of the interaction.
 	Path     []Waypoint // The pathway of the interaction through the scene.
 	SceneID  int
 	dScout   *Scout
+	motion   [2]kalman // The estimated motion of the interaction along the x and y axis.
 }
 
 func (i Interaction) Equal(wp []Waypoint) bool {
@@ -48,27 +50,65 @@ func (i Interaction) Equal(wp []Waypoint) bool {
 	return true
 }
 
-func NewInteraction(w Waypoint, sId int, s *Scout) Interaction {
-	start := time.Now().UTC()
+// NewInteraction creates an interaction that starts with the waypoint w, detected at time t.
+func NewInteraction(w Waypoint, sId int, s *Scout, t time.Time) Interaction {
+	start := t.UTC()
 
-	// The start time broadcasted for the interaction is truncated to the nearest 30 minutes.
-	apparentStart := start.Round(15 * time.Minute)
-
-	i := Interaction{s.UUID, "0.1", apparentStart, start, 0.0, []Waypoint{}, sId, s}
-	i.addWaypoint(w)
+	// The exact start time is kept so that zones, tripwires and hourly summaries count the
+	// interaction when it actually happened. It is rounded when shared, see Privacy.Round.
+	i := Interaction{s.UUID, "0.1", start, start, 0.0, []Waypoint{}, sId, s, [2]kalman{}}
+	i.addWaypoint(w, t)
 	return i
 }
 
-// addWaypoint inserts a new waypoint to the end of the interaction.
-func (i *Interaction) addWaypoint(w Waypoint) {
+// addWaypoint inserts a new waypoint, detected at time t, to the end of the interaction.
+func (i *Interaction) addWaypoint(w Waypoint, t time.Time) {
 	newW := w
-	newW.T = float32(time.Now().UTC().Sub(i.started).Seconds())
+	newW.T = float32(t.Sub(i.started).Seconds())
+
+	// Update the motion estimate with the position of the new waypoint.
+	if len(i.Path) == 0 {
+		i.motion[0] = newKalman(float64(newW.XPixels), i.dScout.MeasurementNoise)
+		i.motion[1] = newKalman(float64(newW.YPixels), i.dScout.MeasurementNoise)
+	} else {
+		dt := float64(newW.T - i.LastWaypoint().T)
+		i.motion[0].update(float64(newW.XPixels), dt, i.dScout.ProcessNoise, i.dScout.MeasurementNoise)
+		i.motion[1].update(float64(newW.YPixels), dt, i.dScout.ProcessNoise, i.dScout.MeasurementNoise)
+	}
 
 	i.Duration = newW.T
 	i.Path = append(i.Path, newW)
 }
 
+// predict returns where the interaction is expected to be t seconds after it started,
+// given the estimated motion of the interaction so far.
+func (i *Interaction) predict(t float32) Waypoint {
+	w := i.LastWaypoint()
+	dt := float64(t - w.T)
+	if dt < 0.0 {
+		dt = 0.0
+	}
+
+	x := i.motion[0].predict(dt, i.dScout.ProcessNoise)
+	y := i.motion[1].predict(dt, i.dScout.ProcessNoise)
+
+	return Waypoint{int(math.Floor(x.X + 0.5)), int(math.Floor(y.X + 0.5)), w.HalfWidthPixels, w.HalfHeightPixels, t}
+}
+
 func douglasPeucker(path []Waypoint, epsilon float64) []Waypoint {
+	return reducePath(path, epsilon, Waypoint.perpendicularDistance)
+}
+
+// timeDouglasPeucker simplifies path using the synchronised distance of each waypoint, rather
+// than its perpendicular distance. Waypoints where the interaction slowed down or stood still
+// are kept, even when they lie on a straight line.
+func timeDouglasPeucker(path []Waypoint, epsilon float64) []Waypoint {
+	return reducePath(path, epsilon, Waypoint.synchronisedDistance)
+}
+
+// reducePath removes waypoints from path that are within epsilon of the segment between their
+// neighbours, as measured by distance.
+func reducePath(path []Waypoint, epsilon float64, distance func(x Waypoint, a Waypoint, b Waypoint) float64) []Waypoint {
 	if len(path) == 1 {
 		return path
 	}
@@ -78,7 +118,7 @@ func douglasPeucker(path []Waypoint, epsilon float64) []Waypoint {
 	end := len(path) - 1
 
 	for i := 1; i < end; i++ {
-		d := path[i].perpendicularDistance(path[0], path[end])
+		d := distance(path[i], path[0], path[end])
 		if d > dMax {
 			iMax = i
 			dMax = d
@@ -86,8 +126,8 @@ func douglasPeucker(path []Waypoint, epsilon float64) []Waypoint {
 	}
 
 	if dMax > epsilon {
-		a := douglasPeucker(path[0:iMax+1], epsilon)
-		b := douglasPeucker(path[iMax:len(path)], epsilon)
+		a := reducePath(path[0:iMax+1], epsilon, distance)
+		b := reducePath(path[iMax:len(path)], epsilon, distance)
 
 		if len(b) > 1 {
 			return append(a, b[1:len(b)]...)
@@ -99,23 +139,128 @@ func douglasPeucker(path []Waypoint, epsilon float64) []Waypoint {
 	return []Waypoint{path[0], path[end]}
 }
 
+// dwellSpans returns the first and last index of each part of path where the interaction stayed
+// within sqDistance of where it stopped for at least duration seconds. A duration of zero
+// disables dwell detection.
+func dwellSpans(path []Waypoint, sqDistance int64, duration float32) [][2]int {
+	spans := [][2]int{}
+	if duration <= 0.0 {
+		return spans
+	}
+
+	for k := 0; k < len(path); {
+		j := k
+		for j+1 < len(path) && path[j+1].distanceSq(path[k]) <= sqDistance {
+			j++
+		}
+
+		if j > k && path[j].T-path[k].T >= duration {
+			spans = append(spans, [2]int{k, j})
+			k = j + 1
+		} else {
+			k++
+		}
+	}
+
+	return spans
+}
+
+// newDwell creates a dwell from the part of path between the indices in span. The dwell is
+// located at the mean position of the waypoints within the span.
+func newDwell(path []Waypoint, span [2]int) Dwell {
+	x := 0
+	y := 0
+	for _, w := range path[span[0] : span[1]+1] {
+		x += w.XPixels
+		y += w.YPixels
+	}
+	n := span[1] - span[0] + 1
+
+	return Dwell{-1, -1, "", x / n, y / n, path[span[0]].T, path[span[1]].T - path[span[0]].T}
+}
+
 // lastWaypoint returns the last waypoint within the interaction.
 func (i *Interaction) LastWaypoint() Waypoint {
 	return i.Path[len(i.Path)-1]
 }
 
-func (i *Interaction) simplify() {
-	i.Path = douglasPeucker(i.Path, i.dScout.SimplifyEpsilon)
+// simplify removes unnecessary waypoints from the path of the interaction. The first and last
+// waypoint of each of the dwells in spans are always kept, so stationary periods survive.
+func (i *Interaction) simplify(spans [][2]int) {
+	result := []Waypoint{}
+	join := func(p []Waypoint) {
+		if len(result) > 0 {
+			p = p[1:]
+		}
+		result = append(result, p...)
+	}
+
+	start := 0
+	for _, s := range spans {
+		join(timeDouglasPeucker(i.Path[start:s[0]+1], i.dScout.SimplifyEpsilon))
+		join([]Waypoint{i.Path[s[0]], i.Path[s[1]]})
+		start = s[1]
+	}
+	join(timeDouglasPeucker(i.Path[start:], i.dScout.SimplifyEpsilon))
+
+	i.Path = result
 }
 
-func (i *Interaction) saveToDB(db *sql.DB) {
-	i.simplify() // Remove unnecessary segments from the pathway before storing in the DB.
+// InteractionSink stores the interactions that have finished within a scene.
+type InteractionSink interface {
+	Save(si *ScoutInteraction, dwells []Dwell) error
+}
+
+// DBSink stores finished interactions in the scout_interactions table of DB.
+type DBSink struct {
+	DB *sql.DB
+}
+
+// Save inserts the interaction along with its dwells in a single transaction, so that an
+// interaction is never stored (and summarised) without them.
+func (d DBSink) Save(si *ScoutInteraction, dwells []Dwell) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = si.Insert(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, dw := range dwells {
+		dw.InteractionId = si.Id
+		dw.ScoutUUID = si.ScoutUUID
+		err = dw.Insert(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (i *Interaction) save(sink InteractionSink) {
+	// Find where the interaction stood still before simplifying the pathway, as most of the
+	// waypoints within a dwell are removed when it is simplified.
+	spans := dwellSpans(i.Path, i.dScout.DwellSqDistance, i.dScout.DwellDuration)
+	dwells := make([]Dwell, len(spans))
+	for k, s := range spans {
+		dwells[k] = newDwell(i.Path, s)
+	}
+	i.simplify(spans) // Remove unnecessary segments from the pathway before storing it.
 
 	si := CreateScoutInteraction(i)
 	si.ScoutUUID = i.dScout.UUID
-	err := si.Insert(db)
+	err := sink.Save(&si, dwells)
 	if err != nil {
-		log.Printf("ERROR: Unable to save Interaction to DB.")
+		log.Printf("ERROR: Unable to save Interaction.")
 		log.Print(err)
 	}
 }
+
+func (i *Interaction) saveToDB(db *sql.DB) {
+	i.save(DBSink{db})
+}
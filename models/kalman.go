@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+// initialVelocityVariance is the uncertainty (in pixels squared per second squared) of
+// the velocity of a newly detected interaction.
+const initialVelocityVariance = 40000.0
+
+// kalman is a constant velocity Kalman filter, estimating the position and velocity of
+// an interaction along a single axis.
+type kalman struct {
+	X float64       // The estimated position in pixels.
+	V float64       // The estimated velocity in pixels per second.
+	P [2][2]float64 // The covariance of the position and velocity estimates.
+}
+
+// newKalman creates a filter that starts at the measured position x, with the supplied
+// measurement noise r.
+func newKalman(x float64, r float64) kalman {
+	return kalman{x, 0.0, [2][2]float64{{r, 0.0}, {0.0, initialVelocityVariance}}}
+}
+
+// predict returns the filter advanced dt seconds into the future, using the process
+// noise q to grow the uncertainty of the estimate.
+func (k kalman) predict(dt float64, q float64) kalman {
+	dt2 := dt * dt
+
+	var p [2][2]float64
+	p[0][0] = k.P[0][0] + dt*(k.P[0][1]+k.P[1][0]) + dt2*k.P[1][1] + q*dt2*dt2/4.0
+	p[0][1] = k.P[0][1] + dt*k.P[1][1] + q*dt2*dt/2.0
+	p[1][0] = k.P[1][0] + dt*k.P[1][1] + q*dt2*dt/2.0
+	p[1][1] = k.P[1][1] + q*dt2
+
+	return kalman{k.X + k.V*dt, k.V, p}
+}
+
+// update advances the filter dt seconds into the future and corrects the estimate with
+// the measured position z, which has the measurement noise r.
+func (k *kalman) update(z float64, dt float64, q float64, r float64) {
+	p := k.predict(dt, q)
+
+	s := p.P[0][0] + r
+	g0 := p.P[0][0] / s
+	g1 := p.P[1][0] / s
+	y := z - p.X
+
+	k.X = p.X + g0*y
+	k.V = p.V + g1*y
+	k.P[0][0] = (1.0 - g0) * p.P[0][0]
+	k.P[0][1] = (1.0 - g0) * p.P[0][1]
+	k.P[1][0] = p.P[1][0] - g1*p.P[0][0]
+	k.P[1][1] = p.P[1][1] - g1*p.P[0][1]
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// LiveWaypoint is the latest position of an interaction in the active scene.
+type LiveWaypoint struct {
+	SceneID  int      `json:"scene_id"`
+	Idle     bool     `json:"idle"`
+	Waypoint Waypoint `json:"waypoint"`
+}
+
+// LiveSnapshot is the state of the active scene at an instant.
+type LiveSnapshot struct {
+	Measuring bool           `json:"measuring"`
+	Active    int            `json:"active"`
+	Idle      int            `json:"idle"`
+	Waypoints []LiveWaypoint `json:"waypoints"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// LiveScene shares snapshots of the scene being measured with any number of readers. It is
+// safe to use from multiple goroutines.
+type LiveScene struct {
+	UUID        string // The UUID of the scout being measured.
+	mu          sync.RWMutex
+	snapshot    LiveSnapshot
+	subscribers map[chan LiveSnapshot]bool
+}
+
+// NewLiveScene creates an empty live scene of the scout uuid that is not measuring.
+func NewLiveScene(uuid string) *LiveScene {
+	return &LiveScene{UUID: uuid, snapshot: LiveSnapshot{false, 0, 0, []LiveWaypoint{}, time.Time{}},
+		subscribers: make(map[chan LiveSnapshot]bool)}
+}
+
+// Publish records the state of the scene s at time t and sends it to the subscribers.
+func (l *LiveScene) Publish(s *Scene, t time.Time) {
+	waypoints := make([]LiveWaypoint, 0, len(s.Interactions)+len(s.IdleInteractions))
+	for _, i := range s.Interactions {
+		waypoints = append(waypoints, LiveWaypoint{i.SceneID, false, i.LastWaypoint()})
+	}
+	for _, i := range s.IdleInteractions {
+		waypoints = append(waypoints, LiveWaypoint{i.SceneID, true, i.LastWaypoint()})
+	}
+
+	l.set(LiveSnapshot{true, len(s.Interactions), len(s.IdleInteractions), waypoints, t})
+}
+
+// Stop records that the scene is no longer being measured, and sends it to the subscribers.
+func (l *LiveScene) Stop(t time.Time) {
+	l.set(LiveSnapshot{false, 0, 0, []LiveWaypoint{}, t})
+}
+
+func (l *LiveScene) set(snapshot LiveSnapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.snapshot = snapshot
+	for c := range l.subscribers {
+		// Subscribers only need the latest snapshot, so replace any they haven't read yet
+		// rather than holding up the scene.
+		select {
+		case <-c:
+		default:
+		}
+		c <- snapshot
+	}
+}
+
+// Snapshot returns the latest state of the scene.
+func (l *LiveScene) Snapshot() LiveSnapshot {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.snapshot
+}
+
+// Subscribe returns a channel that receives each new snapshot of the scene. Readers that
+// fall behind only see the latest snapshot.
+func (l *LiveScene) Subscribe() chan LiveSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := make(chan LiveSnapshot, 1)
+	l.subscribers[c] = true
+	return c
+}
+
+// Unsubscribe stops sending snapshots to the channel c.
+func (l *LiveScene) Unsubscribe(c chan LiveSnapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subscribers, c)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestLive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Live Suite")
+}
+
+var _ = Describe("LiveScene", func() {
+	s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "idle",
+		Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+		GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2,
+		IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal",
+		GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0,
+		Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame,
+		Privacy: DefaultPrivacy}
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+	wpA := Waypoint{100, 100, 20, 20, 0.0}
+	wpB := Waypoint{500, 100, 20, 20, 0.0}
+
+	It("should start empty and not measuring", func() {
+		l := NewLiveScene("")
+		Ω(l.Snapshot()).Should(Equal(LiveSnapshot{false, 0, 0, []LiveWaypoint{}, time.Time{}}))
+	})
+
+	It("should publish the active and idle interactions of a scene", func() {
+		si := InitScene(&s)
+		si.Update(nil, []Waypoint{wpA, wpB}, t)
+		si.Update(nil, []Waypoint{wpA}, t.Add(100*time.Millisecond))
+
+		l := NewLiveScene("")
+		l.Publish(si, t.Add(100*time.Millisecond))
+
+		ls := l.Snapshot()
+		Ω(ls.Measuring).Should(BeTrue())
+		Ω(ls.Active).Should(Equal(1))
+		Ω(ls.Idle).Should(Equal(1))
+		Ω(ls.UpdatedAt).Should(Equal(t.Add(100 * time.Millisecond)))
+		Ω(len(ls.Waypoints)).Should(Equal(2))
+		Ω(ls.Waypoints[0].Idle).Should(BeFalse())
+		Ω(ls.Waypoints[0].Waypoint.XPixels).Should(Equal(100))
+		Ω(ls.Waypoints[1].Idle).Should(BeTrue())
+		Ω(ls.Waypoints[1].Waypoint.XPixels).Should(Equal(500))
+	})
+
+	It("should send the latest snapshot to subscribers", func() {
+		si := InitScene(&s)
+		l := NewLiveScene("")
+		c := l.Subscribe()
+
+		si.Update(nil, []Waypoint{wpA}, t)
+		l.Publish(si, t)
+		si.Update(nil, []Waypoint{wpA, wpB}, t.Add(100*time.Millisecond))
+		l.Publish(si, t.Add(100*time.Millisecond))
+
+		ls := <-c
+		Ω(ls.Active).Should(Equal(2))
+		Ω(len(c)).Should(Equal(0))
+
+		l.Unsubscribe(c)
+		l.Stop(t.Add(time.Second))
+		Ω(len(c)).Should(Equal(0))
+		Ω(l.Snapshot().Measuring).Should(BeFalse())
+	})
+})
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models_test
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Match Suite")
+}
+
+// matchScene creates a scene that matches detections optimally with the cost of vec.MatchCost,
+// weighted by the supplied centroid, overlap and size weights.
+func matchScene(centroid float64, iou float64, size float64) *models.Scene {
+	s := models.Scout{MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+		MatchStrategy: models.OPTIMAL, GateSqDistance: 40000, ProcessNoise: 10000.0,
+		MeasurementNoise: 100.0, CentroidWeight: centroid, IoUWeight: iou, SizeWeight: size,
+		Frame: models.DefaultFrame}
+	scene := models.InitScene(&s)
+	scene.SetCost(vec.MatchCost(&s, s.Frame.Width, s.Frame.Height))
+
+	return scene
+}
+
+var _ = Describe("Match", func() {
+	// A large blob and a small blob, with the small blob moving right next to the large one.
+	large := models.Waypoint{100, 100, 60, 60, 0.0}
+	small := models.Waypoint{180, 100, 10, 10, 0.0}
+	largeA := models.Waypoint{140, 100, 60, 60, 0.0}
+	smallA := models.Waypoint{110, 100, 10, 10, 0.0}
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	It("should swap the blobs when only the centroid distance is used", func() {
+		scene := matchScene(1.0, 0.0, 0.0)
+		scene.Update(nil, []models.Waypoint{large, small}, t)
+		scene.Update(nil, []models.Waypoint{smallA, largeA}, t)
+
+		Ω(len(scene.Interactions)).Should(Equal(2))
+		Ω(scene.Interactions[0].Equal([]models.Waypoint{large, smallA})).Should(BeTrue())
+		Ω(scene.Interactions[1].Equal([]models.Waypoint{small, largeA})).Should(BeTrue())
+	})
+
+	It("should keep the blobs apart when the overlap is weighted", func() {
+		scene := matchScene(1.0, 0.5, 0.0)
+		scene.Update(nil, []models.Waypoint{large, small}, t)
+		scene.Update(nil, []models.Waypoint{smallA, largeA}, t)
+
+		Ω(len(scene.Interactions)).Should(Equal(2))
+		Ω(scene.Interactions[0].Equal([]models.Waypoint{large, largeA})).Should(BeTrue())
+		Ω(scene.Interactions[1].Equal([]models.Waypoint{small, smallA})).Should(BeTrue())
+	})
+
+	It("should keep the blobs apart when the change in size is weighted", func() {
+		scene := matchScene(1.0, 0.0, 1.0)
+		scene.Update(nil, []models.Waypoint{large, small}, t)
+		scene.Update(nil, []models.Waypoint{smallA, largeA}, t)
+
+		Ω(len(scene.Interactions)).Should(Equal(2))
+		Ω(scene.Interactions[0].Equal([]models.Waypoint{large, largeA})).Should(BeTrue())
+		Ω(scene.Interactions[1].Equal([]models.Waypoint{small, smallA})).Should(BeTrue())
+	})
+
+	It("should keep the blobs apart with both the overlap and size weighted", func() {
+		scene := matchScene(1.0, 0.5, 0.5)
+		scene.Update(nil, []models.Waypoint{large, small}, t)
+		scene.Update(nil, []models.Waypoint{smallA, largeA}, t)
+
+		Ω(len(scene.Interactions)).Should(Equal(2))
+		Ω(scene.Interactions[0].Equal([]models.Waypoint{large, largeA})).Should(BeTrue())
+		Ω(scene.Interactions[1].Equal([]models.Waypoint{small, smallA})).Should(BeTrue())
+	})
+})
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"strconv"
+	"time"
+)
+
+// Edge is the side of the frame that an interaction entered or left through.
+type Edge string
+
+const (
+	TOP      Edge = "top"
+	BOTTOM   Edge = "bottom"
+	LEFT     Edge = "left"
+	RIGHT    Edge = "right"
+	INTERIOR Edge = "interior" // The interaction started or finished away from the edges, like at a doorway.
+)
+
+func (e *Edge) Scan(value interface{}) error {
+	asBytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("Unable to deserialise Edge")
+	}
+
+	*e = Edge(string(asBytes))
+	return nil
+}
+
+// InteractionMetrics are figures derived from a single scout interaction. Distances are in
+// pixels, speeds in pixels per second and times in seconds.
+type InteractionMetrics struct {
+	InteractionId int64     `json:"interaction_id"`
+	ScoutUUID     string    `json:"scout_uuid"`
+	EnteredAt     time.Time `json:"entered_at"`
+	PathLength    float32   `json:"path_length"`    // The distance travelled.
+	MeanSpeed     float32   `json:"mean_speed"`     // The path length divided by the duration.
+	MaxSpeed      float32   `json:"max_speed"`      // The fastest speed between two waypoints.
+	LongestDwell  float32   `json:"longest_dwell"`  // The longest time spent standing still.
+	DwellPosition [2]int    `json:"dwell_position"` // Where the longest dwell happened.
+	Bounds        Path      `json:"bounds"`         // The top left and bottom right of the region covered.
+	EntryEdge     Edge      `json:"entry_edge"`
+	ExitEdge      Edge      `json:"exit_edge"`
+}
+
+// MetricsQuery filters the interaction metrics of a scout. Zero values match everything.
+type MetricsQuery struct {
+	From      time.Time // Only match interactions that entered at or after From.
+	To        time.Time // Only match interactions that entered before To.
+	EntryEdge Edge
+	ExitEdge  Edge
+	MinDwell  float32 // Only match interactions with a longest dwell of at least MinDwell seconds.
+}
+
+const metricsColumns = `interaction_id, scout_uuid, entered_at, path_length, mean_speed, max_speed,
+	longest_dwell, dwell_x, dwell_y, bounds, entry_edge, exit_edge`
+
+func scanMetrics(rows *sql.Rows) (InteractionMetrics, error) {
+	var m InteractionMetrics
+	var et time.Time
+	err := rows.Scan(&m.InteractionId, &m.ScoutUUID, &et, &m.PathLength, &m.MeanSpeed,
+		&m.MaxSpeed, &m.LongestDwell, &m.DwellPosition[0], &m.DwellPosition[1], &m.Bounds,
+		&m.EntryEdge, &m.ExitEdge)
+	m.EnteredAt = et.UTC()
+
+	return m, err
+}
+
+// Save stores the metrics, replacing any previously stored for the same interaction.
+func (m *InteractionMetrics) Save(db Queryer) error {
+	const query = `INSERT INTO interaction_metrics (` + metricsColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (interaction_id) DO UPDATE SET scout_uuid = EXCLUDED.scout_uuid,
+		entered_at = EXCLUDED.entered_at, path_length = EXCLUDED.path_length,
+		mean_speed = EXCLUDED.mean_speed, max_speed = EXCLUDED.max_speed,
+		longest_dwell = EXCLUDED.longest_dwell, dwell_x = EXCLUDED.dwell_x,
+		dwell_y = EXCLUDED.dwell_y, bounds = EXCLUDED.bounds, entry_edge = EXCLUDED.entry_edge,
+		exit_edge = EXCLUDED.exit_edge`
+	_, err := db.Exec(query, m.InteractionId, m.ScoutUUID, m.EnteredAt, m.PathLength, m.MeanSpeed,
+		m.MaxSpeed, m.LongestDwell, m.DwellPosition[0], m.DwellPosition[1], m.Bounds,
+		string(m.EntryEdge), string(m.ExitEdge))
+	return err
+}
+
+// GetInteractionMetrics returns the metrics of the interactions of a scout that match q, in
+// the order the interactions entered.
+func GetInteractionMetrics(db *sql.DB, scoutUUID string, q MetricsQuery) ([]*InteractionMetrics, error) {
+	query := `SELECT ` + metricsColumns + ` FROM interaction_metrics WHERE scout_uuid = $1`
+	args := []interface{}{scoutUUID}
+	where := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		query = query + " AND " + clause + " $" + strconv.Itoa(len(args))
+	}
+
+	if !q.From.IsZero() {
+		where("entered_at >=", q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		where("entered_at <", q.To.UTC())
+	}
+	if q.EntryEdge != "" {
+		where("entry_edge =", string(q.EntryEdge))
+	}
+	if q.ExitEdge != "" {
+		where("exit_edge =", string(q.ExitEdge))
+	}
+	if q.MinDwell > 0.0 {
+		where("longest_dwell >=", q.MinDwell)
+	}
+	query = query + " ORDER BY entered_at, interaction_id"
+
+	var result []*InteractionMetrics
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMetrics(rows)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, &m)
+	}
+
+	return result, rows.Err()
+}
+
+// InteractionMetricsAsJSON exports the metrics of every interaction, with their entry times
+// rounded to the privacy of the scout.
+func InteractionMetricsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/interaction_metrics.json"
+
+	privacies, err := GetScoutPrivacies(db)
+	if err != nil {
+		return file, err
+	}
+
+	const query = `SELECT ` + metricsColumns + ` FROM interaction_metrics`
+	rows, err := db.Query(query)
+	if err == sql.ErrNoRows {
+		return file, nil
+	} else if err != nil {
+		return file, err
+	}
+	defer rows.Close()
+
+	var result []InteractionMetrics
+	for rows.Next() {
+		m, err := scanMetrics(rows)
+		if err != nil {
+			return file, err
+		}
+		m.EnteredAt = privacies[m.ScoutUUID].Round(m.EnteredAt)
+
+		result = append(result, m)
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics Suite")
+}
+
+var _ = Describe("Interaction Metrics Model", func() {
+	AfterEach(cleaner)
+
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	insert := func(s *Scout, et time.Time, dwell float32, entry Edge) InteractionMetrics {
+		si := ScoutInteraction{-1, s.UUID, 2.0, Path{[2]int{0, 0}, [2]int{10, 10}}, Path{[2]int{1, 1}, [2]int{1, 1}},
+			RealArray{0.0, 2.0}, true, et}
+		err := si.Insert(db)
+		Ω(err).Should(BeNil())
+
+		m := InteractionMetrics{si.Id, s.UUID, et, 14.1, 7.0, 7.0, dwell, [2]int{10, 10},
+			Path{[2]int{0, 0}, [2]int{10, 10}}, entry, INTERIOR}
+		err = m.Save(db)
+		Ω(err).Should(BeNil())
+
+		return m
+	}
+
+	Context("Save", func() {
+		It("should be able to save and replace the metrics of an interaction", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			m := insert(&s, t, 1.0, LEFT)
+			ml, err := GetInteractionMetrics(db, s.UUID, MetricsQuery{})
+			Ω(err).Should(BeNil())
+			Ω(ml).Should(Equal([]*InteractionMetrics{&m}))
+
+			m.PathLength = 20.0
+			err = m.Save(db)
+			Ω(err).Should(BeNil())
+
+			ml, err = GetInteractionMetrics(db, s.UUID, MetricsQuery{})
+			Ω(err).Should(BeNil())
+			Ω(ml).Should(Equal([]*InteractionMetrics{&m}))
+		})
+	})
+
+	Context("GetInteractionMetrics", func() {
+		It("should filter metrics by time, edge and dwell", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			a := insert(&s, t, 1.0, LEFT)
+			b := insert(&s, t.Add(time.Hour), 10.0, TOP)
+			c := insert(&s, t.Add(2*time.Hour), 5.0, LEFT)
+
+			ml, err := GetInteractionMetrics(db, s.UUID, MetricsQuery{From: t.Add(time.Hour), To: t.Add(2 * time.Hour)})
+			Ω(err).Should(BeNil())
+			Ω(ml).Should(Equal([]*InteractionMetrics{&b}))
+
+			ml, err = GetInteractionMetrics(db, s.UUID, MetricsQuery{EntryEdge: LEFT})
+			Ω(err).Should(BeNil())
+			Ω(ml).Should(Equal([]*InteractionMetrics{&a, &c}))
+
+			ml, err = GetInteractionMetrics(db, s.UUID, MetricsQuery{EntryEdge: LEFT, MinDwell: 2.0})
+			Ω(err).Should(BeNil())
+			Ω(ml).Should(Equal([]*InteractionMetrics{&c}))
+		})
+	})
+})
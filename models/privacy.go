@@ -0,0 +1,284 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrBudgetSpent is returned when publishing a summary would spend more differential privacy
+// than the budget of its scout has left.
+var ErrBudgetSpent = errors.New("Unable to publish, the privacy budget of the scout has been spent")
+
+// PrivacySpend records the differential privacy spent publishing the summaries of a scout.
+type PrivacySpend struct {
+	Id        int64     `json:"id"`
+	ScoutUUID string    `json:"scout_uuid"`
+	Release   string    `json:"release"` // What was published.
+	Epsilon   float64   `json:"epsilon"` // The differential privacy spent publishing it.
+	SpentAt   time.Time `json:"spent_at"`
+}
+
+// SpendPrivacy records that release spent epsilon from the privacy budget of a scout at time t.
+// It returns ErrBudgetSpent, recording nothing, when the budget doesn't have epsilon left.
+func SpendPrivacy(db *sql.DB, scoutUUID string, epsilon float64, release string, t time.Time) (*PrivacySpend, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockBudget(tx, scoutUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := spendPrivacy(tx, scoutUUID, epsilon, release, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, tx.Commit()
+}
+
+// lockBudget holds the privacy budget of a scout until the end of the transaction tx, so that two
+// releases can't both spend the last of it.
+func lockBudget(tx *sql.Tx, scoutUUID string) error {
+	var uuid string
+	return tx.QueryRow(`SELECT uuid FROM scouts WHERE uuid = $1 FOR UPDATE`, scoutUUID).Scan(&uuid)
+}
+
+// spendPrivacy records the spend of epsilon by release within the transaction tx, which must
+// already hold the budget of the scout (see lockBudget).
+func spendPrivacy(tx *sql.Tx, scoutUUID string, epsilon float64, release string, t time.Time) (*PrivacySpend, error) {
+	var budget, spent float64
+	err := tx.QueryRow(`SELECT privacy_budget FROM scouts WHERE uuid = $1`, scoutUUID).Scan(&budget)
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `SELECT COALESCE(SUM(epsilon), 0) FROM privacy_spends WHERE scout_uuid = $1`
+	err = tx.QueryRow(query, scoutUUID).Scan(&spent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow for the rounding of the sum, so that a budget can be spent exactly.
+	if spent+epsilon > budget*(1.0+1e-9) {
+		return nil, ErrBudgetSpent
+	}
+
+	result := PrivacySpend{-1, scoutUUID, release, epsilon, t.UTC()}
+	const insert = `INSERT INTO privacy_spends (scout_uuid, release, epsilon, spent_at)
+				   VALUES ($1, $2, $3, $4) RETURNING id`
+	err = tx.QueryRow(insert, scoutUUID, release, epsilon, result.SpentAt).Scan(&result.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetPrivacySpends returns the ledger of the privacy spent publishing the summaries of a scout,
+// oldest first.
+func GetPrivacySpends(db *sql.DB, scoutUUID string) ([]*PrivacySpend, error) {
+	const query = `SELECT id, release, epsilon, spent_at FROM privacy_spends WHERE scout_uuid = $1
+				   ORDER BY spent_at, id`
+
+	result := []*PrivacySpend{}
+	rows, err := db.Query(query, scoutUUID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s PrivacySpend
+		s.ScoutUUID = scoutUUID
+		err = rows.Scan(&s.Id, &s.Release, &s.Epsilon, &s.SpentAt)
+		if err != nil {
+			return result, err
+		}
+		s.SpentAt = s.SpentAt.UTC()
+
+		result = append(result, &s)
+	}
+
+	return result, rows.Err()
+}
+
+// cryptoSource draws random numbers from crypto/rand, so that the noise added to published
+// summaries can't be predicted and taken away again.
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	var b [8]byte
+	_, err := crand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return int64(binary.LittleEndian.Uint64(b[:]) &^ (1 << 63))
+}
+
+func (cryptoSource) Seed(int64) {}
+
+// noise is the source of the noise added to published summaries.
+var noise = rand.New(cryptoSource{})
+
+// laplace draws from the Laplace distribution centred on zero with scale b.
+func laplace(r *rand.Rand, b float64) float64 {
+	u := r.Float64() - 0.5
+	for u == -0.5 {
+		u = r.Float64() - 0.5
+	}
+
+	if u < 0.0 {
+		return b * math.Log(1.0+2.0*u)
+	}
+
+	return -b * math.Log(1.0-2.0*u)
+}
+
+// noisyCount adds Laplace noise with scale b to the count n, rounding the result to a count that
+// isn't negative.
+func noisyCount(r *rand.Rand, n float64, b float64) float64 {
+	return math.Max(0.0, math.Floor(n+laplace(r, b)+0.5))
+}
+
+// AddNoise adds Laplace noise to the counts and times of the summary, so that publishing it spends
+// the epsilon of p. The epsilon is split evenly between the visitor count, the visitor buckets
+// and the time buckets. Each interaction is clipped to the sensitivities of p as it is
+// summarised (see Privacy.Clip), so one visitor changes the visitor count by one, the visitor
+// buckets by BucketSensitivity in all, and the time buckets by TimeSensitivity seconds in all.
+// The noise of each is scaled to match. Noisy counts are rounded, and nothing is negative.
+func (ss *ScoutSummary) AddNoise(p Privacy, r *rand.Rand) {
+	b := 3.0 / p.Epsilon
+
+	ss.VisitorCount = int64(noisyCount(r, float64(ss.VisitorCount), b))
+	for i := range ss.VisitorBuckets {
+		for j := range ss.VisitorBuckets[i] {
+			v := noisyCount(r, float64(ss.VisitorBuckets[i][j]), b*float64(p.BucketSensitivity))
+			ss.VisitorBuckets[i][j] = int(v)
+
+			t := float64(ss.VisitTimeBuckets[i][j]) + laplace(r, b*p.TimeSensitivity)
+			ss.VisitTimeBuckets[i][j] = float32(math.Max(0.0, t))
+		}
+	}
+}
+
+// digest identifies the exact counts and times of the summary, along with the privacy p they are
+// released under, so that a release of the same summary can be found again.
+func (ss *ScoutSummary) digest(p Privacy) string {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, []float64{p.Epsilon, p.TimeSensitivity})
+	binary.Write(h, binary.LittleEndian, []int64{int64(p.BucketSensitivity), ss.VisitorCount})
+	for i := range ss.VisitorBuckets {
+		binary.Write(h, binary.LittleEndian, int64(len(ss.VisitorBuckets[i])))
+		for j := range ss.VisitorBuckets[i] {
+			binary.Write(h, binary.LittleEndian, int64(ss.VisitorBuckets[i][j]))
+			binary.Write(h, binary.LittleEndian, ss.VisitTimeBuckets[i][j])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// release adds noise to the summary under the privacy p, spending its epsilon as release. A
+// summary that has been released before, with the same counts, times and privacy, is given the
+// noise it was released with again rather than spending the budget twice; fresh noise on the
+// same summary would only let the noise be averaged away.
+func (ss *ScoutSummary) release(db *sql.DB, p Privacy, release string, r *rand.Rand) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockBudget(tx, ss.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	digest := ss.digest(p)
+	noisy := ScoutSummary{ScoutUUID: ss.ScoutUUID}
+	const query = `SELECT visitor_count, visit_time_buckets, visitor_buckets FROM privacy_releases
+				   WHERE scout_uuid = $1 AND digest = $2`
+	err = tx.QueryRow(query, ss.ScoutUUID, digest).Scan(&noisy.VisitorCount, &noisy.VisitTimeBuckets,
+		&noisy.VisitorBuckets)
+	if err == nil {
+		*ss = noisy
+		return nil
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	t := time.Now()
+	_, err = spendPrivacy(tx, ss.ScoutUUID, p.Epsilon, release, t)
+	if err != nil {
+		return err
+	}
+
+	noisy = ScoutSummary{ss.ScoutUUID, ss.VisitorCount, NewBuckets(ss.Grid()), NewIntBuckets(ss.Grid())}
+	for i := range ss.VisitorBuckets {
+		copy(noisy.VisitTimeBuckets[i], ss.VisitTimeBuckets[i])
+		copy(noisy.VisitorBuckets[i], ss.VisitorBuckets[i])
+	}
+	noisy.AddNoise(p, r)
+
+	const insert = `INSERT INTO privacy_releases (scout_uuid, digest, visitor_count, visit_time_buckets,
+				   visitor_buckets, released_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = tx.Exec(insert, ss.ScoutUUID, digest, noisy.VisitorCount, noisy.VisitTimeBuckets,
+		noisy.VisitorBuckets, t.UTC())
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	*ss = noisy
+	return nil
+}
+
+// Publish prepares the summary to be shared as release, under the privacy p of its scout. When p
+// has an epsilon, it is spent from the budget of the scout and noise is added to the summary. An
+// identical summary that has already been published is given the same noise again, without
+// spending any more of the budget. Small counts are then suppressed. Publish returns
+// ErrBudgetSpent, leaving the summary as it was, once the budget has been spent.
+func (ss *ScoutSummary) Publish(db *sql.DB, p Privacy, release string) error {
+	if p.Epsilon > 0.0 {
+		err := ss.release(db, p, release, noise)
+		if err != nil {
+			return err
+		}
+	}
+
+	ss.Suppress(p.MinVisitors)
+	return nil
+}
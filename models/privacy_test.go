@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPrivacy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Privacy Suite")
+}
+
+var _ = Describe("Privacy Model", func() {
+	AfterEach(cleaner)
+
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	scout := func(p Privacy) *Scout {
+		s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+			State: "measuring", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+			ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+			SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+			MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+			CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0,
+			Frame: DefaultFrame, Privacy: p}
+		err := s.Insert(db)
+		Ω(err).Should(BeNil())
+
+		return &s
+	}
+
+	Context("laplace", func() {
+		It("should draw from a Laplace distribution of the scale", func() {
+			r := rand.New(rand.NewSource(1))
+			var sum, abs float64
+			n := 100000
+			for i := 0; i < n; i++ {
+				x := laplace(r, 2.0)
+				sum += x
+				abs += math.Abs(x)
+			}
+
+			// The mean absolute deviation of a Laplace distribution is its scale.
+			Ω(sum / float64(n)).Should(BeNumerically("~", 0.0, 0.05))
+			Ω(abs / float64(n)).Should(BeNumerically("~", 2.0, 0.05))
+		})
+	})
+
+	Context("AddNoise", func() {
+		It("should add noise without making anything negative", func() {
+			ss := NewScoutSummary("", Frame{640, 480, 8, 6})
+			ss.VisitorCount = 100
+			ss.VisitorBuckets[3][2] = 50
+			ss.VisitTimeBuckets[3][2] = 600.0
+
+			ss.AddNoise(Privacy{0, 15, 1.0, 10.0, 60.0, 20}, rand.New(rand.NewSource(1)))
+			Ω(ss.VisitorCount).ShouldNot(Equal(int64(100)))
+			Ω(ss.VisitorCount).Should(BeNumerically("~", 100, 60))
+			for i := range ss.VisitorBuckets {
+				for j := range ss.VisitorBuckets[i] {
+					Ω(ss.VisitorBuckets[i][j]).Should(BeNumerically(">=", 0))
+					Ω(ss.VisitTimeBuckets[i][j]).Should(BeNumerically(">=", 0.0))
+				}
+			}
+		})
+
+		It("should scale the noise of the buckets to the sensitivities", func() {
+			ss := NewScoutSummary("", Frame{1280, 720, 40, 30})
+			for i := range ss.VisitorBuckets {
+				for j := range ss.VisitorBuckets[i] {
+					ss.VisitorBuckets[i][j] = 10000
+					ss.VisitTimeBuckets[i][j] = 100000.0
+				}
+			}
+
+			// An epsilon of three gives each of the visitor and time buckets a scale of one.
+			ss.AddNoise(Privacy{0, 15, 3.0, 10.0, 60.0, 5}, rand.New(rand.NewSource(1)))
+
+			v, t := 0.0, 0.0
+			for i := range ss.VisitorBuckets {
+				for j := range ss.VisitorBuckets[i] {
+					v += math.Abs(float64(ss.VisitorBuckets[i][j]) - 10000.0)
+					t += math.Abs(float64(ss.VisitTimeBuckets[i][j]) - 100000.0)
+				}
+			}
+
+			Ω(v / 1200.0).Should(BeNumerically("~", 5.0, 0.75))
+			Ω(t / 1200.0).Should(BeNumerically("~", 60.0, 9.0))
+		})
+	})
+
+	Context("SpendPrivacy", func() {
+		It("should refuse to spend more than the budget", func() {
+			s := scout(Privacy{0, 15, 0.4, 1.0, 60.0, 20})
+
+			for _, release := range []string{"a", "b"} {
+				_, err := SpendPrivacy(db, s.UUID, 0.4, release, t)
+				Ω(err).Should(BeNil())
+			}
+
+			_, err := SpendPrivacy(db, s.UUID, 0.4, "c", t)
+			Ω(err).Should(Equal(ErrBudgetSpent))
+
+			// The rest of the budget can still be spent exactly.
+			_, err = SpendPrivacy(db, s.UUID, 0.2, "d", t)
+			Ω(err).Should(BeNil())
+
+			sl, err := GetPrivacySpends(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(sl)).Should(Equal(3))
+			Ω(sl[2]).Should(Equal(&PrivacySpend{sl[2].Id, s.UUID, "d", 0.2, t}))
+		})
+	})
+
+	Context("Publish", func() {
+		It("should only spend the budget when adding noise", func() {
+			s := scout(Privacy{2, 15, 0.0, 0.0, 60.0, 20})
+
+			ss := NewScoutSummary(s.UUID, DefaultFrame)
+			ss.VisitorCount = 1
+			err := ss.Publish(db, s.Privacy, "test")
+			Ω(err).Should(BeNil())
+			Ω(ss.VisitorCount).Should(Equal(int64(0)))
+
+			sl, err := GetPrivacySpends(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(sl).Should(BeEmpty())
+		})
+
+		It("should leave the summary as it was once the budget is spent", func() {
+			s := scout(Privacy{0, 15, 1.0, 1.0, 60.0, 20})
+
+			ss := NewScoutSummary(s.UUID, DefaultFrame)
+			err := ss.Publish(db, s.Privacy, "first")
+			Ω(err).Should(BeNil())
+
+			ss = NewScoutSummary(s.UUID, DefaultFrame)
+			ss.VisitorCount = 10
+			err = ss.Publish(db, s.Privacy, "second")
+			Ω(err).Should(Equal(ErrBudgetSpent))
+			Ω(ss).Should(Equal(&ScoutSummary{s.UUID, 10, NewBuckets(20, 20), NewIntBuckets(20, 20)}))
+
+			// The stored summary is never touched.
+			stored, err := GetScoutSummaryByUUID(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(stored).Should(Equal(NewScoutSummary(s.UUID, DefaultFrame)))
+		})
+
+		It("should give an identical summary the same noise again without spending", func() {
+			s := scout(Privacy{0, 15, 1.0, 1.0, 60.0, 20})
+
+			summary := func(n int64) *ScoutSummary {
+				ss := NewScoutSummary(s.UUID, DefaultFrame)
+				ss.VisitorCount = n
+				ss.VisitorBuckets[4][5] = int(n)
+				ss.VisitTimeBuckets[4][5] = 100.0
+				return ss
+			}
+
+			first := summary(1000)
+			err := first.Publish(db, s.Privacy, "scout_summaries.json")
+			Ω(err).Should(BeNil())
+
+			// Whatever it is released as.
+			second := summary(1000)
+			err = second.Publish(db, s.Privacy, "floor heatmap")
+			Ω(err).Should(BeNil())
+			Ω(second).Should(Equal(first))
+
+			sl, err := GetPrivacySpends(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(len(sl)).Should(Equal(1))
+			Ω(sl[0].Release).Should(Equal("scout_summaries.json"))
+
+			// A summary that has changed is a new release.
+			err = summary(1001).Publish(db, s.Privacy, "scout_summaries.json")
+			Ω(err).Should(Equal(ErrBudgetSpent))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Prune records the measurements that were deleted because they were older than their retention.
+// A zero before time means those measurements are kept forever.
+type Prune struct {
+	Id                 int64     `json:"id"`
+	PrunedAt           time.Time `json:"pruned_at"`
+	InteractionsBefore time.Time `json:"interactions_before"` // Interactions last in view before this were deleted.
+	Interactions       int64     `json:"interactions"`        // The number of interactions deleted.
+	HealthsBefore      time.Time `json:"healths_before"`      // Health heartbeats created before this were deleted.
+	Healths            int64     `json:"healths"`             // The number of health heartbeats deleted.
+	LogsBefore         time.Time `json:"logs_before"`         // Logs created before this were deleted.
+	Logs               int64     `json:"logs"`                // The number of logs deleted.
+}
+
+const pruneColumns = `pruned_at, interactions_before, interactions, healths_before, healths,
+	logs_before, logs`
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) pq.NullTime {
+	return pq.NullTime{t.UTC(), !t.IsZero()}
+}
+
+// LockPrunes stops measurements from being pruned by anyone else until the end of the
+// transaction. It waits for anyone reading the prune horizon with GetInteractionsPrunedBefore.
+func LockPrunes(tx *sql.Tx) error {
+	_, err := tx.Exec(`LOCK TABLE prunes IN EXCLUSIVE MODE`)
+	return err
+}
+
+func (p *Prune) Insert(db Queryer) error {
+	const query = `INSERT INTO prunes (` + pruneColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	return db.QueryRow(query, p.PrunedAt.UTC(), nullTime(p.InteractionsBefore), p.Interactions,
+		nullTime(p.HealthsBefore), p.Healths, nullTime(p.LogsBefore), p.Logs).Scan(&p.Id)
+}
+
+// GetPrunes returns every prune, most recent first.
+func GetPrunes(db *sql.DB) ([]*Prune, error) {
+	const query = `SELECT id, ` + pruneColumns + ` FROM prunes ORDER BY pruned_at DESC, id DESC`
+
+	result := []*Prune{}
+	rows, err := db.Query(query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Prune
+		var ib, hb, lb pq.NullTime
+		err = rows.Scan(&p.Id, &p.PrunedAt, &ib, &p.Interactions, &hb, &p.Healths, &lb, &p.Logs)
+		if err != nil {
+			return result, err
+		}
+		p.PrunedAt = p.PrunedAt.UTC()
+		p.InteractionsBefore = ib.Time.UTC()
+		p.HealthsBefore = hb.Time.UTC()
+		p.LogsBefore = lb.Time.UTC()
+
+		result = append(result, &p)
+	}
+
+	return result, rows.Err()
+}
+
+// GetInteractionsPrunedBefore returns the time that summarised interactions have been deleted
+// before, or the zero time when none have been. Nothing more is pruned until the end of the
+// transaction, so the interactions after the time can be relied upon while it lasts.
+func GetInteractionsPrunedBefore(tx *sql.Tx) (time.Time, error) {
+	_, err := tx.Exec(`LOCK TABLE prunes IN SHARE MODE`)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var before pq.NullTime
+	err = tx.QueryRow(`SELECT max(interactions_before) FROM prunes WHERE interactions > 0`).Scan(&before)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return before.Time.UTC(), nil
+}
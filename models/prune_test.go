@@ -0,0 +1,164 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Prune Suite")
+}
+
+var _ = Describe("Prune Model", func() {
+	AfterEach(cleaner)
+
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	scout := func() *Scout {
+		s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+			State: "measuring", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+			ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+			SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+			MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+			CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0,
+			Frame: DefaultFrame, Privacy: DefaultPrivacy}
+		err := s.Insert(db)
+		Ω(err).Should(BeNil())
+
+		return &s
+	}
+
+	Context("PruneInteractions", func() {
+		It("should only delete summarised interactions that left view before the time", func() {
+			s := scout()
+
+			old := ScoutInteraction{-1, s.UUID, 60.0, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.0}, true, t.Add(-2 * time.Minute)}
+			err := old.Insert(db)
+			Ω(err).Should(BeNil())
+
+			inView := ScoutInteraction{-1, s.UUID, 180.0, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.0}, true, t.Add(-2 * time.Minute)}
+			err = inView.Insert(db)
+			Ω(err).Should(BeNil())
+
+			unprocessed := ScoutInteraction{-1, s.UUID, 60.0, Path{[2]int{1, 2}}, Path{[2]int{3, 4}}, RealArray{0.0}, false, t.Add(-time.Hour)}
+			err = unprocessed.Insert(db)
+			Ω(err).Should(BeNil())
+
+			m := InteractionMetrics{old.Id, s.UUID, old.EnteredAt, 1.0, 1.0, 1.0, 0.0, [2]int{1, 2}, Path{[2]int{1, 2}, [2]int{1, 2}}, "left", "right"}
+			err = m.Save(db)
+			Ω(err).Should(BeNil())
+
+			n, err := PruneInteractions(db, t)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+
+			_, err = GetScoutInteractionById(db, old.Id)
+			Ω(err).ShouldNot(BeNil())
+			_, err = GetScoutInteractionById(db, inView.Id)
+			Ω(err).Should(BeNil())
+			_, err = GetScoutInteractionById(db, unprocessed.Id)
+			Ω(err).Should(BeNil())
+
+			// The metrics of a pruned interaction are kept.
+			ml, err := GetInteractionMetrics(db, s.UUID, MetricsQuery{})
+			Ω(err).Should(BeNil())
+			Ω(len(ml)).Should(Equal(1))
+		})
+	})
+
+	Context("PruneScoutHealths", func() {
+		It("should delete the health heartbeats created before the time", func() {
+			s := scout()
+
+			for _, ct := range []time.Time{t.Add(-time.Minute), t} {
+				sh := ScoutHealth{s.UUID, 0.1, 0.2, 0.3, 0.4, ct}
+				err := sh.Insert(db)
+				Ω(err).Should(BeNil())
+			}
+
+			n, err := PruneScoutHealths(db, t)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+
+			n, err = NumScoutHealths(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+		})
+	})
+
+	Context("PruneScoutLogs", func() {
+		It("should delete the logs created before the time", func() {
+			s := scout()
+
+			for _, ct := range []time.Time{t.Add(-time.Minute), t} {
+				sl := ScoutLog{s.UUID, []byte("abc"), ct}
+				err := sl.Insert(db)
+				Ω(err).Should(BeNil())
+			}
+
+			n, err := PruneScoutLogs(db, t)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+
+			n, err = NumScoutLogs(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+		})
+	})
+
+	Context("Insert", func() {
+		It("should record what was pruned", func() {
+			p := Prune{-1, t, t.Add(-90 * 24 * time.Hour), 3, time.Time{}, 0, t.Add(-30 * 24 * time.Hour), 2}
+			err := p.Insert(db)
+			Ω(err).Should(BeNil())
+
+			pl, err := GetPrunes(db)
+			Ω(err).Should(BeNil())
+			Ω(pl).Should(Equal([]*Prune{&p}))
+		})
+	})
+
+	Context("GetInteractionsPrunedBefore", func() {
+		It("should return the latest time interactions were deleted before", func() {
+			tx, err := db.Begin()
+			Ω(err).Should(BeNil())
+			before, err := GetInteractionsPrunedBefore(tx)
+			Ω(err).Should(BeNil())
+			Ω(before.IsZero()).Should(BeTrue())
+			tx.Rollback()
+
+			for i, n := range []int64{1, 4, 0} {
+				p := Prune{-1, t, t.Add(time.Duration(i) * time.Hour), n, time.Time{}, 0, time.Time{}, 0}
+				err = p.Insert(db)
+				Ω(err).Should(BeNil())
+			}
+
+			tx, err = db.Begin()
+			Ω(err).Should(BeNil())
+			defer tx.Rollback()
+			before, err = GetInteractionsPrunedBefore(tx)
+			Ω(err).Should(BeNil())
+			Ω(before).Should(Equal(t.Add(time.Hour)))
+		})
+	})
+})
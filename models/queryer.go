@@ -1,5 +1,5 @@
 /*
- * Copyright (C) 2017 Clinton Freeman
+ * Copyright (C) 2016 Clinton Freeman
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU General Public License as published by
@@ -14,14 +14,16 @@
  * You should have received a copy of the GNU General Public License
  * along with this program.  If not, see <http://www.gnu.org/licenses/>.
  */
+package models
 
-package configuration
-
-const (
-	FrameW   = 1280              // The width of the frames captured by the Webcam in pixels.
-	FrameH   = 720               // The height of the frames captured by the webcam in pixels.
-	WBuckets = 20                // The number of horizontal buckets a frame is broken into.
-	HBuckets = 20                // The number of vertical buckets a frame is broken into.
-	BucketW  = FrameW / WBuckets // The width of a bucket in pixels.
-	BucketH  = FrameH / HBuckets // The height of a bucket in pixels
+import (
+	"database/sql"
 )
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx, allowing the models that summarise
+// interactions to be updated together within a single transaction.
+type Queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
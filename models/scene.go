@@ -19,25 +19,70 @@ package models
 
 import (
 	"database/sql"
-	"encoding/json"
-	"io/ioutil"
 	"math"
 	"time"
 )
 
+// CostFunc returns the cost of pairing a detected waypoint with the predicted position of an
+// interaction. Lower costs are better matches.
+type CostFunc func(detected Waypoint, predicted Waypoint) float64
+
 type Scene struct {
 	Interactions     []Interaction // The current interactions occuring within the scene.
 	IdleInteractions []Interaction // The current interactions that are idle (resumable).
 	sId              int
 	dScout           *Scout
+	cost             CostFunc        // The cost used to match detections with interactions.
+	sink             InteractionSink // Where finished interactions are saved, nil for the DB.
 }
 
 // initScene creates an empty scene that can be used for monitoring interactions.
 func InitScene(scout *Scout) *Scene {
-	return &Scene{[]Interaction{}, []Interaction{}, 0, scout}
+	return &Scene{[]Interaction{}, []Interaction{}, 0, scout, nil, nil}
+}
+
+// SetCost replaces the cost used by the optimal matcher to pair detected waypoints with
+// interactions. A nil cost falls back to the squared distance between centroids.
+func (s *Scene) SetCost(cost CostFunc) {
+	s.cost = cost
+}
+
+// SetSink replaces where the scene saves finished interactions. A nil sink saves them to the
+// DB supplied to Update and Close.
+func (s *Scene) SetSink(sink InteractionSink) {
+	s.sink = sink
+}
+
+// saveInteraction stores the finished interaction i.
+func (s *Scene) saveInteraction(db *sql.DB, i *Interaction) {
+	if s.sink == nil {
+		i.saveToDB(db)
+	} else {
+		i.save(s.sink)
+	}
+}
+
+// matchCost returns the cost of pairing the detected waypoint with the predicted waypoint.
+func (s *Scene) matchCost(detected Waypoint, predicted Waypoint) float64 {
+	if s.cost == nil {
+		return float64(detected.distanceSq(predicted))
+	}
+
+	return s.cost(detected, predicted)
 }
 
-func (s *Scene) buildDistanceMap(detected []Waypoint) map[int][]int {
+// predicted returns where the supplied interaction is expected to be at time t.
+func (s *Scene) predicted(i *Interaction, t time.Time) Waypoint {
+	return i.predict(float32(t.Sub(i.started).Seconds()))
+}
+
+// lastSeen returns the time the supplied interaction was last detected.
+func (s *Scene) lastSeen(i *Interaction) time.Time {
+	wp := i.LastWaypoint()
+	return i.started.Add(time.Duration(float64(wp.T) * float64(time.Second)))
+}
+
+func (s *Scene) buildDistanceMap(detected []Waypoint, t time.Time) map[int][]int {
 	var distances map[int][]int = make(map[int][]int)
 
 	// For each of the detected waypoints, work out the
@@ -47,7 +92,7 @@ func (s *Scene) buildDistanceMap(detected []Waypoint) map[int][]int {
 		closestInteraction := -1
 
 		for j := 0; j < len(s.Interactions); j++ {
-			d := detected[i].distanceSq(s.Interactions[j].LastWaypoint())
+			d := detected[i].distanceSq(s.predicted(&s.Interactions[j], t))
 			if d < dist {
 				dist = d
 				closestInteraction = j
@@ -61,11 +106,11 @@ func (s *Scene) buildDistanceMap(detected []Waypoint) map[int][]int {
 }
 
 // addInteraction
-func (s *Scene) addInteraction(detected []Waypoint) {
+func (s *Scene) addInteraction(detected []Waypoint, t time.Time) {
 	if len(s.Interactions) == 0 {
 		// Empty scene: just add a new interaction for each new waypoint.
 		for i := 0; i < len(detected); i++ {
-			s.Interactions = append(s.Interactions, NewInteraction(detected[i], s.sId, s.dScout))
+			s.Interactions = append(s.Interactions, NewInteraction(detected[i], s.sId, s.dScout, t))
 			s.sId++
 		}
 
@@ -76,7 +121,7 @@ func (s *Scene) addInteraction(detected []Waypoint) {
 		//   for interactions that have more than one close detected waypoints
 		//		create a new interaction from the furthest detected waypoint
 		// 		the nearest waypoint is used to update the existing interaction.
-		distances := s.buildDistanceMap(detected)
+		distances := s.buildDistanceMap(detected, t)
 
 		for i := 0; i < len(distances); i++ {
 			dist := math.MaxInt32
@@ -93,40 +138,40 @@ func (s *Scene) addInteraction(detected []Waypoint) {
 			if i == closestI {
 				// If this detected element is the closest to an interaction - update the interaction with the
 				// detected waypoint.
-				s.Interactions[distances[i][1]].addWaypoint(detected[i])
+				s.Interactions[distances[i][1]].addWaypoint(detected[i], t)
 			} else {
 				// This detected element doesn't appear to belong to an existing interaction within the scene.
-				// Before we create a new interaction, we check and if we can use the detected element to
-				// resume an existing interaction.
-				t := time.Now().UTC()
-				resumed := false
-
-				for k := len(s.IdleInteractions) - 1; k >= 0; k-- {
-					wp := s.IdleInteractions[k].LastWaypoint()
-					wpt := s.IdleInteractions[k].started.Add(time.Duration(wp.T) * time.Second)
-					dt := float32(t.Sub(wpt).Seconds())
-
-					if detected[i].distanceSq(wp) < s.dScout.ResumeSqDistance && dt < s.dScout.IdleDuration {
-						// Resume idle interaction.
-						s.IdleInteractions[k].addWaypoint(detected[i])
-						s.Interactions = append(s.Interactions, s.IdleInteractions[k])
-						s.IdleInteractions = append(s.IdleInteractions[:k], s.IdleInteractions[k+1:]...)
-						resumed = true
-					}
-				}
-
-				// We haven't resumed an idle interaction, so the detected element must be a new interaction.
-				if !resumed {
-					s.Interactions = append(s.Interactions, NewInteraction(detected[i], s.sId, s.dScout))
-					s.sId++
-				}
+				s.resumeInteraction(detected[i], t)
 			}
 		}
 	}
 }
 
-func (s *Scene) removeInteraction(detected []Waypoint) {
-	distances := s.buildDistanceMap(detected)
+// resumeInteraction updates the scene with a detected waypoint that doesn't belong to any of
+// the active interactions. Before creating a new interaction, we check and see if we can use the
+// detected waypoint to resume an idle interaction.
+func (s *Scene) resumeInteraction(detected Waypoint, t time.Time) {
+	for k := len(s.IdleInteractions) - 1; k >= 0; k-- {
+		dt := float32(t.Sub(s.lastSeen(&s.IdleInteractions[k])).Seconds())
+
+		// Compare against where the idle interaction would be by now, had it kept moving.
+		p := s.predicted(&s.IdleInteractions[k], t)
+		if detected.distanceSq(p) < s.dScout.ResumeSqDistance && dt < s.dScout.IdleDuration {
+			// Resume idle interaction.
+			s.IdleInteractions[k].addWaypoint(detected, t)
+			s.Interactions = append(s.Interactions, s.IdleInteractions[k])
+			s.IdleInteractions = append(s.IdleInteractions[:k], s.IdleInteractions[k+1:]...)
+			return
+		}
+	}
+
+	// We haven't resumed an idle interaction, so the detected element must be a new interaction.
+	s.Interactions = append(s.Interactions, NewInteraction(detected, s.sId, s.dScout, t))
+	s.sId++
+}
+
+func (s *Scene) removeInteraction(detected []Waypoint, t time.Time) {
+	distances := s.buildDistanceMap(detected, t)
 	matched := map[int]int{}
 
 	for i := 0; i < len(distances); i++ {
@@ -135,7 +180,7 @@ func (s *Scene) removeInteraction(detected []Waypoint) {
 
 	for i := len(s.Interactions) - 1; i >= 0; i-- {
 		if v, ok := matched[i]; ok {
-			s.Interactions[i].addWaypoint(detected[v])
+			s.Interactions[i].addWaypoint(detected[v], t)
 		} else {
 			// Interactions are not removed (and broadcasted to the mothership) immediately,
 			// they are marked as idle first and can be subsequently resumed by waypoints
@@ -147,44 +192,103 @@ func (s *Scene) removeInteraction(detected []Waypoint) {
 	}
 }
 
-func (s *Scene) Update(db *sql.DB, detected []Waypoint) {
-	if len(detected) >= len(s.Interactions) {
-		s.addInteraction(detected)
+// assignInteractions updates the scene by pairing detected waypoints and interactions so that
+// the total distance between the waypoints and the predicted position of each interaction is as
+// small as possible. Unlike the greedy nearest neighbour match, this stops people who pass close
+// to each other from swapping identities. Pairings that cost more than the GateSqDistance of the
+// scout are never made.
+func (s *Scene) assignInteractions(detected []Waypoint, t time.Time) {
+	predictions := make([]Waypoint, len(s.Interactions))
+	for j := 0; j < len(s.Interactions); j++ {
+		predictions[j] = s.predicted(&s.Interactions[j], t)
+	}
+
+	costs := make([][]float64, len(detected))
+	for i := 0; i < len(detected); i++ {
+		costs[i] = make([]float64, len(s.Interactions))
+
+		for j := 0; j < len(s.Interactions); j++ {
+			costs[i][j] = s.matchCost(detected[i], predictions[j])
+		}
+	}
+
+	pairs := assign(costs, float64(s.dScout.GateSqDistance))
+	matched := make([]bool, len(s.Interactions))
+	for i, j := range pairs {
+		if j >= 0 {
+			s.Interactions[j].addWaypoint(detected[i], t)
+			matched[j] = true
+		}
+	}
+
+	// Interactions that no longer have a detected waypoint become idle. They are only moved
+	// into the idle list once the unpaired waypoints below have been placed, so that an
+	// interaction can't be resumed by a detection that was just rejected by the gate.
+	var active []Interaction
+	var idle []Interaction
+	for j, m := range matched {
+		if m {
+			active = append(active, s.Interactions[j])
+		} else {
+			idle = append(idle, s.Interactions[j])
+		}
+	}
+	s.Interactions = active
+
+	for i, j := range pairs {
+		if j < 0 {
+			s.resumeInteraction(detected[i], t)
+		}
+	}
+
+	s.IdleInteractions = append(s.IdleInteractions, idle...)
+}
+
+// Update the scene with the waypoints detected in a frame captured at time t. All the timing
+// within the scene (waypoint times, durations and idle expiry) is measured from the frame
+// times, so recorded video can be processed faster than real time.
+func (s *Scene) Update(db *sql.DB, detected []Waypoint, t time.Time) {
+	if s.dScout.MatchStrategy != OPTIMAL {
+		if len(detected) >= len(s.Interactions) {
+			s.addInteraction(detected, t)
+		} else {
+			s.removeInteraction(detected, t)
+		}
 	} else {
-		s.removeInteraction(detected)
+		s.assignInteractions(detected, t)
 	}
 
 	// broadcast idle interactions that have expired and are no longer resumable.
-	t := time.Now().UTC()
-	for i := len(s.IdleInteractions) - 1; i >= 0; i-- {
-		wp := s.IdleInteractions[i].LastWaypoint()
-		wpt := s.IdleInteractions[i].started.Add(time.Duration(wp.T) * time.Second)
-		dt := float32(t.Sub(wpt).Seconds())
+	s.IdleInteractions = s.expire(db, s.IdleInteractions, t)
+}
+
+// expire saves and removes the interactions that haven't been seen for longer than the idle
+// duration at time t, returning the interactions that remain.
+func (s *Scene) expire(db *sql.DB, interactions []Interaction, t time.Time) []Interaction {
+	for i := len(interactions) - 1; i >= 0; i-- {
+		dt := float32(t.Sub(s.lastSeen(&interactions[i])).Seconds())
 
 		if dt > s.dScout.IdleDuration {
 			// Only transmit the interaction to the mothership if it is longer than the
 			// specified minimum duration. This is to filter out any detected noise.
-			if s.IdleInteractions[i].Duration > s.dScout.MinDuration {
-				s.IdleInteractions[i].saveToDB(db)
+			if interactions[i].Duration > s.dScout.MinDuration {
+				s.saveInteraction(db, &interactions[i])
 			}
 
-			s.IdleInteractions = append(s.IdleInteractions[:i], s.IdleInteractions[i+1:]...)
+			interactions = append(interactions[:i], interactions[i+1:]...)
 		}
 	}
+
+	return interactions
 }
 
 func (s *Scene) Close(db *sql.DB) {
 	// Broadcast all results to the mothership.
 	for _, i := range s.Interactions {
-		i.saveToDB(db)
+		s.saveInteraction(db, &i)
 	}
 
 	for _, i := range s.IdleInteractions {
-		i.saveToDB(db)
+		s.saveInteraction(db, &i)
 	}
 }
-
-func (s *Scene) save(filename string) {
-	b, _ := json.Marshal(s)
-	ioutil.WriteFile(filename, b, 0611)
-}
@@ -0,0 +1,321 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScene(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Scene Suite")
+}
+
+var _ = Describe("Scene", func() {
+	Context("hungarian", func() {
+		It("should find the minimum cost assignment", func() {
+			costs := [][]float64{
+				[]float64{4, 1, 3},
+				[]float64{2, 0, 5},
+				[]float64{3, 2, 2},
+			}
+
+			Ω(hungarian(costs)).Should(Equal([]int{1, 0, 2}))
+		})
+	})
+
+	Context("assign", func() {
+		It("should handle empty cost matrices", func() {
+			Ω(assign([][]float64{}, 10.0)).Should(Equal([]int{}))
+			Ω(assign([][]float64{[]float64{}, []float64{}}, 10.0)).Should(Equal([]int{-1, -1}))
+		})
+
+		It("should pair rows and columns when there are more rows than columns", func() {
+			costs := [][]float64{
+				[]float64{9, 1},
+				[]float64{1, 9},
+				[]float64{5, 5},
+			}
+
+			Ω(assign(costs, 100.0)).Should(Equal([]int{1, 0, -1}))
+		})
+
+		It("should not make pairings that cost more than the gate", func() {
+			costs := [][]float64{
+				[]float64{1, 60},
+				[]float64{60, 200},
+			}
+
+			Ω(assign(costs, 100.0)).Should(Equal([]int{0, -1}))
+			Ω(assign([][]float64{[]float64{math.Inf(1)}}, 100.0)).Should(Equal([]int{-1}))
+		})
+	})
+
+	Context("assignInteractions", func() {
+		wpA := Waypoint{100, 100, 20, 20, 0.0}
+		wpB := Waypoint{200, 100, 20, 20, 0.0}
+		t := time.Now().UTC()
+
+		It("should keep identities when two people pass close to each other", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			si.assignInteractions([]Waypoint{wpA, wpB}, t)
+
+			// Both of the new waypoints are closest to A.
+			wpAA := Waypoint{120, 100, 20, 20, 0.0}
+			wpBA := Waypoint{140, 100, 20, 20, 0.0}
+			si.assignInteractions([]Waypoint{wpBA, wpAA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(2))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+			Ω(si.Interactions[0].Equal([]Waypoint{wpA, wpAA})).Should(BeTrue())
+			Ω(si.Interactions[1].Equal([]Waypoint{wpB, wpBA})).Should(BeTrue())
+		})
+
+		It("should create an extra interaction with the greedy matcher when two people pass close to each other", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "greedy", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			si.Update(nil, []Waypoint{wpA, wpB}, t)
+
+			wpAA := Waypoint{120, 100, 20, 20, 0.0}
+			wpBA := Waypoint{140, 100, 20, 20, 0.0}
+			si.Update(nil, []Waypoint{wpBA, wpAA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(3))
+		})
+
+		It("should handle people appearing and disappearing at the same time", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			si.assignInteractions([]Waypoint{wpA, wpB}, t)
+
+			// B leaves the scene as C enters it on the other side of the frame.
+			wpAA := Waypoint{105, 100, 20, 20, 0.0}
+			wpC := Waypoint{900, 600, 20, 20, 0.0}
+			si.assignInteractions([]Waypoint{wpC, wpAA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(2))
+			Ω(si.Interactions[0].Equal([]Waypoint{wpA, wpAA})).Should(BeTrue())
+			Ω(si.Interactions[1].Equal([]Waypoint{wpC})).Should(BeTrue())
+			Ω(si.Interactions[1].SceneID).Should(Equal(2))
+
+			Ω(len(si.IdleInteractions)).Should(Equal(1))
+			Ω(si.IdleInteractions[0].Equal([]Waypoint{wpB})).Should(BeTrue())
+		})
+
+		It("should resume idle interactions", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 5.0, ResumeSqDistance: 400, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			si.assignInteractions([]Waypoint{wpA, wpB}, t)
+			si.assignInteractions([]Waypoint{wpA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(1))
+			Ω(len(si.IdleInteractions)).Should(Equal(1))
+
+			wpBA := Waypoint{205, 105, 20, 20, 0.0}
+			si.assignInteractions([]Waypoint{wpA, wpBA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(2))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+			Ω(si.Interactions[1].SceneID).Should(Equal(1))
+			Ω(si.Interactions[1].Equal([]Waypoint{wpB, wpBA})).Should(BeTrue())
+		})
+
+		It("should match with the cost supplied to the scene", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			si.SetCost(func(detected Waypoint, predicted Waypoint) float64 {
+				if detected.HalfWidthPixels != predicted.HalfWidthPixels {
+					return math.Inf(1)
+				}
+
+				return float64(detected.distanceSq(predicted))
+			})
+			si.assignInteractions([]Waypoint{wpA}, t)
+
+			// The new waypoint is close to A, but is a different size.
+			wpAA := Waypoint{105, 100, 5, 5, 0.0}
+			si.assignInteractions([]Waypoint{wpAA}, t)
+
+			Ω(len(si.Interactions)).Should(Equal(1))
+			Ω(si.Interactions[0].Equal([]Waypoint{wpAA})).Should(BeTrue())
+			Ω(len(si.IdleInteractions)).Should(Equal(1))
+		})
+	})
+
+	Context("Update", func() {
+		It("should keep identities when two people walk through each other", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+			// A walks right and B walks left, crossing between the fourth and fifth frames.
+			for k := 0; k < 8; k++ {
+				a := Waypoint{100 + k*50, 100, 20, 20, 0.0}
+				b := Waypoint{475 - k*50, 110, 20, 20, 0.0}
+				si.Update(nil, []Waypoint{b, a}, t.Add(time.Duration(k)*100*time.Millisecond))
+			}
+
+			Ω(len(si.Interactions)).Should(Equal(2))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+			for k := 0; k < 8; k++ {
+				Ω(si.Interactions[0].Path[k].XPixels).Should(Equal(475 - k*50))
+				Ω(si.Interactions[1].Path[k].XPixels).Should(Equal(100 + k*50))
+			}
+			Ω(si.Interactions[0].Duration).Should(BeNumerically("~", float32(0.7), 0.001))
+		})
+
+		It("should match greedily when the scout has no match strategy", func() {
+			s := Scout{MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, Frame: DefaultFrame}
+			si := InitScene(&s)
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+			// Without a gate the optimal matcher would refuse every match.
+			si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t)
+			si.Update(nil, []Waypoint{Waypoint{110, 100, 20, 20, 0.0}}, t.Add(100*time.Millisecond))
+
+			Ω(len(si.Interactions)).Should(Equal(1))
+			Ω(len(si.Interactions[0].Path)).Should(Equal(2))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+		})
+
+		It("should expire idle interactions using the frame times", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+			si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t)
+			si.Update(nil, []Waypoint{}, t.Add(100*time.Millisecond))
+			Ω(len(si.IdleInteractions)).Should(Equal(1))
+
+			si.Update(nil, []Waypoint{}, t.Add(500*time.Millisecond))
+			Ω(len(si.Interactions)).Should(Equal(0))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+		})
+
+		It("should time idle interactions from the fraction of a second they were last seen", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			si := InitScene(&s)
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+			si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t)
+			si.Update(nil, []Waypoint{Waypoint{100, 100, 20, 20, 0.0}}, t.Add(1500*time.Millisecond))
+			si.Update(nil, []Waypoint{}, t.Add(1700*time.Millisecond))
+
+			// Last seen 0.2 seconds ago, so it can still be resumed.
+			Ω(len(si.IdleInteractions)).Should(Equal(1))
+
+			si.resumeInteraction(Waypoint{100, 100, 20, 20, 0.0}, t.Add(1750*time.Millisecond))
+			Ω(len(si.Interactions)).Should(Equal(1))
+			Ω(len(si.Interactions[0].Path)).Should(Equal(3))
+			Ω(len(si.IdleInteractions)).Should(Equal(0))
+		})
+	})
+
+	Context("kalman", func() {
+		It("should converge on the velocity of a steadily moving object", func() {
+			k := newKalman(0.0, 100.0)
+			for i := 1; i <= 20; i++ {
+				k.update(float64(i)*10.0, 0.1, 10000.0, 100.0)
+			}
+
+			Ω(k.V).Should(BeNumerically("~", 100.0, 5.0))
+			Ω(k.X).Should(BeNumerically("~", 200.0, 5.0))
+		})
+
+		It("should extrapolate the position of a moving object", func() {
+			k := kalman{100.0, 50.0, [2][2]float64{{1.0, 0.0}, {0.0, 1.0}}}
+			p := k.predict(2.0, 0.0)
+
+			Ω(p.X).Should(BeNumerically("~", 200.0, 0.001))
+			Ω(p.V).Should(BeNumerically("~", 50.0, 0.001))
+			Ω(p.P[0][0]).Should(BeNumerically(">", k.P[0][0]))
+		})
+
+		It("should predict interactions along their path", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			i := NewInteraction(Waypoint{100, 100, 20, 20, 0.0}, 1, &s, time.Now())
+			i.Path[0].T = 1.0
+			i.motion[0].V = 100.0
+
+			p := i.predict(1.5)
+			Ω(p.XPixels).Should(Equal(150))
+			Ω(p.YPixels).Should(Equal(100))
+			Ω(p.HalfWidthPixels).Should(Equal(20))
+			Ω(p.T).Should(Equal(float32(1.5)))
+		})
+	})
+})
@@ -20,12 +20,15 @@ package models
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/MeasureTheFuture/mothership/configuration"
+	"github.com/MeasureTheFuture/scout/configuration"
 	_ "github.com/lib/pq"
 	"io/ioutil"
 	"log"
+	"strconv"
+	"time"
 )
 
 type ScoutState string
@@ -51,6 +54,129 @@ func (s ScoutState) Value() (driver.Value, error) {
 	return string(s), nil
 }
 
+// MatchStrategy is how detections are matched with interactions. The zero value matches
+// greedily, as scouts did before the strategy could be chosen.
+type MatchStrategy string
+
+const (
+	GREEDY  MatchStrategy = "greedy"  // Match each detection with the nearest interaction.
+	OPTIMAL MatchStrategy = "optimal" // Match detections and interactions with the lowest total cost.
+)
+
+func (m *MatchStrategy) Scan(value interface{}) error {
+	asBytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("Unable to deserialise MatchStrategy")
+	}
+
+	*m = MatchStrategy(string(asBytes))
+	return nil
+}
+
+func (m MatchStrategy) Value() (driver.Value, error) {
+	if m == "" {
+		return string(GREEDY), nil
+	}
+
+	return string(m), nil
+}
+
+// Valid returns true if m is one of the known strategies, or the zero value.
+func (m MatchStrategy) Valid() bool {
+	return m == "" || m == GREEDY || m == OPTIMAL
+}
+
+// Masks are the exclusion polygons of a scout, detections inside them are ignored.
+type Masks []Path
+
+func (m *Masks) Scan(value interface{}) error {
+	asBytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("Unable to deserialise Masks")
+	}
+
+	return json.Unmarshal(asBytes, m)
+}
+
+func (m Masks) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Frame is the resolution of the frames a scout captures, and the grid of buckets that each
+// frame is broken into when summarising interactions.
+type Frame struct {
+	Width    int // The width of the frames in pixels.
+	Height   int // The height of the frames in pixels.
+	WBuckets int // The number of horizontal buckets a frame is broken into.
+	HBuckets int // The number of vertical buckets a frame is broken into.
+}
+
+// DefaultFrame is the frame of a scout that hasn't been configured otherwise.
+var DefaultFrame = Frame{1280, 720, 20, 20}
+
+// BucketW returns the width of a bucket in pixels.
+func (f Frame) BucketW() int {
+	return f.Width / f.WBuckets
+}
+
+// BucketH returns the height of a bucket in pixels.
+func (f Frame) BucketH() int {
+	return f.Height / f.HBuckets
+}
+
+// Valid returns true if the frame has a size and can be broken into at least one bucket, and no
+// more buckets than pixels, each way.
+func (f Frame) Valid() bool {
+	return f.WBuckets > 0 && f.HBuckets > 0 && f.WBuckets <= f.Width && f.HBuckets <= f.Height
+}
+
+// Privacy is how the measurements of a scout are protected when they are shared.
+type Privacy struct {
+	MinVisitors     int     // Counts of fewer visitors than this are suppressed from heatmaps and summaries.
+	TimeRounding    int     // The number of minutes that the entry times of shared interactions are rounded down to.
+	Epsilon         float64 // The differential privacy spent on each published summary, zero publishes them without noise.
+	Budget          float64 // The total differential privacy that can be spent publishing the summaries of the scout.
+	TimeSensitivity float64 // The most time (seconds) that one visitor adds to a summary while noise is added.
+
+	BucketSensitivity int // The most buckets that one visitor is counted in while noise is added.
+}
+
+// DefaultPrivacy is the privacy of a scout that hasn't been configured otherwise. It suppresses
+// nothing, shares entry times rounded to 15 minutes as they are measured, and adds no noise.
+var DefaultPrivacy = Privacy{0, 15, 0.0, 0.0, 60.0, 20}
+
+// Valid returns true if the privacy doesn't suppress a negative number of visitors, rounds times
+// to a whole number of 15 minutes, and has sensitivities to calibrate any noise with.
+func (p Privacy) Valid() bool {
+	return p.MinVisitors >= 0 && p.TimeRounding > 0 && p.TimeRounding%15 == 0 &&
+		p.Epsilon >= 0.0 && p.Budget >= 0.0 && p.TimeSensitivity > 0.0 && p.BucketSensitivity > 0
+}
+
+// Clip returns the most time (seconds) and the most buckets that one interaction may add to the
+// summaries of the scout. Interactions are only clipped while noise is added, and zero leaves
+// them as they are.
+func (p Privacy) Clip() (float64, int) {
+	if p.Epsilon <= 0.0 {
+		return 0.0, 0
+	}
+
+	return p.TimeSensitivity, p.BucketSensitivity
+}
+
+// Round rounds the entry time t of an interaction down to the time rounding of the privacy.
+func (p Privacy) Round(t time.Time) time.Time {
+	if p.TimeRounding <= 0 {
+		return t
+	}
+
+	return t.Truncate(time.Duration(p.TimeRounding) * time.Minute)
+}
+
 type Scout struct {
 	UUID       string        `json:"uuid"`
 	IpAddress  string        `json:"ip_address"`
@@ -72,20 +198,44 @@ type Scout struct {
 	IdleDuration       float32
 	ResumeSqDistance   int64
 	MaxArea            float64
+	MatchStrategy      MatchStrategy
+	GateSqDistance     int64
+	ProcessNoise       float64 // The uncertainty in how interactions accelerate between frames.
+	MeasurementNoise   float64 // The uncertainty in the position of detected waypoints (pixels squared).
+	CentroidWeight     float64 // The weight of the distance between centroids when matching detections.
+	IoUWeight          float64 // The weight of the overlap between bounding boxes when matching detections.
+	SizeWeight         float64 // The weight of the change in size when matching detections.
+	Masks              Masks   // Detections with a centroid inside one of these polygons are dropped.
+	MaskCoverage       float64 // Detections with this fraction of their box inside the masks are dropped.
+	DwellSqDistance    int64   // How far (pixels squared) a visitor can move and still be dwelling.
+	DwellDuration      float32 // How long (seconds) a visitor must stay put before it counts as a dwell.
+	Frame              Frame   // The resolution of the scout and the grid its summaries use.
+	Privacy            Privacy // How the measurements of the scout are protected when shared.
 }
 
 func GetScoutByUUID(db *sql.DB, uuid string) (*Scout, error) {
 	const query = `SELECT ip_address, port, authorised, name, state, min_area,
 				   dilation_iterations, foreground_thresh, guassian_smooth,
 				   mog_history_length, mog_threshold, mog_detect_shadows,
-				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, max_area
+				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, process_noise,
+				   measurement_noise, max_area, match_strategy, gate_sq_distance,
+				   centroid_weight, iou_weight, size_weight, masks, mask_coverage, dwell_sq_distance, dwell_duration,
+				   frame_width, frame_height, w_buckets, h_buckets, min_visitors, time_rounding,
+				   epsilon, privacy_budget, time_sensitivity, bucket_sensitivity
 				   FROM scouts WHERE uuid = $1`
 	var result Scout
 	err := db.QueryRow(query, uuid).Scan(&result.IpAddress, &result.Port, &result.Authorised,
 		&result.Name, &result.State, &result.MinArea, &result.DilationIterations,
 		&result.ForegroundThresh, &result.GaussianSmooth, &result.MogHistoryLength,
 		&result.MogThreshold, &result.MogDetectShadows, &result.SimplifyEpsilon,
-		&result.MinDuration, &result.IdleDuration, &result.ResumeSqDistance, &result.MaxArea)
+		&result.MinDuration, &result.IdleDuration, &result.ResumeSqDistance, &result.ProcessNoise,
+		&result.MeasurementNoise, &result.MaxArea,
+		&result.MatchStrategy, &result.GateSqDistance, &result.CentroidWeight,
+		&result.IoUWeight, &result.SizeWeight, &result.Masks, &result.MaskCoverage,
+		&result.DwellSqDistance, &result.DwellDuration, &result.Frame.Width, &result.Frame.Height,
+		&result.Frame.WBuckets, &result.Frame.HBuckets, &result.Privacy.MinVisitors,
+		&result.Privacy.TimeRounding, &result.Privacy.Epsilon, &result.Privacy.Budget,
+		&result.Privacy.TimeSensitivity, &result.Privacy.BucketSensitivity)
 	result.UUID = uuid
 	result.Summary, err = GetScoutSummaryByUUID(db, result.UUID)
 	if err != nil {
@@ -99,14 +249,25 @@ func GetScout(db *sql.DB) *Scout {
 	const query = `SELECT uuid, ip_address, port, authorised, name, state, min_area,
 				   dilation_iterations, foreground_thresh, guassian_smooth,
 				   mog_history_length, mog_threshold, mog_detect_shadows,
-				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, max_area
+				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, process_noise,
+				   measurement_noise, max_area, match_strategy, gate_sq_distance,
+				   centroid_weight, iou_weight, size_weight, masks, mask_coverage, dwell_sq_distance, dwell_duration,
+				   frame_width, frame_height, w_buckets, h_buckets, min_visitors, time_rounding,
+				   epsilon, privacy_budget, time_sensitivity, bucket_sensitivity
 				   FROM scouts LIMIT 1`
 	var result Scout
 	err := db.QueryRow(query).Scan(&result.UUID, &result.IpAddress, &result.Port, &result.Authorised,
 		&result.Name, &result.State, &result.MinArea, &result.DilationIterations,
 		&result.ForegroundThresh, &result.GaussianSmooth, &result.MogHistoryLength,
 		&result.MogThreshold, &result.MogDetectShadows, &result.SimplifyEpsilon,
-		&result.MinDuration, &result.IdleDuration, &result.ResumeSqDistance, &result.MaxArea)
+		&result.MinDuration, &result.IdleDuration, &result.ResumeSqDistance, &result.ProcessNoise,
+		&result.MeasurementNoise, &result.MaxArea,
+		&result.MatchStrategy, &result.GateSqDistance, &result.CentroidWeight,
+		&result.IoUWeight, &result.SizeWeight, &result.Masks, &result.MaskCoverage,
+		&result.DwellSqDistance, &result.DwellDuration, &result.Frame.Width, &result.Frame.Height,
+		&result.Frame.WBuckets, &result.Frame.HBuckets, &result.Privacy.MinVisitors,
+		&result.Privacy.TimeRounding, &result.Privacy.Epsilon, &result.Privacy.Budget,
+		&result.Privacy.TimeSensitivity, &result.Privacy.BucketSensitivity)
 	if err != nil {
 		log.Fatalf("Unable to get scout %v", err)
 	}
@@ -130,7 +291,11 @@ func GetAllScouts(db *sql.DB) ([]*Scout, error) {
 				   dilation_iterations, foreground_thresh, guassian_smooth,
 				   mog_history_length, mog_threshold, mog_detect_shadows,
 				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance,
-				   max_area FROM scouts`
+				   process_noise, measurement_noise, max_area, match_strategy, gate_sq_distance,
+				   centroid_weight, iou_weight, size_weight, masks, mask_coverage, dwell_sq_distance, dwell_duration,
+				   frame_width, frame_height, w_buckets, h_buckets, min_visitors, time_rounding,
+				   epsilon, privacy_budget, time_sensitivity, bucket_sensitivity
+				   FROM scouts`
 
 	var result []*Scout
 	rows, err := db.Query(query)
@@ -147,7 +312,13 @@ func GetAllScouts(db *sql.DB) ([]*Scout, error) {
 			&s.MinArea, &s.DilationIterations, &s.ForegroundThresh,
 			&s.GaussianSmooth, &s.MogHistoryLength, &s.MogThreshold,
 			&s.MogDetectShadows, &s.SimplifyEpsilon, &s.MinDuration,
-			&s.IdleDuration, &s.ResumeSqDistance, &s.MaxArea)
+			&s.IdleDuration, &s.ResumeSqDistance, &s.ProcessNoise, &s.MeasurementNoise,
+			&s.MaxArea, &s.MatchStrategy, &s.GateSqDistance, &s.CentroidWeight,
+			&s.IoUWeight, &s.SizeWeight, &s.Masks, &s.MaskCoverage,
+			&s.DwellSqDistance, &s.DwellDuration, &s.Frame.Width, &s.Frame.Height,
+			&s.Frame.WBuckets, &s.Frame.HBuckets, &s.Privacy.MinVisitors, &s.Privacy.TimeRounding,
+			&s.Privacy.Epsilon, &s.Privacy.Budget, &s.Privacy.TimeSensitivity,
+			&s.Privacy.BucketSensitivity)
 		if err != nil {
 			return result, err
 		}
@@ -194,47 +365,134 @@ func (s *Scout) Insert(db *sql.DB) error {
 	const query = `INSERT INTO scouts (ip_address, port, authorised, name, state, min_area,
 				   dilation_iterations, foreground_thresh, guassian_smooth,
 				   mog_history_length, mog_threshold, mog_detect_shadows,
-				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, max_area)
-				   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) RETURNING uuid`
+				   simplify_epsilon, min_duration, idle_duration, resume_sq_distance, process_noise,
+				   measurement_noise, max_area, match_strategy, gate_sq_distance,
+				   centroid_weight, iou_weight, size_weight, masks, mask_coverage,
+				   dwell_sq_distance, dwell_duration, frame_width, frame_height, w_buckets, h_buckets,
+				   min_visitors, time_rounding, epsilon, privacy_budget, time_sensitivity,
+				   bucket_sensitivity)
+				   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+				   $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33,
+				   $34, $35, $36, $37, $38) RETURNING uuid`
 	err := db.QueryRow(query, s.IpAddress, s.Port, s.Authorised, s.Name, s.State,
 		s.MinArea, s.DilationIterations, s.ForegroundThresh,
 		s.GaussianSmooth, s.MogHistoryLength, s.MogThreshold,
 		s.MogDetectShadows, s.SimplifyEpsilon, s.MinDuration,
-		s.IdleDuration, s.ResumeSqDistance, s.MaxArea).Scan(&s.UUID)
+		s.IdleDuration, s.ResumeSqDistance, s.ProcessNoise, s.MeasurementNoise,
+		s.MaxArea, s.MatchStrategy, s.GateSqDistance, s.CentroidWeight, s.IoUWeight,
+		s.SizeWeight, s.Masks, s.MaskCoverage, s.DwellSqDistance, s.DwellDuration, s.Frame.Width,
+		s.Frame.Height, s.Frame.WBuckets, s.Frame.HBuckets, s.Privacy.MinVisitors,
+		s.Privacy.TimeRounding, s.Privacy.Epsilon, s.Privacy.Budget,
+		s.Privacy.TimeSensitivity, s.Privacy.BucketSensitivity).Scan(&s.UUID)
 	if err != nil {
 		return err
 	}
 
 	// Create matching empty summary.
-	s.Summary = &ScoutSummary{s.UUID, 0, Buckets{}, IntBuckets{}}
+	s.Summary = NewScoutSummary(s.UUID, s.Frame)
 	return s.Summary.Insert(db)
 }
 
+// Update stores the settings of the scout. The grid of the scout is left as it is, as the
+// summaries would no longer match it, use UpdateScoutGrid while rebuilding the summaries.
 func (s *Scout) Update(db *sql.DB) error {
 	const query = `UPDATE scouts SET ip_address = $1, port = $2, authorised = $3, name = $4,
 				   state = $5, min_area = $6, dilation_iterations = $7,
 				   foreground_thresh = $8, guassian_smooth = $9, mog_history_length = $10,
 				   mog_threshold = $11, mog_detect_shadows = $12, simplify_epsilon = $13,
 				   min_duration = $14, idle_duration = $15, resume_sq_distance = $16,
-				   max_area = $17 WHERE uuid = $18`
+				   max_area = $17, match_strategy = $18, gate_sq_distance = $19,
+				   process_noise = $20, measurement_noise = $21, centroid_weight = $22,
+				   iou_weight = $23, size_weight = $24, masks = $25, mask_coverage = $26,
+				   dwell_sq_distance = $27, dwell_duration = $28, frame_width = $29,
+				   frame_height = $30, min_visitors = $31, time_rounding = $32, epsilon = $33,
+				   privacy_budget = $34, time_sensitivity = $35, bucket_sensitivity = $36
+				   WHERE uuid = $37`
 	_, err := db.Exec(query, s.IpAddress, s.Port, s.Authorised, s.Name, s.State,
 		s.MinArea, s.DilationIterations, s.ForegroundThresh,
 		s.GaussianSmooth, s.MogHistoryLength, s.MogThreshold,
 		s.MogDetectShadows, s.SimplifyEpsilon, s.MinDuration,
-		s.IdleDuration, s.ResumeSqDistance, s.MaxArea, s.UUID)
+		s.IdleDuration, s.ResumeSqDistance, s.MaxArea, s.MatchStrategy,
+		s.GateSqDistance, s.ProcessNoise, s.MeasurementNoise, s.CentroidWeight,
+		s.IoUWeight, s.SizeWeight, s.Masks, s.MaskCoverage, s.DwellSqDistance, s.DwellDuration, s.Frame.Width,
+		s.Frame.Height, s.Privacy.MinVisitors, s.Privacy.TimeRounding, s.Privacy.Epsilon,
+		s.Privacy.Budget, s.Privacy.TimeSensitivity, s.Privacy.BucketSensitivity, s.UUID)
 	return err
 }
 
-func ScoutsAsJSON(db *sql.DB) ([]string, error) {
+// GetScoutFrame returns the frame of the scout with the supplied UUID.
+func GetScoutFrame(db Queryer, uuid string) (Frame, error) {
+	const query = `SELECT frame_width, frame_height, w_buckets, h_buckets FROM scouts WHERE uuid = $1`
+
+	var result Frame
+	err := db.QueryRow(query, uuid).Scan(&result.Width, &result.Height, &result.WBuckets, &result.HBuckets)
+	return result, err
+}
+
+// UpdateScoutGrid changes the number of buckets the frames of a scout are broken into.
+func UpdateScoutGrid(db Queryer, uuid string, wBuckets int, hBuckets int) error {
+	const query = `UPDATE scouts SET w_buckets = $1, h_buckets = $2 WHERE uuid = $3`
+	_, err := db.Exec(query, wBuckets, hBuckets, uuid)
+	return err
+}
+
+// GetScoutPrivacy returns the privacy of the scout with the supplied UUID.
+func GetScoutPrivacy(db Queryer, uuid string) (Privacy, error) {
+	const query = `SELECT min_visitors, time_rounding, epsilon, privacy_budget, time_sensitivity,
+				   bucket_sensitivity FROM scouts WHERE uuid = $1`
+
+	var result Privacy
+	err := db.QueryRow(query, uuid).Scan(&result.MinVisitors, &result.TimeRounding, &result.Epsilon,
+		&result.Budget, &result.TimeSensitivity, &result.BucketSensitivity)
+	return result, err
+}
+
+// UpdateScoutPrivacy changes the privacy of the scout with the supplied UUID.
+func UpdateScoutPrivacy(db Queryer, uuid string, p Privacy) error {
+	const query = `UPDATE scouts SET min_visitors = $1, time_rounding = $2, epsilon = $3,
+				   privacy_budget = $4, time_sensitivity = $5, bucket_sensitivity = $6 WHERE uuid = $7`
+	_, err := db.Exec(query, p.MinVisitors, p.TimeRounding, p.Epsilon, p.Budget, p.TimeSensitivity,
+		p.BucketSensitivity, uuid)
+	return err
+}
+
+// GetScoutPrivacies returns the privacy of every scout, keyed by UUID.
+func GetScoutPrivacies(db *sql.DB) (map[string]Privacy, error) {
+	const query = `SELECT uuid, min_visitors, time_rounding, epsilon, privacy_budget, time_sensitivity,
+				   bucket_sensitivity FROM scouts`
+
+	result := map[string]Privacy{}
+	rows, err := db.Query(query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uuid string
+		var p Privacy
+		err = rows.Scan(&uuid, &p.MinVisitors, &p.TimeRounding, &p.Epsilon, &p.Budget, &p.TimeSensitivity,
+			&p.BucketSensitivity)
+		if err != nil {
+			return result, err
+		}
+
+		result[uuid] = p
+	}
+
+	return result, rows.Err()
+}
+
+// exportScouts returns every scout for the data download, writing the calibration frame of each
+// scout that has one alongside the download. The files of the calibration frames are returned
+// with the scouts.
+func exportScouts(db *sql.DB) ([]Scout, []string, error) {
 	var files []string
-	file := configuration.GetDataDir() + "/scouts.json"
 
 	const query = `SELECT * FROM scouts`
 	rows, err := db.Query(query)
-	if err == sql.ErrNoRows {
-		return files, nil
-	} else if err != nil {
-		return files, err
+	if err != nil {
+		return nil, files, err
 	}
 	defer rows.Close()
 
@@ -246,9 +504,15 @@ func ScoutsAsJSON(db *sql.DB) ([]string, error) {
 		err = rows.Scan(&s.UUID, &s.IpAddress, &s.Authorised, &image, &s.Name, &s.State, &s.Port,
 			&s.MinArea, &s.DilationIterations, &s.ForegroundThresh, &s.GaussianSmooth,
 			&s.MogHistoryLength, &s.MogThreshold, &s.MogDetectShadows, &s.SimplifyEpsilon,
-			&s.MinDuration, &s.IdleDuration, &s.ResumeSqDistance, &s.MaxArea)
+			&s.MinDuration, &s.IdleDuration, &s.ResumeSqDistance, &s.MaxArea,
+			&s.MatchStrategy, &s.GateSqDistance, &s.ProcessNoise, &s.MeasurementNoise,
+			&s.CentroidWeight, &s.IoUWeight, &s.SizeWeight, &s.Masks, &s.MaskCoverage,
+			&s.DwellSqDistance, &s.DwellDuration, &s.Frame.Width, &s.Frame.Height,
+			&s.Frame.WBuckets, &s.Frame.HBuckets, &s.Privacy.MinVisitors, &s.Privacy.TimeRounding,
+			&s.Privacy.Epsilon, &s.Privacy.Budget, &s.Privacy.TimeSensitivity,
+			&s.Privacy.BucketSensitivity)
 		if err != nil {
-			return files, err
+			return nil, files, err
 		}
 
 		// Write image.
@@ -256,7 +520,7 @@ func ScoutsAsJSON(db *sql.DB) ([]string, error) {
 			imgF := configuration.GetDataDir() + "/scout-" + fmt.Sprintf("%s", s.UUID) + ".jpg"
 			err = ioutil.WriteFile(imgF, image, 0644)
 			if err != nil {
-				return files, err
+				return nil, files, err
 			}
 
 			files = append(files, imgF)
@@ -265,6 +529,16 @@ func ScoutsAsJSON(db *sql.DB) ([]string, error) {
 		result = append(result, s)
 	}
 
+	return result, files, rows.Err()
+}
+
+func ScoutsAsJSON(db *sql.DB) ([]string, error) {
+	file := configuration.GetDataDir() + "/scouts.json"
+	result, files, err := exportScouts(db)
+	if err != nil {
+		return files, err
+	}
+
 	err = configuration.SaveAsJSON(result, file)
 	if err != nil {
 		return files, err
@@ -272,3 +546,50 @@ func ScoutsAsJSON(db *sql.DB) ([]string, error) {
 
 	return append(files, file), nil
 }
+
+// ScoutsAsCSV exports every scout, one row per scout, along with the calibration frames of the
+// scouts. The masks of each scout are written as a single JSON field.
+func ScoutsAsCSV(db *sql.DB) ([]string, error) {
+	file := configuration.GetDataDir() + "/scouts.csv"
+	scouts, files, err := exportScouts(db)
+	if err != nil {
+		return files, err
+	}
+
+	records := [][]string{{"UUID", "IpAddress", "Port", "Authorised", "Name", "State", "MinArea",
+		"DilationIterations", "ForegroundThresh", "GaussianSmooth", "MogHistoryLength", "MogThreshold",
+		"MogDetectShadows", "SimplifyEpsilon", "MinDuration", "IdleDuration", "ResumeSqDistance",
+		"MaxArea", "MatchStrategy", "GateSqDistance", "ProcessNoise", "MeasurementNoise",
+		"CentroidWeight", "IoUWeight", "SizeWeight", "Masks", "MaskCoverage", "DwellSqDistance",
+		"DwellDuration", "FrameWidth", "FrameHeight", "FrameWBuckets", "FrameHBuckets",
+		"MinVisitors", "TimeRounding", "Epsilon", "PrivacyBudget", "TimeSensitivity",
+		"BucketSensitivity"}}
+	for _, s := range scouts {
+		masks, err := json.Marshal(s.Masks)
+		if err != nil {
+			return files, err
+		}
+
+		records = append(records, []string{s.UUID, s.IpAddress, formatInt(s.Port),
+			strconv.FormatBool(s.Authorised), s.Name, string(s.State), formatFloat(s.MinArea),
+			formatInt(s.DilationIterations), formatInt(s.ForegroundThresh), formatInt(s.GaussianSmooth),
+			formatInt(s.MogHistoryLength), formatFloat(s.MogThreshold), formatInt(s.MogDetectShadows),
+			formatFloat(s.SimplifyEpsilon), formatFloat32(s.MinDuration), formatFloat32(s.IdleDuration),
+			formatInt(s.ResumeSqDistance), formatFloat(s.MaxArea), string(s.MatchStrategy),
+			formatInt(s.GateSqDistance), formatFloat(s.ProcessNoise), formatFloat(s.MeasurementNoise),
+			formatFloat(s.CentroidWeight), formatFloat(s.IoUWeight), formatFloat(s.SizeWeight),
+			string(masks), formatFloat(s.MaskCoverage), formatInt(s.DwellSqDistance),
+			formatFloat32(s.DwellDuration), strconv.Itoa(s.Frame.Width), strconv.Itoa(s.Frame.Height),
+			strconv.Itoa(s.Frame.WBuckets), strconv.Itoa(s.Frame.HBuckets),
+			strconv.Itoa(s.Privacy.MinVisitors), strconv.Itoa(s.Privacy.TimeRounding),
+			formatFloat(s.Privacy.Epsilon), formatFloat(s.Privacy.Budget),
+			formatFloat(s.Privacy.TimeSensitivity), strconv.Itoa(s.Privacy.BucketSensitivity)})
+	}
+
+	err = configuration.SaveAsCSV(records, file)
+	if err != nil {
+		return files, err
+	}
+
+	return append(files, file), nil
+}
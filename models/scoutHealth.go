@@ -60,6 +60,18 @@ func DeleteScoutHealths(db *sql.DB, scoutUUID string) error {
 	return err
 }
 
+// PruneScoutHealths deletes the health heartbeats of every scout that were created before the
+// time before, returning the number deleted.
+func PruneScoutHealths(db Queryer, before time.Time) (int64, error) {
+	const query = `DELETE FROM scout_healths WHERE created_at < $1`
+	r, err := db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return r.RowsAffected()
+}
+
 func NumScoutHealths(db *sql.DB) (int64, error) {
 	const query = `SELECT COUNT(*) FROM scout_healths`
 	var result int64
@@ -75,14 +87,12 @@ func (s *ScoutHealth) Insert(db *sql.DB) error {
 	return err
 }
 
-func ScoutHealthsAsJSON(db *sql.DB) (string, error) {
-	file := configuration.GetDataDir() + "/scout_healths.json"
+// exportScoutHealths returns the health heartbeats of every scout for the data download.
+func exportScoutHealths(db *sql.DB) ([]ScoutHealth, error) {
 	const query = `SELECT scout_uuid, cpu, memory, total_memory, storage, created_at FROM scout_healths`
 	rows, err := db.Query(query)
-	if err == sql.ErrNoRows {
-		return file, nil
-	} else if err != nil {
-		return file, err
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -91,12 +101,40 @@ func ScoutHealthsAsJSON(db *sql.DB) (string, error) {
 		var sh ScoutHealth
 		err = rows.Scan(&sh.ScoutUUID, &sh.CPU, &sh.Memory, &sh.TotalMemory, &sh.Storage, &sh.CreatedAt)
 		if err != nil {
-			return file, err
+			return nil, err
 		}
 
 		result = append(result, sh)
 	}
 
+	return result, rows.Err()
+}
+
+func ScoutHealthsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_healths.json"
+	result, err := exportScoutHealths(db)
+	if err != nil {
+		return file, err
+	}
+
 	err = configuration.SaveAsJSON(result, file)
 	return file, err
 }
+
+// ScoutHealthsAsCSV exports the health heartbeats of every scout, one row per heartbeat.
+func ScoutHealthsAsCSV(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/scout_healths.csv"
+	healths, err := exportScoutHealths(db)
+	if err != nil {
+		return file, err
+	}
+
+	records := [][]string{{"ScoutUUID", "CPU", "Memory", "TotalMemory", "Storage", "CreatedAt"}}
+	for _, sh := range healths {
+		records = append(records, []string{sh.ScoutUUID, formatFloat32(sh.CPU), formatFloat32(sh.Memory),
+			formatFloat32(sh.TotalMemory), formatFloat32(sh.Storage), formatTime(sh.CreatedAt)})
+	}
+
+	err = configuration.SaveAsCSV(records, file)
+	return file, err
+}
@@ -18,11 +18,13 @@
 package models
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	_ "github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 )
@@ -37,8 +39,13 @@ var _ = Describe("Scout Health Model", func() {
 
 	Context("Insert", func() {
 		It("should insert a valid scouthealth into the DB.", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -62,8 +69,13 @@ var _ = Describe("Scout Health Model", func() {
 
 	Context("Delete", func() {
 		It("should be able to delete healths for a specified scout", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -84,8 +96,13 @@ var _ = Describe("Scout Health Model", func() {
 
 	Context("Get", func() {
 		It("should be able to get scout healths as json", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "idle", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -104,5 +121,35 @@ var _ = Describe("Scout Health Model", func() {
 			Ω(err).Should(BeNil())
 			Ω(result).Should(Equal([]ScoutHealth{sh}))
 		})
+
+		It("should be able to get scout healths as csv", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 11, 45, 0, 0, time.UTC)
+			sh := ScoutHealth{s.UUID, 0.1, 0.2, 0.3, 0.4, t}
+			err = sh.Insert(db)
+			Ω(err).Should(BeNil())
+
+			csvF, err := ScoutHealthsAsCSV(db)
+			Ω(err).Should(BeNil())
+
+			f, err := os.Open(csvF)
+			Ω(err).Should(BeNil())
+			defer f.Close()
+
+			result, err := csv.NewReader(f).ReadAll()
+			Ω(err).Should(BeNil())
+			Ω(result).Should(Equal([][]string{
+				{"ScoutUUID", "CPU", "Memory", "TotalMemory", "Storage", "CreatedAt"},
+				{s.UUID, "0.1", "0.2", "0.3", "0.4", "2016-05-12T11:45:00Z"}}))
+		})
 	})
 })
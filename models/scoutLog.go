@@ -50,6 +50,18 @@ func GetLastScoutLog(db *sql.DB, scoutUUID string) (*ScoutLog, error) {
 	return &result, err
 }
 
+// PruneScoutLogs deletes the logs of every scout that were created before the time before,
+// returning the number deleted.
+func PruneScoutLogs(db Queryer, before time.Time) (int64, error) {
+	const query = `DELETE FROM scout_logs WHERE created_at < $1`
+	r, err := db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return r.RowsAffected()
+}
+
 func NumScoutLogs(db *sql.DB) (int64, error) {
 	const query = `SELECT COUNT(*) FROM scout_logs`
 	var result int64
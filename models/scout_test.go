@@ -19,12 +19,14 @@ package models
 
 import (
 	"database/sql"
+	"encoding/csv"
 	"github.com/MeasureTheFuture/scout/configuration"
 	_ "github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestScout(t *testing.T) {
@@ -44,12 +46,27 @@ func cleaner() {
 	_, err = db.Exec(`DELETE FROM scout_interactions`)
 	Ω(err).Should(BeNil())
 
+	_, err = db.Exec(`DELETE FROM prunes`)
+	Ω(err).Should(BeNil())
+
 	_, err = db.Exec(`DELETE FROM scout_logs`)
 	Ω(err).Should(BeNil())
 
 	_, err = db.Exec(`DELETE FROM scout_healths`)
 	Ω(err).Should(BeNil())
 
+	_, err = db.Exec(`DELETE FROM tripwire_counts`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM tripwires`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zone_visits`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zones`)
+	Ω(err).Should(BeNil())
+
 	_, err = db.Exec(`DELETE FROM scouts`)
 	Ω(err).Should(BeNil())
 }
@@ -68,8 +85,13 @@ var _ = Describe("Scout Model", func() {
 
 	Context("Insert", func() {
 		It("should insert a valid scout into the DB.", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "calibrated", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "calibrated", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -79,8 +101,14 @@ var _ = Describe("Scout Model", func() {
 		})
 
 		It("should return an error when an invalid scout is inserted into the DB.", func() {
-			s := Scout{"aa", "192.168.0.1", 8080, true, "foo", "calibratingas", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{UUID: "aa", IpAddress: "192.168.0.1", Port: 8080, Authorised: true,
+				Name: "foo", State: "calibratingas", Summary: &ScoutSummary{}, MinArea: 2.0,
+				DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+				MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3,
+				ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000,
+				ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: Masks{},
+				MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame,
+				Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).ShouldNot(BeNil())
 			Ω(s.UUID).Should(Equal("aa"))
@@ -93,13 +121,23 @@ var _ = Describe("Scout Model", func() {
 			Ω(err).Should(BeNil())
 			Ω(len(al)).Should(Equal(0))
 
-			s1 := Scout{"", "192.168.0.1", 8080, true, "foo", "calibrated", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s1 := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "calibrated", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err = s1.Insert(db)
 			Ω(err).Should(BeNil())
 
-			s2 := Scout{"", "192.168.0.2", 8080, true, "foo", "calibrated", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s2 := Scout{IpAddress: "192.168.0.2", Port: 8080, Authorised: true, Name: "foo",
+				State: "calibrated", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err = s2.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -108,21 +146,105 @@ var _ = Describe("Scout Model", func() {
 			Ω(len(al)).Should(Equal(2))
 			Ω(al).Should(Equal([]*Scout{&s1, &s2}))
 		})
+
+		It("should be able to get scouts as csv", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo, bar",
+				State: "calibrated", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{Path{{0, 0}, {10, 0}, {10, 10}}}, MaskCoverage: 0.5,
+				DwellSqDistance: 400, DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			files, err := ScoutsAsCSV(db)
+			Ω(err).Should(BeNil())
+			Ω(len(files)).Should(Equal(1))
+
+			f, err := os.Open(files[0])
+			Ω(err).Should(BeNil())
+			defer f.Close()
+
+			result, err := csv.NewReader(f).ReadAll()
+			Ω(err).Should(BeNil())
+			Ω(len(result)).Should(Equal(2))
+			Ω(len(result[1])).Should(Equal(len(result[0])))
+			Ω(result[1][0:6]).Should(Equal([]string{s.UUID, "192.168.0.1", "8080", "true", "foo, bar", "calibrated"}))
+			Ω(result[1][25]).Should(Equal("[[[0,0],[10,0],[10,10]]]"))
+			Ω(result[1][len(result[1])-5:]).Should(Equal([]string{"0", "15", "0", "0", "60"}))
+		})
 	})
 
 	Context("Update", func() {
 		It("should be able to update a scout in the DB", func() {
-			s := Scout{"", "192.168.0.1", 8080, true, "foo", "measuring", &ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "measuring", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
 			s.IpAddress = "192.168.0.2"
+			s.Privacy = Privacy{5, 60, 0.5, 5.0, 120.0, 20}
 			err = s.Update(db)
 			Ω(err).Should(BeNil())
 			s2, err := GetScoutByUUID(db, s.UUID)
 			Ω(err).Should(BeNil())
 			Ω(&s).Should(Equal(s2))
+
+			p, err := GetScoutPrivacies(db)
+			Ω(err).Should(BeNil())
+			Ω(p).Should(Equal(map[string]Privacy{s.UUID: Privacy{5, 60, 0.5, 5.0, 120.0, 20}}))
+		})
+	})
+
+	Context("MatchStrategy", func() {
+		It("should treat the zero value as the greedy strategy", func() {
+			var m MatchStrategy
+			Ω(m.Valid()).Should(BeTrue())
+			Ω(m.Value()).Should(Equal("greedy"))
+			Ω(OPTIMAL.Value()).Should(Equal("optimal"))
+		})
+
+		It("should only accept known strategies", func() {
+			Ω(GREEDY.Valid()).Should(BeTrue())
+			Ω(OPTIMAL.Valid()).Should(BeTrue())
+			Ω(MatchStrategy("nearest").Valid()).Should(BeFalse())
+		})
+	})
+
+	Context("Privacy", func() {
+		It("should only round times to multiples of 15 minutes", func() {
+			Ω(DefaultPrivacy.Valid()).Should(BeTrue())
+			Ω(Privacy{10, 1440, 0.5, 5.0, 120.0, 20}.Valid()).Should(BeTrue())
+			Ω(Privacy{-1, 15, 0.0, 0.0, 60.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 0, 0.0, 0.0, 60.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 20, 0.0, 0.0, 60.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 15, -1.0, 0.0, 60.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 15, 1.0, -1.0, 60.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 15, 1.0, 1.0, 0.0, 20}.Valid()).Should(BeFalse())
+			Ω(Privacy{0, 15, 1.0, 1.0, 60.0, 0}.Valid()).Should(BeFalse())
+		})
+
+		It("should round times down", func() {
+			t := time.Date(2016, 5, 12, 10, 45, 0, 0, time.UTC)
+			Ω(DefaultPrivacy.Round(t)).Should(Equal(t))
+			Ω(Privacy{0, 60, 0.0, 0.0, 60.0, 20}.Round(t)).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+			Ω(Privacy{0, 1440, 0.0, 0.0, 60.0, 20}.Round(t)).Should(Equal(time.Date(2016, 5, 12, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("should only clip interactions while noise is added", func() {
+			maxT, maxB := DefaultPrivacy.Clip()
+			Ω(maxT).Should(Equal(0.0))
+			Ω(maxB).Should(Equal(0))
+
+			maxT, maxB = Privacy{0, 15, 0.5, 5.0, 120.0, 10}.Clip()
+			Ω(maxT).Should(Equal(120.0))
+			Ω(maxB).Should(Equal(10))
 		})
 	})
 })
@@ -0,0 +1,238 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"time"
+)
+
+// Tripwire is a named line segment drawn on the calibration frame of a scout (for example
+// across a door), counting the interactions that cross it in each direction. Crossings from
+// the left of the segment (as drawn from its first point to its second) to the right are 'in',
+// crossings the other way are 'out'.
+type Tripwire struct {
+	Id        int64  `json:"id"`
+	ScoutUUID string `json:"scout_uuid"`
+	Name      string `json:"name"`
+	Segment   Path   `json:"segment"`   // The two ends of the tripwire in pixels.
+	InCount   int64  `json:"in_count"`  // The total number of crossings in.
+	OutCount  int64  `json:"out_count"` // The total number of crossings out.
+}
+
+// TripwireCount is the number of crossings of a tripwire within an hour.
+type TripwireCount struct {
+	TripwireId int64     `json:"tripwire_id"`
+	Hour       time.Time `json:"hour"` // The start of the hour.
+	InCount    int64     `json:"in_count"`
+	OutCount   int64     `json:"out_count"`
+}
+
+func GetTripwireById(db *sql.DB, id int64) (*Tripwire, error) {
+	const query = `SELECT scout_uuid, name, segment, in_count, out_count FROM tripwires WHERE id = $1`
+
+	var result Tripwire
+	err := db.QueryRow(query, id).Scan(&result.ScoutUUID, &result.Name, &result.Segment,
+		&result.InCount, &result.OutCount)
+	result.Id = id
+
+	return &result, err
+}
+
+func GetTripwires(db Queryer, scoutUUID string) ([]*Tripwire, error) {
+	const query = `SELECT id, name, segment, in_count, out_count FROM tripwires
+				   WHERE scout_uuid = $1 ORDER BY id`
+
+	result := []*Tripwire{}
+	rows, err := db.Query(query, scoutUUID)
+	if err == sql.ErrNoRows {
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Tripwire
+		t.ScoutUUID = scoutUUID
+		err = rows.Scan(&t.Id, &t.Name, &t.Segment, &t.InCount, &t.OutCount)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, &t)
+	}
+
+	return result, rows.Err()
+}
+
+func (t *Tripwire) Insert(db *sql.DB) error {
+	const query = `INSERT INTO tripwires (scout_uuid, name, segment, in_count, out_count)
+				   VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return db.QueryRow(query, t.ScoutUUID, t.Name, t.Segment, t.InCount, t.OutCount).Scan(&t.Id)
+}
+
+func (t *Tripwire) Update(db Queryer) error {
+	const query = `UPDATE tripwires SET name = $1, segment = $2, in_count = $3, out_count = $4
+				   WHERE id = $5`
+	_, err := db.Exec(query, t.Name, t.Segment, t.InCount, t.OutCount, t.Id)
+	return err
+}
+
+func (t *Tripwire) Delete(db *sql.DB) error {
+	const query = `DELETE FROM tripwires WHERE id = $1`
+	_, err := db.Exec(query, t.Id)
+	return err
+}
+
+// AddCrossing counts a crossing of the tripwire at time at, in the direction given by in.
+func (t *Tripwire) AddCrossing(db Queryer, at time.Time, in bool) error {
+	const query = `INSERT INTO tripwire_counts (tripwire_id, hour, in_count, out_count)
+				   VALUES ($1, $2, $3, $4) ON CONFLICT (tripwire_id, hour) DO UPDATE SET
+				   in_count = tripwire_counts.in_count + EXCLUDED.in_count,
+				   out_count = tripwire_counts.out_count + EXCLUDED.out_count`
+
+	var i, o int64
+	if in {
+		i = 1
+	} else {
+		o = 1
+	}
+
+	_, err := db.Exec(query, t.Id, at.UTC().Truncate(time.Hour), i, o)
+	if err != nil {
+		return err
+	}
+
+	// Add to the totals in place, so that crossings counted elsewhere at the same time are kept.
+	const total = `UPDATE tripwires SET in_count = in_count + $1, out_count = out_count + $2
+				   WHERE id = $3 RETURNING in_count, out_count`
+	return db.QueryRow(total, i, o, t.Id).Scan(&t.InCount, &t.OutCount)
+}
+
+func GetTripwireCounts(db *sql.DB, tripwireId int64) ([]*TripwireCount, error) {
+	const query = `SELECT hour, in_count, out_count FROM tripwire_counts
+				   WHERE tripwire_id = $1 ORDER BY hour`
+
+	result := []*TripwireCount{}
+	rows, err := db.Query(query, tripwireId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc TripwireCount
+		tc.TripwireId = tripwireId
+		err = rows.Scan(&tc.Hour, &tc.InCount, &tc.OutCount)
+		if err != nil {
+			return result, err
+		}
+		tc.Hour = tc.Hour.UTC()
+
+		result = append(result, &tc)
+	}
+
+	return result, rows.Err()
+}
+
+// ClearTripwires removes all the crossings counted by the tripwires of a scout, keeping the
+// tripwires themselves.
+func ClearTripwires(db *sql.DB, scoutUUID string) error {
+	const deleteCounts = `DELETE FROM tripwire_counts WHERE tripwire_id IN
+						  (SELECT id FROM tripwires WHERE scout_uuid = $1)`
+	_, err := db.Exec(deleteCounts, scoutUUID)
+	if err != nil {
+		return err
+	}
+
+	const resetTripwires = `UPDATE tripwires SET in_count = 0, out_count = 0 WHERE scout_uuid = $1`
+	_, err = db.Exec(resetTripwires, scoutUUID)
+	return err
+}
+
+// ClearTripwireCounts removes the crossings of the tripwires of a scout counted in each hour
+// starting between from and to, taking them off the totals of each tripwire.
+func ClearTripwireCounts(db Queryer, scoutUUID string, from time.Time, to time.Time) error {
+	const deleteCounts = `DELETE FROM tripwire_counts WHERE tripwire_id IN
+						  (SELECT id FROM tripwires WHERE scout_uuid = $1) AND hour >= $2 AND hour < $3`
+	_, err := db.Exec(deleteCounts, scoutUUID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+
+	const recountTripwires = `UPDATE tripwires SET
+							  in_count = (SELECT COALESCE(SUM(in_count), 0) FROM tripwire_counts WHERE tripwire_id = tripwires.id),
+							  out_count = (SELECT COALESCE(SUM(out_count), 0) FROM tripwire_counts WHERE tripwire_id = tripwires.id)
+							  WHERE scout_uuid = $1`
+	_, err = db.Exec(recountTripwires, scoutUUID)
+	return err
+}
+
+func TripwiresAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/tripwires.json"
+
+	const query = `SELECT id, scout_uuid, name, segment, in_count, out_count FROM tripwires`
+	rows, err := db.Query(query)
+	if err == sql.ErrNoRows {
+		return file, nil
+	} else if err != nil {
+		return file, err
+	}
+	defer rows.Close()
+
+	var result []Tripwire
+	for rows.Next() {
+		var t Tripwire
+		err = rows.Scan(&t.Id, &t.ScoutUUID, &t.Name, &t.Segment, &t.InCount, &t.OutCount)
+		if err != nil {
+			return file, err
+		}
+
+		result = append(result, t)
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
+
+func TripwireCountsAsJSON(db *sql.DB) (string, error) {
+	file := configuration.GetDataDir() + "/tripwire_counts.json"
+
+	const query = `SELECT tripwire_id, hour, in_count, out_count FROM tripwire_counts`
+	rows, err := db.Query(query)
+	if err == sql.ErrNoRows {
+		return file, nil
+	} else if err != nil {
+		return file, err
+	}
+	defer rows.Close()
+
+	var result []TripwireCount
+	for rows.Next() {
+		var tc TripwireCount
+		err = rows.Scan(&tc.TripwireId, &tc.Hour, &tc.InCount, &tc.OutCount)
+		if err != nil {
+			return file, err
+		}
+		tc.Hour = tc.Hour.UTC()
+
+		result = append(result, tc)
+	}
+
+	return file, configuration.SaveAsJSON(result, file)
+}
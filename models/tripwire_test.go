@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestTripwire(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tripwire Suite")
+}
+
+var _ = Describe("Tripwire Model", func() {
+	AfterEach(cleaner)
+
+	door := Path{[2]int{100, 200}, [2]int{300, 200}}
+
+	Context("Insert", func() {
+		It("should be able to insert and get tripwires", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := Tripwire{-1, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw2, err := GetTripwireById(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(tw2).Should(Equal(&tw))
+
+			tl, err := GetTripwires(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(tl).Should(Equal([]*Tripwire{&tw}))
+		})
+	})
+
+	Context("AddCrossing", func() {
+		It("should count crossings by the hour", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := Tripwire{-1, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			Ω(tw.AddCrossing(db, t.Add(5*time.Minute), true)).Should(BeNil())
+			Ω(tw.AddCrossing(db, t.Add(25*time.Minute), true)).Should(BeNil())
+			Ω(tw.AddCrossing(db, t.Add(35*time.Minute), false)).Should(BeNil())
+			Ω(tw.AddCrossing(db, t.Add(65*time.Minute), false)).Should(BeNil())
+
+			tw2, err := GetTripwireById(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(tw2.InCount).Should(Equal(int64(2)))
+			Ω(tw2.OutCount).Should(Equal(int64(2)))
+
+			tc, err := GetTripwireCounts(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(tc).Should(Equal([]*TripwireCount{&TripwireCount{tw.Id, t, 2, 1},
+				&TripwireCount{tw.Id, t.Add(time.Hour), 0, 1}}))
+		})
+
+		It("should keep crossings counted through other copies of the tripwire", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := Tripwire{-1, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+
+			// Two summarisers, each holding the tripwire as it was before either counted.
+			other := tw
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			Ω(tw.AddCrossing(db, t, true)).Should(BeNil())
+			Ω(other.AddCrossing(db, t, true)).Should(BeNil())
+			Ω(other.InCount).Should(Equal(int64(2)))
+
+			tw2, err := GetTripwireById(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(tw2.InCount).Should(Equal(int64(2)))
+			Ω(tw2.OutCount).Should(Equal(int64(0)))
+		})
+
+		It("should be able to clear the crossings of tripwires", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := Tripwire{-1, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+			Ω(tw.AddCrossing(db, time.Now(), true)).Should(BeNil())
+
+			err = ClearTripwires(db, s.UUID)
+			Ω(err).Should(BeNil())
+
+			tw2, err := GetTripwireById(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(tw2.InCount).Should(Equal(int64(0)))
+
+			tc, err := GetTripwireCounts(db, tw.Id)
+			Ω(err).Should(BeNil())
+			Ω(len(tc)).Should(Equal(0))
+		})
+	})
+
+	Context("Get", func() {
+		It("should be able to get tripwires and their counts as json", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			tw := Tripwire{-1, s.UUID, "Front door", door, 0, 0}
+			err = tw.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			Ω(tw.AddCrossing(db, t, true)).Should(BeNil())
+
+			jsonF, err := TripwiresAsJSON(db)
+			Ω(err).Should(BeNil())
+			jsonB, err := ioutil.ReadFile(jsonF)
+			Ω(err).Should(BeNil())
+
+			var tl []Tripwire
+			err = json.Unmarshal(jsonB, &tl)
+			Ω(err).Should(BeNil())
+			Ω(tl).Should(Equal([]Tripwire{tw}))
+
+			jsonF, err = TripwireCountsAsJSON(db)
+			Ω(err).Should(BeNil())
+			jsonB, err = ioutil.ReadFile(jsonF)
+			Ω(err).Should(BeNil())
+
+			var tc []TripwireCount
+			err = json.Unmarshal(jsonB, &tc)
+			Ω(err).Should(BeNil())
+			Ω(tc).Should(Equal([]TripwireCount{TripwireCount{tw.Id, t, 1, 0}}))
+		})
+	})
+})
@@ -47,6 +47,21 @@ func (x Waypoint) perpendicularDistance(a Waypoint, b Waypoint) float64 {
 	return (math.Abs(n) / math.Sqrt(d))
 }
 
+// synchronisedDistance calculates the distance from a point (x) to where it would be at
+// time x.T if it had moved at a constant speed from a to b.
+func (x Waypoint) synchronisedDistance(a Waypoint, b Waypoint) float64 {
+	dt := float64(b.T - a.T)
+	if dt <= 0.0 {
+		return math.Sqrt(float64(x.distanceSq(a)))
+	}
+
+	r := float64(x.T-a.T) / dt
+	dx := float64(a.XPixels) + r*float64(b.XPixels-a.XPixels) - float64(x.XPixels)
+	dy := float64(a.YPixels) + r*float64(b.YPixels-a.YPixels) - float64(x.YPixels)
+
+	return math.Sqrt((dx * dx) + (dy * dy))
+}
+
 // compare returns true if two waypoints are the same, false otherwise.
 func (a Waypoint) Equal(b Waypoint) bool {
 	return a.XPixels == b.XPixels &&
@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Zone is a named polygon within the view of a scout (for example "Help desk"), along with
+// the number of visitors that have passed through it and the time they spent inside.
+type Zone struct {
+	Id            int64   `json:"id"`
+	ScoutUUID     string  `json:"scout_uuid"`
+	Name          string  `json:"name"`
+	Polygon       Path    `json:"polygon"`         // The vertices of the zone in pixels.
+	VisitorCount  int64   `json:"visitor_count"`   // The number of interactions that entered the zone.
+	DwellTime     float32 `json:"dwell_time"`      // The total number of seconds spent within the zone.
+	MeanDwellTime float32 `json:"mean_dwell_time"` // The mean number of seconds each visitor spent within the zone.
+}
+
+// ZoneVisit is a single interaction passing through a zone.
+type ZoneVisit struct {
+	ZoneId         int64     `json:"zone_id"`
+	InteractionId  int64     `json:"interaction_id"`
+	DwellTime      float32   `json:"dwell_time"`       // The number of seconds the interaction spent within the zone.
+	FirstEnteredAt time.Time `json:"first_entered_at"` // When the interaction first entered the zone.
+	LastEnteredAt  time.Time `json:"last_entered_at"`  // When the interaction last entered the zone.
+}
+
+func (z *Zone) updateMeanDwellTime() {
+	z.MeanDwellTime = 0.0
+	if z.VisitorCount > 0 {
+		z.MeanDwellTime = z.DwellTime / float32(z.VisitorCount)
+	}
+}
+
+func GetZoneById(db *sql.DB, id int64) (*Zone, error) {
+	const query = `SELECT scout_uuid, name, polygon, visitor_count, dwell_time FROM zones WHERE id = $1`
+
+	var result Zone
+	err := db.QueryRow(query, id).Scan(&result.ScoutUUID, &result.Name, &result.Polygon,
+		&result.VisitorCount, &result.DwellTime)
+	result.Id = id
+	result.updateMeanDwellTime()
+
+	return &result, err
+}
+
+func GetZones(db Queryer, scoutUUID string) ([]*Zone, error) {
+	const query = `SELECT id, name, polygon, visitor_count, dwell_time FROM zones
+				   WHERE scout_uuid = $1 ORDER BY id`
+
+	result := []*Zone{}
+	rows, err := db.Query(query, scoutUUID)
+	if err == sql.ErrNoRows {
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var z Zone
+		z.ScoutUUID = scoutUUID
+		err = rows.Scan(&z.Id, &z.Name, &z.Polygon, &z.VisitorCount, &z.DwellTime)
+		if err != nil {
+			return result, err
+		}
+		z.updateMeanDwellTime()
+
+		result = append(result, &z)
+	}
+
+	return result, rows.Err()
+}
+
+func (z *Zone) Insert(db *sql.DB) error {
+	const query = `INSERT INTO zones (scout_uuid, name, polygon, visitor_count, dwell_time)
+				   VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return db.QueryRow(query, z.ScoutUUID, z.Name, z.Polygon, z.VisitorCount, z.DwellTime).Scan(&z.Id)
+}
+
+func (z *Zone) Update(db Queryer) error {
+	const query = `UPDATE zones SET name = $1, polygon = $2, visitor_count = $3, dwell_time = $4
+				   WHERE id = $5`
+	_, err := db.Exec(query, z.Name, z.Polygon, z.VisitorCount, z.DwellTime, z.Id)
+	z.updateMeanDwellTime()
+
+	return err
+}
+
+func (z *Zone) Delete(db *sql.DB) error {
+	const query = `DELETE FROM zones WHERE id = $1`
+	_, err := db.Exec(query, z.Id)
+	return err
+}
+
+// AddVisit records the visit v to the zone, adding it to the totals for the zone.
+func (z *Zone) AddVisit(db Queryer, v *ZoneVisit) error {
+	v.ZoneId = z.Id
+	z.VisitorCount += 1
+	z.DwellTime += v.DwellTime
+
+	err := v.Insert(db)
+	if err != nil {
+		return err
+	}
+
+	return z.Update(db)
+}
+
+// ClearZones removes all the visits to the zones of a scout, keeping the zones themselves.
+func ClearZones(db *sql.DB, scoutUUID string) error {
+	const deleteVisits = `DELETE FROM zone_visits WHERE zone_id IN (SELECT id FROM zones WHERE scout_uuid = $1)`
+	_, err := db.Exec(deleteVisits, scoutUUID)
+	if err != nil {
+		return err
+	}
+
+	const resetZones = `UPDATE zones SET visitor_count = 0, dwell_time = 0.0 WHERE scout_uuid = $1`
+	_, err = db.Exec(resetZones, scoutUUID)
+	return err
+}
+
+// ClearZoneVisits removes the visits made to the zones of a scout by interactions that entered
+// between from and to, taking them off the totals of each zone.
+func ClearZoneVisits(db Queryer, scoutUUID string, from time.Time, to time.Time) error {
+	const deleteVisits = `DELETE FROM zone_visits WHERE zone_id IN (SELECT id FROM zones WHERE scout_uuid = $1)
+						  AND interaction_id IN (SELECT id FROM scout_interactions WHERE scout_uuid = $1
+						  AND entered_at >= $2 AND entered_at < $3)`
+	_, err := db.Exec(deleteVisits, scoutUUID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+
+	const recountZones = `UPDATE zones SET
+						  visitor_count = (SELECT COUNT(*) FROM zone_visits WHERE zone_id = zones.id),
+						  dwell_time = (SELECT COALESCE(SUM(dwell_time), 0.0) FROM zone_visits WHERE zone_id = zones.id)
+						  WHERE scout_uuid = $1`
+	_, err = db.Exec(recountZones, scoutUUID)
+	return err
+}
+
+func GetZoneVisits(db *sql.DB, zoneId int64) ([]*ZoneVisit, error) {
+	const query = `SELECT interaction_id, dwell_time, first_entered_at, last_entered_at
+				   FROM zone_visits WHERE zone_id = $1 ORDER BY first_entered_at`
+
+	result := []*ZoneVisit{}
+	rows, err := db.Query(query, zoneId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v ZoneVisit
+		v.ZoneId = zoneId
+		err = rows.Scan(&v.InteractionId, &v.DwellTime, &v.FirstEnteredAt, &v.LastEnteredAt)
+		if err != nil {
+			return result, err
+		}
+		v.FirstEnteredAt = v.FirstEnteredAt.UTC()
+		v.LastEnteredAt = v.LastEnteredAt.UTC()
+
+		result = append(result, &v)
+	}
+
+	return result, rows.Err()
+}
+
+func (v *ZoneVisit) Insert(db Queryer) error {
+	const query = `INSERT INTO zone_visits (zone_id, interaction_id, dwell_time, first_entered_at,
+				   last_entered_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.Exec(query, v.ZoneId, v.InteractionId, v.DwellTime, v.FirstEnteredAt, v.LastEnteredAt)
+
+	return err
+}
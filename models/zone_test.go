@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestZone(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zone Suite")
+}
+
+var _ = Describe("Zone Model", func() {
+	AfterEach(cleaner)
+
+	square := Path{[2]int{0, 0}, [2]int{10, 0}, [2]int{10, 10}, [2]int{0, 10}}
+
+	Context("Insert", func() {
+		It("should be able to insert and get zones", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := Zone{-1, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z2, err := GetZoneById(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(z2).Should(Equal(&z))
+
+			zl, err := GetZones(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(zl).Should(Equal([]*Zone{&z}))
+		})
+
+		It("should return an empty list for a scout without zones", func() {
+			zl, err := GetZones(db, "59ef7180-f6b2-4129-99bf-970eb4312b4b")
+			Ω(err).Should(BeNil())
+			Ω(len(zl)).Should(Equal(0))
+		})
+	})
+
+	Context("AddVisit", func() {
+		It("should add visits to the zone totals", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := Zone{-1, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			err = z.AddVisit(db, &ZoneVisit{0, 1, 2.0, t, t})
+			Ω(err).Should(BeNil())
+			err = z.AddVisit(db, &ZoneVisit{0, 2, 4.0, t, t.Add(time.Second)})
+			Ω(err).Should(BeNil())
+
+			z2, err := GetZoneById(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(z2.VisitorCount).Should(Equal(int64(2)))
+			Ω(z2.DwellTime).Should(Equal(float32(6.0)))
+			Ω(z2.MeanDwellTime).Should(Equal(float32(3.0)))
+
+			v, err := GetZoneVisits(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(v).Should(Equal([]*ZoneVisit{&ZoneVisit{z.Id, 1, 2.0, t, t},
+				&ZoneVisit{z.Id, 2, 4.0, t, t.Add(time.Second)}}))
+		})
+
+		It("should be able to clear the visits to zones", func() {
+			s := Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: DefaultFrame, Privacy: DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := Zone{-1, s.UUID, "Help desk", square, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			err = z.AddVisit(db, &ZoneVisit{0, 1, 2.0, t, t})
+			Ω(err).Should(BeNil())
+
+			err = ClearZones(db, s.UUID)
+			Ω(err).Should(BeNil())
+
+			z2, err := GetZoneById(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(z2.VisitorCount).Should(Equal(int64(0)))
+			Ω(z2.Name).Should(Equal("Help desk"))
+
+			v, err := GetZoneVisits(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(len(v)).Should(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,145 @@
+/*
+ * Copyright (C) 2015 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"time"
+)
+
+// capture is a source of frames that CVDetector detects objects in. It is CVBindings, unless
+// scout is built without OpenCV.
+type capture interface {
+	// calibrate writes a single frame from videoFile (or the webcam when empty), at the
+	// resolution of the scout s, as a JPG to dstFile.
+	calibrate(videoFile string, s *models.Scout, dstFile string) error
+
+	// start opens videoFile (or the webcam when empty), ready for detecting objects with the
+	// detection parameters in s.
+	start(videoFile string, s *models.Scout) error
+
+	// grab returns the objects detected in the next frame. ok is false when no frame could be
+	// grabbed.
+	grab(s *models.Scout, debug bool) (objects []models.Waypoint, ok bool)
+
+	// position returns the timestamp of the frame last grabbed, in milliseconds from the start
+	// of the video.
+	position() float64
+
+	// frameRate returns the number of frames per second recorded by the video, or zero when it
+	// doesn't say.
+	frameRate() float64
+
+	// stop closes the capture.
+	stop()
+}
+
+// CVDetector detects objects from a webcam (or video file) via OpenCV and
+// CVBindings. Frames from a webcam are timed by the clock, while frames from a
+// video file are timed by their position within the video, so that recordings
+// can be processed faster than real time. When the container has no usable
+// timestamps, frames are timed by the frame rate of the stream, or by FrameRate
+// if the stream doesn't have one. The source is exhausted once CVBindings can't
+// grab another frame, which ends processing at the end of a video file.
+type CVDetector struct {
+	VideoFile  string  // The path to a video file to detect motion from instead of a webcam.
+	FrameRate  float64 // The number of frames per second in VideoFile, if the video doesn't say.
+	Debug      bool    // Should detected materials be rendered to disk.
+	capture    capture // The capture frames are grabbed from, CVBindings when nil.
+	frames     int64
+	started    time.Time
+	timestamps bool    // Do the frames of VideoFile have usable timestamps.
+	rate       float64 // The frame rate used when the frames have no timestamps.
+	lastMsec   float64 // The timestamp of the previous frame (in milliseconds).
+}
+
+// source returns the capture that frames are grabbed from.
+func (d *CVDetector) source() capture {
+	if d.capture == nil {
+		d.capture = cvCapture{}
+	}
+
+	return d.capture
+}
+
+func (d *CVDetector) Calibrate(s *models.Scout, dstFile string) error {
+	return d.source().calibrate(d.VideoFile, s, dstFile)
+}
+
+func (d *CVDetector) Start(s *models.Scout) error {
+	err := d.source().start(d.VideoFile, s)
+	if err != nil {
+		return err
+	}
+
+	d.frames = 0
+	d.started = time.Now()
+	d.timestamps = true
+	d.rate = d.FrameRate
+	d.lastMsec = -1.0
+
+	if fps := d.capture.frameRate(); fps > 0.0 {
+		d.rate = fps
+	}
+
+	return nil
+}
+
+// frameTime returns the time the frame just grabbed was captured.
+func (d *CVDetector) frameTime() time.Time {
+	if d.VideoFile == "" {
+		return time.Now()
+	}
+
+	offset := -1.0
+	if d.timestamps {
+		// Some containers report no (or the same) position for every frame, so once a frame
+		// doesn't move forward the rest of the video is timed by the frame rate.
+		msec := d.capture.position()
+		if msec < 0.0 || msec <= d.lastMsec {
+			d.timestamps = false
+		} else {
+			offset = msec / 1000.0
+			d.lastMsec = msec
+		}
+	}
+
+	if offset < 0.0 {
+		if d.rate <= 0.0 {
+			return time.Now()
+		}
+
+		offset = float64(d.frames) / d.rate
+	}
+	d.frames++
+
+	return d.started.Add(time.Duration(offset * float64(time.Second)))
+}
+
+func (d *CVDetector) Detect(s *models.Scout) ([]models.Waypoint, time.Time, bool) {
+	objects, ok := d.capture.grab(s, d.Debug)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	return objects, d.frameTime(), true
+}
+
+func (d *CVDetector) Stop() {
+	d.source().stop()
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/MeasureTheFuture/scout/models"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+)
+
+// Detector is a source of detected objects that Monitor drives when calibrating
+// and measuring.
+type Detector interface {
+	// Calibrate captures a single frame from the source, at the resolution of the
+	// scout s, and writes it as a JPG to dstFile.
+	Calibrate(s *models.Scout, dstFile string) error
+
+	// Start opens the source, ready for detecting objects with the detection
+	// parameters in s.
+	Start(s *models.Scout) error
+
+	// Detect returns the objects detected in the next frame from the source, along
+	// with the time the frame was captured. ok is false once the source has been
+	// exhausted, like at the end of a recording or when a camera stops supplying
+	// frames, after which Detect isn't called again.
+	Detect(s *models.Scout) (objects []models.Waypoint, t time.Time, ok bool)
+
+	// Stop closes the source.
+	Stop()
+}
+
+// ReplayFrame is a single frame of recorded detections.
+type ReplayFrame struct {
+	T       float32           // The number of seconds elapsed since the start of the recording.
+	Objects []models.Waypoint // The objects detected within the frame.
+}
+
+// ReplayDetector is a pure-Go Detector that plays back recorded detections,
+// allowing measurement to run on machines without a camera or OpenCV.
+type ReplayDetector struct {
+	Frames   []ReplayFrame // The recorded frames to play back.
+	RealTime bool          // Should playback be paced to match the recorded frame times.
+	next     int
+	stopped  int32 // Set (atomically) by Stop, which is called from another goroutine than Detect.
+	started  time.Time
+}
+
+// LoadReplayDetector creates a ReplayDetector from a JSON file containing an
+// array of ReplayFrames.
+func LoadReplayDetector(replayFile string, realTime bool) (*ReplayDetector, error) {
+	b, err := ioutil.ReadFile(replayFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []ReplayFrame
+	err = json.Unmarshal(b, &frames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayDetector{frames, realTime, 0, 0, time.Time{}}, nil
+}
+
+func (r *ReplayDetector) Calibrate(s *models.Scout, dstFile string) error {
+	return errors.New("Unable to calibrate from recorded detections")
+}
+
+func (r *ReplayDetector) Start(s *models.Scout) error {
+	r.next = 0
+	atomic.StoreInt32(&r.stopped, 0)
+	r.started = time.Now()
+	return nil
+}
+
+func (r *ReplayDetector) Detect(s *models.Scout) ([]models.Waypoint, time.Time, bool) {
+	if r.next >= len(r.Frames) || atomic.LoadInt32(&r.stopped) != 0 {
+		return nil, time.Time{}, false
+	}
+
+	f := r.Frames[r.next]
+	r.next++
+
+	t := r.started.Add(time.Duration(f.T * float32(time.Second)))
+	if r.RealTime {
+		time.Sleep(t.Sub(time.Now()))
+	}
+
+	return f.Objects, t, true
+}
+
+func (r *ReplayDetector) Stop() {
+	atomic.StoreInt32(&r.stopped, 1)
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/configuration"
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestDetector(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "detector Suite")
+}
+
+var _ = Describe("Detector", func() {
+	AfterEach(cleaner)
+
+	Context("ReplayDetector", func() {
+		It("should return an error for a missing replay file", func() {
+			_, err := LoadReplayDetector("foo", false)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should play back recorded detections until exhausted", func() {
+			d, err := LoadReplayDetector("../testdata/detections.json", false)
+			Ω(err).Should(BeNil())
+			Ω(len(d.Frames)).Should(Equal(10))
+
+			err = d.Start(nil)
+			Ω(err).Should(BeNil())
+
+			o, t0, ok := d.Detect(nil)
+			Ω(ok).Should(BeTrue())
+			Ω(o).Should(Equal([]models.Waypoint{models.Waypoint{100, 300, 40, 80, 0.0},
+				models.Waypoint{1100, 600, 40, 80, 0.0}}))
+
+			for i := 1; i < 10; i++ {
+				_, t, ok := d.Detect(nil)
+				Ω(ok).Should(BeTrue())
+				Ω(t.Sub(t0).Seconds()).Should(BeNumerically("~", float64(i)*0.1, 0.001))
+			}
+
+			_, _, ok = d.Detect(nil)
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("should stop playing back when stopped from another goroutine", func() {
+			d, err := LoadReplayDetector("../testdata/detections.json", false)
+			Ω(err).Should(BeNil())
+
+			err = d.Start(nil)
+			Ω(err).Should(BeNil())
+
+			_, _, ok := d.Detect(nil)
+			Ω(ok).Should(BeTrue())
+
+			done := make(chan bool)
+			go func() {
+				d.Stop()
+				done <- true
+			}()
+			<-done
+
+			_, _, ok = d.Detect(nil)
+			Ω(ok).Should(BeFalse())
+
+			// Starting again plays back from the first frame.
+			err = d.Start(nil)
+			Ω(err).Should(BeNil())
+			_, _, ok = d.Detect(nil)
+			Ω(ok).Should(BeTrue())
+		})
+
+		It("should not be able to calibrate", func() {
+			d := &ReplayDetector{}
+			Ω(d.Calibrate(&models.Scout{}, "foo.jpg")).ShouldNot(BeNil())
+		})
+	})
+
+	Context("measure", func() {
+		It("should save interactions detected from recorded detections", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "measuring",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			d, err := LoadReplayDetector("../testdata/detections.json", false)
+			Ω(err).Should(BeNil())
+
+			live := models.NewLiveScene("")
+			measure(db, configuration.Configuration{}, make(chan models.Command), d, live)
+			Ω(live.Snapshot().Measuring).Should(BeFalse())
+
+			n, err := models.NumScoutInteractions(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(2)))
+
+			// Durations come from the recorded frame times, not from how fast they were replayed.
+			si, err := models.GetLastScoutInteraction(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(si.Duration).Should(BeNumerically("~", float32(0.9), 0.001))
+		})
+	})
+})
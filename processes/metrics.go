@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	"math"
+)
+
+// waypoint returns the k'th waypoint of the interaction si.
+func waypoint(si *models.ScoutInteraction, k int) models.Waypoint {
+	return models.Waypoint{si.Waypoints[k][0], si.Waypoints[k][1],
+		si.WaypointWidths[k][0], si.WaypointWidths[k][1], si.WaypointTimes[k]}
+}
+
+// nearestEdge returns the edge of the frame f closest to the waypoint w. An interaction needs
+// to be within a bucket of the edge to have entered or left through it.
+func nearestEdge(w models.Waypoint, f models.Frame) models.Edge {
+	b := vec.AABBFromWaypoint(w, f.Width, f.Height)
+
+	result := models.INTERIOR
+	nearest := math.MaxInt32
+	for _, e := range []struct {
+		edge   models.Edge
+		d      int
+		within int
+	}{
+		{models.LEFT, b.Min[0], f.BucketW()},
+		{models.TOP, b.Min[1], f.BucketH()},
+		{models.RIGHT, f.Width - b.Max[0], f.BucketW()},
+		{models.BOTTOM, f.Height - b.Max[1], f.BucketH()},
+	} {
+		if e.d <= e.within && e.d <= nearest {
+			result = e.edge
+			nearest = e.d
+		}
+	}
+
+	return result
+}
+
+// longestDwell returns the duration and position of the longest of the dwells of an interaction.
+func longestDwell(dwells []models.Dwell) (float32, [2]int, bool) {
+	var result *models.Dwell
+	for k := range dwells {
+		if result == nil || dwells[k].Duration > result.Duration {
+			result = &dwells[k]
+		}
+	}
+
+	if result == nil {
+		return 0.0, [2]int{}, false
+	}
+
+	return result.Duration, [2]int{result.XPixels, result.YPixels}, true
+}
+
+// interactionMetrics derives the metrics of the interaction si with the dwells detected along its
+// path, within the frame f.
+func interactionMetrics(si *models.ScoutInteraction, dwells []models.Dwell, f models.Frame) models.InteractionMetrics {
+	m := models.InteractionMetrics{si.Id, si.ScoutUUID, si.EnteredAt, 0.0, 0.0, 0.0, 0.0,
+		[2]int{}, models.Path{}, models.INTERIOR, models.INTERIOR}
+	if len(si.Waypoints) == 0 {
+		return m
+	}
+
+	bounds := vec.AABBFromWaypoint(waypoint(si, 0), f.Width, f.Height)
+	for k := 1; k < len(si.Waypoints); k++ {
+		b := vec.AABBFromWaypoint(waypoint(si, k), f.Width, f.Height)
+		bounds = vec.AABB{vec.Vec{vec.Min(bounds.Min[0], b.Min[0]), vec.Min(bounds.Min[1], b.Min[1])},
+			vec.Vec{vec.Max(bounds.Max[0], b.Max[0]), vec.Max(bounds.Max[1], b.Max[1])}}
+
+		travel := vec.Vec{si.Waypoints[k][0] - si.Waypoints[k-1][0], si.Waypoints[k][1] - si.Waypoints[k-1][1]}
+		d := travel.Length()
+		m.PathLength += float32(d)
+
+		if dt := si.WaypointTimes[k] - si.WaypointTimes[k-1]; dt > 0.0 {
+			m.MaxSpeed = float32(math.Max(float64(m.MaxSpeed), d/float64(dt)))
+		}
+	}
+
+	if si.Duration > 0.0 {
+		m.MeanSpeed = m.PathLength / si.Duration
+	}
+
+	// An interaction that never dwelled is placed where it entered.
+	m.DwellPosition = si.Waypoints[0]
+	if d, at, ok := longestDwell(dwells); ok {
+		m.LongestDwell, m.DwellPosition = d, at
+	}
+	m.Bounds = models.Path{[2]int{bounds.Min[0], bounds.Min[1]}, [2]int{bounds.Max[0], bounds.Max[1]}}
+	m.EntryEdge = nearestEdge(waypoint(si, 0), f)
+	m.ExitEdge = nearestEdge(waypoint(si, len(si.Waypoints)-1), f)
+
+	return m
+}
+
+// updateMetrics stores the metrics of the interaction si, within the frame f.
+func updateMetrics(db models.Queryer, si *models.ScoutInteraction, f models.Frame) error {
+	dwells, err := models.GetInteractionDwells(db, si.Id)
+	if err != nil {
+		return err
+	}
+
+	m := interactionMetrics(si, dwells, f)
+	return m.Save(db)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "metrics Suite")
+}
+
+var _ = Describe("Metrics", func() {
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	Context("nearestEdge", func() {
+		It("should find the edge of the frame an interaction is next to", func() {
+			Ω(nearestEdge(models.Waypoint{20, 360, 10, 40, 0.0}, models.DefaultFrame)).Should(Equal(models.LEFT))
+			Ω(nearestEdge(models.Waypoint{1270, 360, 10, 40, 0.0}, models.DefaultFrame)).Should(Equal(models.RIGHT))
+			Ω(nearestEdge(models.Waypoint{640, 30, 10, 20, 0.0}, models.DefaultFrame)).Should(Equal(models.TOP))
+			Ω(nearestEdge(models.Waypoint{640, 700, 10, 20, 0.0}, models.DefaultFrame)).Should(Equal(models.BOTTOM))
+			Ω(nearestEdge(models.Waypoint{640, 360, 10, 20, 0.0}, models.DefaultFrame)).Should(Equal(models.INTERIOR))
+		})
+
+		It("should measure each edge by the size of the buckets across it", func() {
+			// The buckets of the default frame are 64 pixels wide, but only 36 pixels high.
+			Ω(nearestEdge(models.Waypoint{60, 360, 10, 40, 0.0}, models.DefaultFrame)).Should(Equal(models.LEFT))
+			Ω(nearestEdge(models.Waypoint{640, 70, 10, 20, 0.0}, models.DefaultFrame)).Should(Equal(models.INTERIOR))
+			Ω(nearestEdge(models.Waypoint{640, 50, 10, 20, 0.0}, models.DefaultFrame)).Should(Equal(models.TOP))
+		})
+
+		It("should use the resolution and grid of the scout", func() {
+			f := models.Frame{640, 480, 10, 10}
+			Ω(nearestEdge(models.Waypoint{600, 240, 10, 40, 0.0}, f)).Should(Equal(models.RIGHT))
+			Ω(nearestEdge(models.Waypoint{600, 240, 10, 40, 0.0}, models.DefaultFrame)).Should(Equal(models.INTERIOR))
+		})
+	})
+
+	Context("longestDwell", func() {
+		It("should find the longest of the dwells of an interaction", func() {
+			dwells := []models.Dwell{
+				models.Dwell{1, 1, "", 200, 100, 2.0, 4.0},
+				models.Dwell{2, 1, "", 400, 120, 8.0, 12.0},
+				models.Dwell{3, 1, "", 600, 100, 22.0, 3.0},
+			}
+
+			d, at, ok := longestDwell(dwells)
+			Ω(ok).Should(BeTrue())
+			Ω(d).Should(BeNumerically("~", 12.0, 0.001))
+			Ω(at).Should(Equal([2]int{400, 120}))
+		})
+
+		It("should not find a dwell for an interaction that keeps moving", func() {
+			d, _, ok := longestDwell([]models.Dwell{})
+			Ω(ok).Should(BeFalse())
+			Ω(d).Should(Equal(float32(0.0)))
+		})
+	})
+
+	Context("interactionMetrics", func() {
+		It("should derive the metrics of an interaction", func() {
+			si := models.ScoutInteraction{7, "59ef7180-f6b2-4129-99bf-970eb4312b4b", 5.0,
+				models.Path{[2]int{20, 300}, [2]int{320, 300}, [2]int{320, 700}},
+				models.Path{[2]int{10, 20}, [2]int{10, 20}, [2]int{10, 20}},
+				models.RealArray{0.0, 1.0, 5.0}, false, t}
+
+			m := interactionMetrics(&si, []models.Dwell{models.Dwell{1, 7, si.ScoutUUID, 320, 500, 1.0, 3.0}},
+				models.DefaultFrame)
+			Ω(m.InteractionId).Should(Equal(int64(7)))
+			Ω(m.ScoutUUID).Should(Equal(si.ScoutUUID))
+			Ω(m.EnteredAt).Should(Equal(t))
+			Ω(m.PathLength).Should(BeNumerically("~", 700.0, 0.001))
+			Ω(m.MeanSpeed).Should(BeNumerically("~", 140.0, 0.001))
+			Ω(m.MaxSpeed).Should(BeNumerically("~", 300.0, 0.001))
+			Ω(m.LongestDwell).Should(BeNumerically("~", 3.0, 0.001))
+			Ω(m.DwellPosition).Should(Equal([2]int{320, 500}))
+			Ω(m.Bounds).Should(Equal(models.Path{[2]int{10, 280}, [2]int{330, 720}}))
+			Ω(m.EntryEdge).Should(Equal(models.LEFT))
+			Ω(m.ExitEdge).Should(Equal(models.BOTTOM))
+		})
+	})
+})
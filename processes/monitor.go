@@ -17,32 +17,23 @@
 
 package processes
 
-/*
-#cgo darwin CFLAGS: -I/usr/local/opt/opencv@3/include -I/usr/local/opt/opencv@3/include/opencv
-#cgo linux CFLAGS: -I/usr/local/include -I/usr/local/include/opencv
-#cgo CFLAGS: -Wno-error
-#cgo darwin LDFLAGS: -L/usr/local/opt/opencv@3/
-#cgo linux LDFLAGS: -L/usr/local/lib -L/usr/lib
-#cgo darwin LDFLAGS: -lstdc++ -lopencv_imgcodecs -lopencv_imgproc -lopencv_videoio -lopencv_highgui -lopencv_core -lopencv_features2d -lopencv_video -lopencv_core -lCVBindings
-#cgo linux LDFLAGS: -lm -lstdc++ -lz -ldl -lpthread -lv4l1 -lv4l2 -lopencv_imgcodecs -lopencv_imgproc -lopencv_videoio -lopencv_highgui -lopencv_video -lopencv_core -lCVBindings
-#include "stdlib.h"
-#include "CVBindings.h"
-*/
-import "C"
-
 import (
 	"database/sql"
 	"github.com/MeasureTheFuture/scout/configuration"
 	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
 	"runtime"
-	"unsafe"
+	"time"
 )
 
-func Monitor(db *sql.DB, deltaC chan models.Command, videoFile string, debug bool) {
+// Monitor listens for commands on deltaC, calibrating and measuring with the
+// supplied detector. Snapshots of the scene being measured are published to live.
+func Monitor(db *sql.DB, config configuration.Configuration, deltaC chan models.Command, d Detector,
+	live *models.LiveScene) {
 
 	// All OpenCV operations must run on the OS thread to access the webcam.
 	runtime.LockOSThread()
@@ -53,7 +44,7 @@ func Monitor(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 		switch {
 		case c == models.CALIBRATE:
 			log.Printf("INFO: Calibrating scout.")
-			calibrate(db, videoFile)
+			calibrate(db, d)
 
 		case c == models.START_MEASURE:
 			log.Printf("INFO: Starting measure")
@@ -66,7 +57,7 @@ func Monitor(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 				log.Print(err)
 			}
 
-			measure(db, deltaC, videoFile, debug)
+			measure(db, config, deltaC, d, live)
 
 		case c == models.STOP_MEASURE:
 			log.Printf("INFO: Stopping measure")
@@ -83,28 +74,22 @@ func Monitor(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 	runtime.UnlockOSThread()
 }
 
-func calibrate(db *sql.DB, videoFile string) {
-	srcFile := C.CString(videoFile)
-	dstFile := C.CString("calibrationFrame.jpg")
-
-	success := C.calibrate(srcFile, dstFile, C.int(configuration.FrameW), C.int(configuration.FrameH))
-
-	C.free(unsafe.Pointer(srcFile))
-	C.free(unsafe.Pointer(dstFile))
-
-	if success != true {
-		log.Printf("ERROR: Unable to Calibrate")
+func calibrate(db *sql.DB, d Detector) {
+	s, err := models.GetScoutByUUID(db, models.GetScoutUUID(db))
+	if err != nil {
+		log.Printf("ERROR: Unable to calibrate, can't fetch scout from DB")
+		log.Print(err)
 		return
 	}
 
-	// Update the DB with the latest calibration details.
-	s, err := models.GetScoutByUUID(db, models.GetScoutUUID(db))
+	err = d.Calibrate(s, "calibrationFrame.jpg")
 	if err != nil {
-		log.Printf("ERROR: Unable to calibrate, can't fetch scout from DB")
+		log.Printf("ERROR: Unable to Calibrate")
 		log.Print(err)
 		return
 	}
 
+	// Update the DB with the latest calibration details.
 	frame, err := ioutil.ReadFile("calibrationFrame.jpg")
 	if err != nil {
 		log.Printf("ERROR: Unable to calibrate, can't fetch frame from disk.")
@@ -122,27 +107,22 @@ func calibrate(db *sql.DB, videoFile string) {
 	}
 }
 
-func measure(db *sql.DB, deltaC chan models.Command, videoFile string, debug bool) {
-	s := models.GetScout(db)
-
-	if _, err := os.Stat("calibrationFrame.jpg"); err != nil {
-		log.Printf("ERROR: Unable to measure, missing calibration frame")
-		log.Print(err)
-		return
-	}
-
-	srcFile := C.CString(videoFile)
-	calFile := C.CString("calibrationFrame.jpg")
+// newScene creates the scene used to track interactions detected by the scout s.
+func newScene(s *models.Scout) *models.Scene {
+	scene := models.InitScene(s)
+	scene.SetCost(vec.MatchCost(s, s.Frame.Width, s.Frame.Height))
 
-	success := C.startMeasure(srcFile, calFile,
-		C.int(configuration.FrameW), C.int(configuration.FrameH),
-		C.int(s.MogHistoryLength), C.double(s.MogThreshold), C.int(s.MogDetectShadows))
+	return scene
+}
 
-	C.free(unsafe.Pointer(srcFile))
-	C.free(unsafe.Pointer(calFile))
+func measure(db *sql.DB, config configuration.Configuration, deltaC chan models.Command, d Detector,
+	live *models.LiveScene) {
+	s := models.GetScout(db)
 
-	if success != true {
-		log.Printf("ERROR: Unable to get video source")
+	err := d.Start(s)
+	if err != nil {
+		log.Printf("ERROR: Unable to start measuring")
+		log.Print(err)
 		return
 	}
 
@@ -152,11 +132,21 @@ func measure(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 	go func() {
 		<-c
 		log.Printf("INFO: The OS shut down the scout.")
-		C.stopMeasure()
+		d.Stop()
 		return
 	}()
 
-	scene := models.InitScene(s)
+	scene := newScene(s)
+	mask := vec.NewMask(s)
+
+	// Resume tracking the interactions that were in view when the scout last stopped.
+	err = scene.Restore(db, ".mtf-scene", time.Now().UTC())
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("ERROR: Unable to restore the scene checkpoint")
+		log.Print(err)
+	}
+	interval := time.Millisecond * time.Duration(config.CheckpointInterval)
+	var checkpointed time.Time
 
 	measuring := true
 
@@ -182,28 +172,23 @@ func measure(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 			// Procceed with measuring.
 		}
 
-		numObjects := C.int(0)
-		objects := C.grabFrame(&numObjects,
-			C._Bool(debug),
-			C.double(s.GaussianSmooth),
-			C.double(s.ForegroundThresh),
-			C.int(s.DilationIterations),
-			C.double(s.MinArea),
-			C.double(s.MaxArea))
-		o := (*[1 << 30]C.int)(unsafe.Pointer(objects))
-
-		var detectedObjects []models.Waypoint
-		for i := C.int(0); i < numObjects; i = i + 4 {
-			detectedObjects = append(detectedObjects,
-				models.Waypoint{int(o[i]),
-					int(o[i+1]),
-					int(o[i+2]),
-					int(o[i+3]), 0.0})
+		detectedObjects, t, ok := d.Detect(s)
+		if !ok {
+			log.Printf("INFO: Detector exhausted, stopping measure")
+			break
 		}
 
-		C.free(unsafe.Pointer(objects))
+		scene.Update(db, mask.Filter(detectedObjects), t)
+		live.Publish(scene, t)
 
-		scene.Update(db, detectedObjects)
+		if t.Sub(checkpointed) >= interval {
+			err = scene.Checkpoint(".mtf-scene", t)
+			if err != nil {
+				log.Printf("ERROR: Unable to checkpoint the scene")
+				log.Print(err)
+			}
+			checkpointed = t
+		}
 
 		/**
 		TODO: Need a new method call for debug printing the interaction path.
@@ -249,5 +234,13 @@ func measure(db *sql.DB, deltaC chan models.Command, videoFile string, debug boo
 
 	log.Printf("INFO: Finished measure")
 	scene.Close(db)
-	C.stopMeasure()
+	live.Stop(time.Now().UTC())
+
+	// The scene has been closed, so there is nothing to resume.
+	err = os.Remove(".mtf-scene")
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("ERROR: Unable to remove .mtf-scene file")
+		log.Print(err)
+	}
+	d.Stop()
 }
@@ -0,0 +1,127 @@
+//go:build !nocv
+// +build !nocv
+
+/*
+ * Copyright (C) 2015 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+/*
+#cgo darwin CFLAGS: -I/usr/local/opt/opencv@3/include -I/usr/local/opt/opencv@3/include/opencv
+#cgo linux CFLAGS: -I/usr/local/include -I/usr/local/include/opencv
+#cgo CFLAGS: -Wno-error
+#cgo darwin LDFLAGS: -L/usr/local/opt/opencv@3/
+#cgo linux LDFLAGS: -L/usr/local/lib -L/usr/lib
+#cgo darwin LDFLAGS: -lstdc++ -lopencv_imgcodecs -lopencv_imgproc -lopencv_videoio -lopencv_highgui -lopencv_core -lopencv_features2d -lopencv_video -lopencv_core -lCVBindings
+#cgo linux LDFLAGS: -lm -lstdc++ -lz -ldl -lpthread -lv4l1 -lv4l2 -lopencv_imgcodecs -lopencv_imgproc -lopencv_videoio -lopencv_highgui -lopencv_video -lopencv_core -lCVBindings
+#include "stdlib.h"
+#include "CVBindings.h"
+#include "opencv2/videoio/videoio_c.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"github.com/MeasureTheFuture/scout/models"
+	"os"
+	"unsafe"
+)
+
+// cvCapture grabs frames from a webcam (or video file) through CVBindings, which holds a single
+// capture open at a time.
+type cvCapture struct{}
+
+func (cvCapture) calibrate(videoFile string, s *models.Scout, dstFile string) error {
+	srcFile := C.CString(videoFile)
+	dst := C.CString(dstFile)
+
+	success := C.calibrate(srcFile, dst, C.int(s.Frame.Width), C.int(s.Frame.Height))
+
+	C.free(unsafe.Pointer(srcFile))
+	C.free(unsafe.Pointer(dst))
+
+	if success != true {
+		return errors.New("Unable to calibrate from video source")
+	}
+
+	return nil
+}
+
+func (cvCapture) start(videoFile string, s *models.Scout) error {
+	if _, err := os.Stat("calibrationFrame.jpg"); err != nil {
+		return err
+	}
+
+	srcFile := C.CString(videoFile)
+	calFile := C.CString("calibrationFrame.jpg")
+
+	success := C.startMeasure(srcFile, calFile,
+		C.int(s.Frame.Width), C.int(s.Frame.Height),
+		C.int(s.MogHistoryLength), C.double(s.MogThreshold), C.int(s.MogDetectShadows))
+
+	C.free(unsafe.Pointer(srcFile))
+	C.free(unsafe.Pointer(calFile))
+
+	if success != true {
+		return errors.New("Unable to get video source")
+	}
+
+	return nil
+}
+
+func (cvCapture) grab(s *models.Scout, debug bool) ([]models.Waypoint, bool) {
+	numObjects := C.int(0)
+	objects := C.grabFrame(&numObjects,
+		C._Bool(debug),
+		C.double(s.GaussianSmooth),
+		C.double(s.ForegroundThresh),
+		C.int(s.DilationIterations),
+		C.double(s.MinArea),
+		C.double(s.MaxArea))
+
+	// CVBindings returns no objects at all (rather than an empty list) once a frame can't be
+	// grabbed, like at the end of a video file.
+	if objects == nil {
+		return nil, false
+	}
+	o := (*[1 << 30]C.int)(unsafe.Pointer(objects))
+
+	var detectedObjects []models.Waypoint
+	for i := C.int(0); i < numObjects; i = i + 4 {
+		detectedObjects = append(detectedObjects,
+			models.Waypoint{int(o[i]),
+				int(o[i+1]),
+				int(o[i+2]),
+				int(o[i+3]), 0.0})
+	}
+
+	C.free(unsafe.Pointer(objects))
+
+	return detectedObjects, true
+}
+
+func (cvCapture) position() float64 {
+	return float64(C.captureProperty(C.CV_CAP_PROP_POS_MSEC))
+}
+
+func (cvCapture) frameRate() float64 {
+	return float64(C.captureProperty(C.CV_CAP_PROP_FPS))
+}
+
+func (cvCapture) stop() {
+	C.stopMeasure()
+}
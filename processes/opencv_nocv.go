@@ -0,0 +1,55 @@
+//go:build nocv
+// +build nocv
+
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"errors"
+	"github.com/MeasureTheFuture/scout/models"
+)
+
+var errNoCV = errors.New("Scout was built without OpenCV (nocv), use recorded detections instead")
+
+// cvCapture is unavailable when scout is built with the nocv tag. Every operation fails, so
+// Monitor must be driven with a ReplayDetector instead.
+type cvCapture struct{}
+
+func (cvCapture) calibrate(videoFile string, s *models.Scout, dstFile string) error {
+	return errNoCV
+}
+
+func (cvCapture) start(videoFile string, s *models.Scout) error {
+	return errNoCV
+}
+
+func (cvCapture) grab(s *models.Scout, debug bool) ([]models.Waypoint, bool) {
+	return nil, false
+}
+
+func (cvCapture) position() float64 {
+	return -1.0
+}
+
+func (cvCapture) frameRate() float64 {
+	return 0.0
+}
+
+func (cvCapture) stop() {
+}
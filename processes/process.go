@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	"log"
+	"path/filepath"
+)
+
+// SummarySink keeps finished interactions in memory, summarising each of them as they
+// arrive. It allows recordings to be processed without touching the database.
+type SummarySink struct {
+	Interactions []models.ScoutInteraction
+	Dwells       []models.Dwell
+	Summary      models.ScoutSummary
+	Frame        models.Frame // The frame of the scout that detected the interactions.
+}
+
+// NewSummarySink creates an empty SummarySink for interactions detected by the scout s.
+func NewSummarySink(s *models.Scout) *SummarySink {
+	return &SummarySink{[]models.ScoutInteraction{}, []models.Dwell{}, *models.NewScoutSummary(s.UUID, s.Frame),
+		s.Frame}
+}
+
+func (m *SummarySink) Save(si *models.ScoutInteraction, dwells []models.Dwell) error {
+	si.Id = int64(len(m.Interactions) + 1)
+	si.Processed = true
+
+	for _, d := range dwells {
+		d.Id = int64(len(m.Dwells) + 1)
+		d.InteractionId = si.Id
+		d.ScoutUUID = si.ScoutUUID
+		m.Dwells = append(m.Dwells, d)
+	}
+
+	// The summary is kept for the recording alone, so it isn't clipped for noise.
+	m.Summary.VisitorCount += 1
+	updateTimeBuckets(&m.Summary, si, m.Frame, models.Privacy{})
+	m.Interactions = append(m.Interactions, *si)
+
+	return nil
+}
+
+// WriteJSON saves the interactions, dwells and summary held by the sink as
+// scout_interactions.json, dwells.json and scout_summaries.json within dir.
+func (m *SummarySink) WriteJSON(dir string) error {
+	err := configuration.SaveAsJSON(m.Interactions, filepath.Join(dir, "scout_interactions.json"))
+	if err != nil {
+		return err
+	}
+
+	err = configuration.SaveAsJSON(m.Dwells, filepath.Join(dir, "dwells.json"))
+	if err != nil {
+		return err
+	}
+
+	return configuration.SaveAsJSON([]models.ScoutSummary{m.Summary}, filepath.Join(dir, "scout_summaries.json"))
+}
+
+// Process runs detection and tracking over a recording with the settings of the scout s, as
+// fast as the detector can supply frames. Finished interactions are saved to sink rather than
+// the tables of the live scout.
+func Process(s *models.Scout, d Detector, sink models.InteractionSink) error {
+	err := d.Start(s)
+	if err != nil {
+		return err
+	}
+	defer d.Stop()
+
+	scene := newScene(s)
+	scene.SetSink(sink)
+	mask := vec.NewMask(s)
+
+	frames := 0
+	for {
+		detectedObjects, t, ok := d.Detect(s)
+		if !ok {
+			break
+		}
+
+		scene.Update(nil, mask.Filter(detectedObjects), t)
+		frames++
+	}
+
+	log.Printf("INFO: Processed %d frames", frames)
+	scene.Close(nil)
+
+	return nil
+}
+
+// SummariseDataset summarises all the unprocessed interactions held in db. It is used to
+// build the summary of a separate dataset once a recording has been processed into it.
+func SummariseDataset(db *sql.DB) {
+	updateUnprocessed(db)
+}
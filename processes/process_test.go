@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"encoding/json"
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "process Suite")
+}
+
+// recordedCapture stands in for CVBindings, grabbing the frames of a recording until it runs out.
+type recordedCapture struct {
+	frames []ReplayFrame
+	next   int
+}
+
+func (c *recordedCapture) calibrate(videoFile string, s *models.Scout, dstFile string) error {
+	return nil
+}
+
+func (c *recordedCapture) start(videoFile string, s *models.Scout) error {
+	c.next = 0
+	return nil
+}
+
+func (c *recordedCapture) grab(s *models.Scout, debug bool) ([]models.Waypoint, bool) {
+	if c.next >= len(c.frames) {
+		return nil, false
+	}
+
+	c.next++
+	return c.frames[c.next-1].Objects, true
+}
+
+func (c *recordedCapture) position() float64 {
+	return float64(c.frames[c.next-1].T) * 1000.0
+}
+
+func (c *recordedCapture) frameRate() float64 {
+	return 0.0
+}
+
+func (c *recordedCapture) stop() {
+}
+
+var _ = Describe("Process", func() {
+	s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+		Port: 8080, Authorised: true, Name: "foo", State: "idle", Summary: &models.ScoutSummary{},
+		MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+		MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1,
+		MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0,
+		MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5,
+		DwellSqDistance: 400, DwellDuration: 5.0, Frame: models.DefaultFrame,
+		Privacy: models.DefaultPrivacy}
+
+	It("should summarise interactions from a recording in memory", func() {
+		d, err := LoadReplayDetector("../testdata/detections.json", false)
+		Ω(err).Should(BeNil())
+
+		sink := NewSummarySink(&s)
+		err = Process(&s, d, sink)
+		Ω(err).Should(BeNil())
+
+		Ω(len(sink.Interactions)).Should(Equal(2))
+		Ω(sink.Interactions[0].Id).Should(Equal(int64(1)))
+		Ω(sink.Interactions[0].ScoutUUID).Should(Equal(s.UUID))
+		Ω(sink.Interactions[0].Duration).Should(BeNumerically("~", float32(0.9), 0.001))
+		Ω(sink.Interactions[0].Processed).Should(BeTrue())
+		Ω(sink.Summary.VisitorCount).Should(Equal(int64(2)))
+	})
+
+	It("should finish processing once the video runs out of frames", func() {
+		r, err := LoadReplayDetector("../testdata/detections.json", false)
+		Ω(err).Should(BeNil())
+		d := &CVDetector{VideoFile: "footage.mp4", capture: &recordedCapture{r.Frames, 0}}
+
+		sink := NewSummarySink(&s)
+		done := make(chan error)
+		go func() {
+			done <- Process(&s, d, sink)
+		}()
+
+		select {
+		case err = <-done:
+			Ω(err).Should(BeNil())
+		case <-time.After(5 * time.Second):
+			Fail("Process didn't finish at the end of the video")
+		}
+
+		Ω(len(sink.Interactions)).Should(Equal(2))
+		Ω(sink.Interactions[0].Duration).Should(BeNumerically("~", float32(0.9), 0.001))
+		Ω(sink.Summary.VisitorCount).Should(Equal(int64(2)))
+	})
+
+	It("should write the results of processing as JSON", func() {
+		dir, err := ioutil.TempDir("", "scout-process")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+
+		d, err := LoadReplayDetector("../testdata/detections.json", false)
+		Ω(err).Should(BeNil())
+
+		sink := NewSummarySink(&s)
+		err = Process(&s, d, sink)
+		Ω(err).Should(BeNil())
+		Ω(sink.WriteJSON(dir)).Should(BeNil())
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "scout_interactions.json"))
+		Ω(err).Should(BeNil())
+		var interactions []models.ScoutInteraction
+		Ω(json.Unmarshal(b, &interactions)).Should(BeNil())
+		Ω(len(interactions)).Should(Equal(2))
+
+		b, err = ioutil.ReadFile(filepath.Join(dir, "scout_summaries.json"))
+		Ω(err).Should(BeNil())
+		var summaries []models.ScoutSummary
+		Ω(json.Unmarshal(b, &summaries)).Should(BeNil())
+		Ω(summaries[0].VisitorCount).Should(Equal(int64(2)))
+	})
+})
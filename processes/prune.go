@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"database/sql"
+	"github.com/MeasureTheFuture/scout/configuration"
+	"github.com/MeasureTheFuture/scout/models"
+	"log"
+	"time"
+)
+
+// Prune deletes the interactions, health heartbeats and logs that are older than their retention
+// every PruneInterval milliseconds, logging what was deleted. It returns straight away when every
+// measurement is kept forever.
+func Prune(db *sql.DB, c configuration.Configuration) {
+	if c.InteractionRetention <= 0 && c.HealthRetention <= 0 && c.LogRetention <= 0 {
+		return
+	}
+
+	poll := time.NewTicker(time.Millisecond * time.Duration(c.PruneInterval)).C
+	for {
+		p, err := prune(db, c, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Unable to prune old measurements.")
+			log.Print(err)
+		} else if p != nil {
+			log.Printf("INFO: Pruned %d interactions, %d health heartbeats and %d logs",
+				p.Interactions, p.Healths, p.Logs)
+		}
+
+		<-poll
+	}
+}
+
+// retentionCutoff returns the time that measurements kept for days are deleted before at time t,
+// rounded down to the hour so that whole hours of interactions can still be resummarised. It
+// returns the zero time when they are kept forever.
+func retentionCutoff(days int, t time.Time) time.Time {
+	if days <= 0 {
+		return time.Time{}
+	}
+
+	return t.UTC().Truncate(time.Hour).AddDate(0, 0, -days)
+}
+
+// prune deletes the measurements that are older than their retention at time t within a single
+// transaction, and records what was deleted. Interactions are only deleted once they have been
+// summarised. prune returns nil when there was nothing to delete.
+func prune(db *sql.DB, c configuration.Configuration, t time.Time) (*models.Prune, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Wait for any resummarise that relies on the interactions to finish.
+	err = models.LockPrunes(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := models.Prune{0, t.UTC(), retentionCutoff(c.InteractionRetention, t), 0,
+		retentionCutoff(c.HealthRetention, t), 0, retentionCutoff(c.LogRetention, t), 0}
+
+	if !p.InteractionsBefore.IsZero() {
+		p.Interactions, err = models.PruneInteractions(tx, p.InteractionsBefore)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.HealthsBefore.IsZero() {
+		p.Healths, err = models.PruneScoutHealths(tx, p.HealthsBefore)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.LogsBefore.IsZero() {
+		p.Logs, err = models.PruneScoutLogs(tx, p.LogsBefore)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Interactions == 0 && p.Healths == 0 && p.Logs == 0 {
+		return nil, nil
+	}
+
+	err = p.Insert(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, tx.Commit()
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/configuration"
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "prune process Suite")
+}
+
+var _ = Describe("Prune", func() {
+	AfterEach(cleaner)
+
+	t := time.Date(2016, 5, 12, 10, 30, 0, 0, time.UTC)
+	c := configuration.Configuration{"mtf", "", "mothership", "mothership_test", ":80", "public", 60000, 5000, 90, 30, 0, 3600000}
+
+	scout := func() *models.Scout {
+		s := models.Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+			State: "measuring", Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+			ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+			SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+			MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+			CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+			DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+		err := s.Insert(db)
+		Ω(err).Should(BeNil())
+
+		return &s
+	}
+
+	Context("retentionCutoff", func() {
+		It("should keep measurements forever without a retention", func() {
+			Ω(retentionCutoff(0, t).IsZero()).Should(BeTrue())
+		})
+
+		It("should round the cutoff down to the hour", func() {
+			Ω(retentionCutoff(30, t)).Should(Equal(time.Date(2016, 4, 12, 10, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Context("prune", func() {
+		It("should delete and record the measurements older than their retention", func() {
+			s := scout()
+			cutoff := t.Truncate(time.Hour).AddDate(0, 0, -90)
+
+			for _, et := range []time.Time{cutoff.Add(-time.Hour), cutoff.Add(time.Hour)} {
+				si := models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+					models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, true, et}
+				err := si.Insert(db)
+				Ω(err).Should(BeNil())
+			}
+
+			// Old interactions are kept until they have been summarised.
+			si := models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, cutoff.Add(-time.Hour)}
+			err := si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			sh := models.ScoutHealth{s.UUID, 0.1, 0.2, 0.3, 0.4, t.AddDate(0, 0, -31)}
+			err = sh.Insert(db)
+			Ω(err).Should(BeNil())
+
+			// Logs are kept forever.
+			sl := models.ScoutLog{s.UUID, []byte("abc"), t.AddDate(0, 0, -365)}
+			err = sl.Insert(db)
+			Ω(err).Should(BeNil())
+
+			p, err := prune(db, c, t)
+			Ω(err).Should(BeNil())
+			Ω(p).Should(Equal(&models.Prune{p.Id, t, cutoff, 1, time.Date(2016, 4, 12, 10, 0, 0, 0, time.UTC), 1, time.Time{}, 0}))
+
+			pl, err := models.GetPrunes(db)
+			Ω(err).Should(BeNil())
+			Ω(pl).Should(Equal([]*models.Prune{p}))
+
+			n, err := models.NumScoutInteractions(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(2)))
+
+			n, err = models.NumScoutLogs(db)
+			Ω(err).Should(BeNil())
+			Ω(n).Should(Equal(int64(1)))
+		})
+
+		It("should not record anything when there is nothing to delete", func() {
+			scout()
+
+			p, err := prune(db, c, t)
+			Ω(err).Should(BeNil())
+			Ω(p).Should(BeNil())
+
+			pl, err := models.GetPrunes(db)
+			Ω(err).Should(BeNil())
+			Ω(pl).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,309 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/MeasureTheFuture/scout/models"
+	"time"
+)
+
+// ErrInvalidGrid is returned when resummarising onto a grid that doesn't fit the frame of a scout.
+var ErrInvalidGrid = errors.New("Unable to resummarise, the grid doesn't fit the frame")
+
+// ErrPruned is returned when resummarising onto a new grid after interactions have been pruned.
+var ErrPruned = errors.New("Unable to resummarise onto a new grid, interactions have been pruned")
+
+// ErrPrunedClip is returned when changing how interactions are clipped for noise after
+// interactions have been pruned, as the pruned ones can't be clipped again.
+var ErrPrunedClip = errors.New("Unable to clip the summaries again, interactions have been pruned")
+
+// Resummarise rebuilds the summaries of a scout from the interactions it has already processed,
+// for example after the detection or bucket settings have changed. Only the interactions that
+// were in view between from and to (a zero time leaves that end open) are summarised again, and
+// the period is widened to whole hours. Everything is rebuilt within a single transaction, so the
+// old summaries are swapped for the new ones at once. The all-time summary of the scout is
+// rebuilt from the hourly summaries, so any hours outside the period are kept as they were. A
+// scout without hourly summaries has them backfilled first (see BackfillHourly).
+//
+// When wBuckets or hBuckets are above zero, the summaries are rebuilt on a grid of that many
+// buckets across or down the frame instead of the current grid of the scout. Hours outside the
+// period would no longer match, so a new grid always rebuilds every hour.
+//
+// Hours before interactions were pruned can't be rebuilt, so the period never starts before them
+// and the grid can't be changed once any have been pruned.
+//
+// Resummarise returns the rebuilt all-time summary and the number of interactions it summarised.
+func Resummarise(db *sql.DB, scoutUUID string, from time.Time, to time.Time, wBuckets int,
+	hBuckets int) (*models.ScoutSummary, int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	ss, n, err := resummarise(tx, scoutUUID, from, to, wBuckets, hBuckets)
+	if err != nil {
+		return nil, n, err
+	}
+
+	return ss, n, tx.Commit()
+}
+
+// UpdatePrivacy changes the privacy of a scout to p. Interactions are clipped to the privacy of
+// their scout as they are summarised (see Privacy.Clip), so when p clips them differently every
+// summary of the scout is rebuilt to match, within the same transaction. UpdatePrivacy returns
+// ErrPrunedClip, changing nothing, when that would be needed after interactions have been pruned.
+func UpdatePrivacy(db *sql.DB, scoutUUID string, p models.Privacy) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Hold the summary of the scout, so it isn't updated by Summarise with the old privacy.
+	_, err = models.GetScoutSummaryForUpdate(tx, scoutUUID)
+	if err != nil {
+		return err
+	}
+
+	old, err := models.GetScoutPrivacy(tx, scoutUUID)
+	if err != nil {
+		return err
+	}
+
+	err = models.UpdateScoutPrivacy(tx, scoutUUID, p)
+	if err != nil {
+		return err
+	}
+
+	oldT, oldB := old.Clip()
+	newT, newB := p.Clip()
+	if oldT == newT && oldB == newB {
+		return tx.Commit()
+	}
+
+	pruned, err := models.GetInteractionsPrunedBefore(tx)
+	if err != nil {
+		return err
+	}
+
+	if !pruned.IsZero() {
+		return ErrPrunedClip
+	}
+
+	_, _, err = resummarise(tx, scoutUUID, time.Time{}, time.Time{}, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// resummarise is Resummarise within the transaction tx, which is left for the caller to commit.
+func resummarise(tx *sql.Tx, scoutUUID string, from time.Time, to time.Time, wBuckets int,
+	hBuckets int) (*models.ScoutSummary, int, error) {
+	// Hold the summary of the scout, so it isn't updated by Summarise while it is rebuilt.
+	_, err := models.GetScoutSummaryForUpdate(tx, scoutUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := models.GetScoutFrame(tx, scoutUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	p, err := models.GetScoutPrivacy(tx, scoutUUID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = backfillHourly(tx, scoutUUID, f, p)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pruned, err := models.GetInteractionsPrunedBefore(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	grid := f
+	if wBuckets > 0 {
+		grid.WBuckets = wBuckets
+	}
+	if hBuckets > 0 {
+		grid.HBuckets = hBuckets
+	}
+
+	if grid != f {
+		if !grid.Valid() {
+			return nil, 0, ErrInvalidGrid
+		}
+
+		if !pruned.IsZero() {
+			return nil, 0, ErrPruned
+		}
+
+		err = models.UpdateScoutGrid(tx, scoutUUID, grid.WBuckets, grid.HBuckets)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		f = grid
+		from, to = time.Time{}, time.Time{}
+	}
+
+	from = from.UTC().Truncate(time.Hour)
+	if from.Before(pruned) {
+		// Prunes are rounded to the hour, and keep every interaction in view after them.
+		from = pruned
+	}
+	if to.IsZero() {
+		// Widened to the end of the hour in progress below.
+		to = time.Now()
+	}
+	to = to.UTC()
+	if t := to.Truncate(time.Hour); t.Before(to) {
+		to = t.Add(time.Hour)
+	}
+
+	err = models.DeleteHourlySummariesBetween(tx, scoutUUID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = models.ClearZoneVisits(tx, scoutUUID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = models.ClearTripwireCounts(tx, scoutUUID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	interactions, err := models.GetSummarisedInteractions(tx, scoutUUID, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n := 0
+	for _, si := range interactions {
+		// Interactions that entered before the period may still cross tripwires within it.
+		err = updateTripwires(tx, si, from, to)
+		if err != nil {
+			return nil, n, err
+		}
+
+		// They may also have spent time within it.
+		err = updateHourly(tx, si, f, p, from, to)
+		if err != nil {
+			return nil, n, err
+		}
+
+		if si.EnteredAt.Before(from) {
+			continue
+		}
+
+		err = updateZones(tx, si)
+		if err != nil {
+			return nil, n, err
+		}
+
+		err = updateMetrics(tx, si, f)
+		if err != nil {
+			return nil, n, err
+		}
+		n++
+	}
+
+	ss, err := models.GetHeatmap(tx, scoutUUID, models.HeatmapQuery{})
+	if err != nil {
+		return nil, n, err
+	}
+
+	err = ss.Update(tx)
+	if err != nil {
+		return nil, n, err
+	}
+
+	return ss, n, nil
+}
+
+// BackfillHourly builds the hourly summaries of a scout that was upgraded from before they were
+// kept, from the interactions it has already summarised. The all-time summary of the scout is
+// left as it is, and nothing is done if the scout already has hourly summaries. BackfillHourly
+// returns the number of interactions it summarised.
+func BackfillHourly(db *sql.DB, scoutUUID string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Hold the summary of the scout, so Summarise doesn't add hours while they are backfilled.
+	_, err = models.GetScoutSummaryForUpdate(tx, scoutUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := models.GetScoutFrame(tx, scoutUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	p, err := models.GetScoutPrivacy(tx, scoutUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := backfillHourly(tx, scoutUUID, f, p)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+// backfillHourly summarises every processed interaction of a scout into the hourly summaries
+// on the grid of frame f and clipped to the privacy p, if the scout has none.
+func backfillHourly(tx models.Queryer, scoutUUID string, f models.Frame, p models.Privacy) (int, error) {
+	c, err := models.NumHourlySummaries(tx, scoutUUID)
+	if err != nil || c > 0 {
+		return 0, err
+	}
+
+	// Interactions stored before then entered at a time rounded to the nearest 15 minutes, which
+	// can be slightly ahead of now.
+	interactions, err := models.GetSummarisedInteractions(tx, scoutUUID, time.Time{},
+		time.Now().Add(time.Hour))
+	if err != nil {
+		return 0, err
+	}
+
+	for k, si := range interactions {
+		err = updateHourly(tx, si, f, p, time.Time{}, time.Time{})
+		if err != nil {
+			return k, err
+		}
+	}
+
+	return len(interactions), nil
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestResummarise(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "resummarise process Suite")
+}
+
+var _ = Describe("Resummarise", func() {
+	AfterEach(cleaner)
+
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	// measure inserts an interaction crossing the door of the scout s at each of the times in
+	// entered, and summarises them.
+	measure := func(s *models.Scout, entered ...time.Time) {
+		for _, et := range entered {
+			si := &models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, et}
+			err := si.Insert(db)
+			Ω(err).Should(BeNil())
+		}
+
+		updateUnprocessed(db)
+	}
+
+	scout := func() *models.Scout {
+		s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b", IpAddress: "192.168.0.1",
+			Port: 8080, Authorised: true, Name: "foo", State: "calibrating", Summary: &models.ScoutSummary{},
+			MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2,
+			MogThreshold: 2.0, SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3,
+			ResumeSqDistance: 1, MaxArea: 4.0, MatchStrategy: "optimal", GateSqDistance: 40000,
+			ProcessNoise: 10000.0, MeasurementNoise: 100.0, CentroidWeight: 1.0, Masks: models.Masks{},
+			MaskCoverage: 0.5, DwellSqDistance: 400, DwellDuration: 5.0, Frame: models.DefaultFrame,
+			Privacy: models.DefaultPrivacy}
+		err := s.Insert(db)
+		Ω(err).Should(BeNil())
+
+		tw := models.Tripwire{-1, s.UUID, "Door", models.Path{[2]int{150, 0}, [2]int{150, 300}}, 0, 0}
+		err = tw.Insert(db)
+		Ω(err).Should(BeNil())
+
+		z := models.Zone{-1, s.UUID, "Desk", models.Path{[2]int{0, 0}, [2]int{100, 0}, [2]int{100, 300}, [2]int{0, 300}}, 0, 0.0, 0.0}
+		err = z.Insert(db)
+		Ω(err).Should(BeNil())
+
+		return &s
+	}
+
+	It("should rebuild the summaries of a scout from its interactions", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		before, err := models.GetScoutSummaryByUUID(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(before.VisitorCount).Should(Equal(int64(3)))
+
+		// Throw away the summaries, as if they had been built with different settings.
+		err = before.Clear(db)
+		Ω(err).Should(BeNil())
+		err = models.ClearTripwires(db, s.UUID)
+		Ω(err).Should(BeNil())
+		err = models.ClearZones(db, s.UUID)
+		Ω(err).Should(BeNil())
+
+		ss, n, err := Resummarise(db, s.UUID, time.Time{}, time.Time{}, 0, 0)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(3))
+
+		after, err := models.GetScoutSummaryByUUID(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(after).Should(Equal(ss))
+		Ω(after.VisitorCount).Should(Equal(int64(3)))
+		Ω(after.VisitorBuckets).ShouldNot(Equal(models.NewIntBuckets(20, 20)))
+
+		tl, err := models.GetTripwires(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(tl[0].InCount + tl[0].OutCount).Should(Equal(int64(3)))
+
+		zl, err := models.GetZones(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(zl[0].VisitorCount).Should(Equal(int64(3)))
+	})
+
+	It("should only rebuild the interactions that entered within the period", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		// The period is widened to whole hours, taking in the interactions at t and an hour later.
+		_, n, err := Resummarise(db, s.UUID, t.Add(30*time.Minute), t.Add(90*time.Minute), 0, 0)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(2))
+
+		// Rebuilding a period doesn't count its interactions twice, or lose the others.
+		ss, err := models.GetScoutSummaryByUUID(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(ss.VisitorCount).Should(Equal(int64(3)))
+
+		hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+		Ω(err).Should(BeNil())
+		Ω(len(hl)).Should(Equal(3))
+
+		tl, err := models.GetTripwires(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(tl[0].InCount + tl[0].OutCount).Should(Equal(int64(3)))
+
+		zl, err := models.GetZones(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(zl[0].VisitorCount).Should(Equal(int64(3)))
+	})
+
+	It("should backfill the hours of scouts upgraded from before hourly summaries were kept", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		// Upgraded scouts have an all-time summary, but no hourly summaries.
+		_, err := db.Exec(`DELETE FROM hourly_summaries`)
+		Ω(err).Should(BeNil())
+
+		// Rebuilding a period keeps the interactions outside it.
+		ss, n, err := Resummarise(db, s.UUID, t, t.Add(time.Hour), 0, 0)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(1))
+		Ω(ss.VisitorCount).Should(Equal(int64(3)))
+
+		hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+		Ω(err).Should(BeNil())
+		Ω(len(hl)).Should(Equal(3))
+	})
+
+	It("should only backfill scouts without hourly summaries", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		n, err := BackfillHourly(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(0))
+
+		_, err = db.Exec(`DELETE FROM hourly_summaries`)
+		Ω(err).Should(BeNil())
+
+		n, err = BackfillHourly(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(3))
+
+		before, err := models.GetScoutSummaryByUUID(db, s.UUID)
+		Ω(err).Should(BeNil())
+		h, err := models.GetHeatmap(db, s.UUID, models.HeatmapQuery{})
+		Ω(err).Should(BeNil())
+		Ω(h).Should(Equal(before))
+	})
+
+	It("should rebuild every hour on a new grid", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		// A new grid rebuilds every hour, even when a period is given.
+		ss, n, err := Resummarise(db, s.UUID, t, t.Add(time.Hour), 40, 10)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(3))
+		Ω(ss.VisitorCount).Should(Equal(int64(3)))
+		w, h := ss.Grid()
+		Ω([]int{w, h}).Should(Equal([]int{40, 10}))
+
+		f, err := models.GetScoutFrame(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(f).Should(Equal(models.Frame{1280, 720, 40, 10}))
+
+		hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+		Ω(err).Should(BeNil())
+		for _, hs := range hl {
+			w, h = hs.Grid()
+			Ω([]int{w, h}).Should(Equal([]int{40, 10}))
+		}
+
+		_, _, err = Resummarise(db, s.UUID, time.Time{}, time.Time{}, 2000, 0)
+		Ω(err).Should(Equal(ErrInvalidGrid))
+	})
+
+	It("should keep the hours before interactions were pruned", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		before := t.Add(2 * time.Hour)
+		n, err := models.PruneInteractions(db, before)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(Equal(int64(2)))
+
+		p := models.Prune{-1, time.Now(), before, n, time.Time{}, 0, time.Time{}, 0}
+		err = p.Insert(db)
+		Ω(err).Should(BeNil())
+
+		// Only the interaction that was kept is rebuilt, without losing the pruned ones.
+		ss, m, err := Resummarise(db, s.UUID, time.Time{}, time.Time{}, 0, 0)
+		Ω(err).Should(BeNil())
+		Ω(m).Should(Equal(1))
+		Ω(ss.VisitorCount).Should(Equal(int64(3)))
+
+		hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+		Ω(err).Should(BeNil())
+		Ω(len(hl)).Should(Equal(3))
+
+		tl, err := models.GetTripwires(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(tl[0].InCount + tl[0].OutCount).Should(Equal(int64(3)))
+
+		_, _, err = Resummarise(db, s.UUID, time.Time{}, time.Time{}, 40, 10)
+		Ω(err).Should(Equal(ErrPruned))
+	})
+
+	It("should clip the summaries again when the noise changes", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		// Each interaction takes three seconds, which is clipped to a second once noise is added.
+		p := models.Privacy{0, 15, 1.0, 10.0, 1.0, 20}
+		err := UpdatePrivacy(db, s.UUID, p)
+		Ω(err).Should(BeNil())
+
+		ss, err := models.GetScoutSummaryByUUID(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(ss.VisitorCount).Should(Equal(int64(3)))
+		total := 0.0
+		for i := range ss.VisitTimeBuckets {
+			for j := range ss.VisitTimeBuckets[i] {
+				total += float64(ss.VisitTimeBuckets[i][j])
+			}
+		}
+		Ω(total).Should(BeNumerically("~", 3.0, 0.001))
+
+		np, err := models.GetScoutPrivacy(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(np).Should(Equal(p))
+	})
+
+	It("should refuse to clip the summaries again once interactions have been pruned", func() {
+		s := scout()
+		measure(s, t, t.Add(time.Hour), t.Add(24*time.Hour))
+
+		pr := models.Prune{-1, time.Now(), t.Add(2 * time.Hour), 2, time.Time{}, 0, time.Time{}, 0}
+		err := pr.Insert(db)
+		Ω(err).Should(BeNil())
+
+		// Changes that don't clip differently are still allowed.
+		p := s.Privacy
+		p.MinVisitors = 5
+		err = UpdatePrivacy(db, s.UUID, p)
+		Ω(err).Should(BeNil())
+
+		p.Epsilon = 1.0
+		p.Budget = 10.0
+		err = UpdatePrivacy(db, s.UUID, p)
+		Ω(err).Should(Equal(ErrPrunedClip))
+
+		np, err := models.GetScoutPrivacy(db, s.UUID)
+		Ω(err).Should(BeNil())
+		Ω(np.MinVisitors).Should(Equal(5))
+		Ω(np.Epsilon).Should(Equal(0.0))
+	})
+})
@@ -19,109 +19,333 @@ package processes
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/MeasureTheFuture/scout/configuration"
 	"github.com/MeasureTheFuture/scout/models"
 	"github.com/MeasureTheFuture/scout/vec"
+	"github.com/lib/pq"
 	"log"
+	"math"
 	"time"
 )
 
+// Summarise updates the interaction summaries whenever an interaction is inserted. In case a
+// notification is missed, it also checks for interactions to summarise every SummariseInterval
+// milliseconds.
 func Summarise(db *sql.DB, c configuration.Configuration) {
+	l := pq.NewListener(configuration.DBConnection(c, c.DBName), 10*time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("ERROR: Summarise unable to listen for scout interactions.")
+				log.Print(err)
+			}
+		})
+	defer l.Close()
+
+	err := l.Listen(models.InteractionsChannel)
+	if err != nil {
+		log.Printf("ERROR: Summarise unable to listen for scout interactions.")
+		log.Print(err)
+	}
+
 	poll := time.NewTicker(time.Millisecond * time.Duration(c.SummariseInterval)).C
+	updateUnprocessed(db)
 
 	for {
+		// A nil notification means the listener reconnected, and may have missed some.
 		select {
+		case <-l.Notify:
+			updateUnprocessed(db)
 		case <-poll:
 			updateUnprocessed(db)
 		}
 	}
 }
 
-func updateUnprocessed(db *sql.DB) {
-	up, err := models.GetUnprocessed(db)
-	if err != nil {
-		log.Printf("ERROR: Summarise unable to get unprocessed scout interactions.")
-		log.Print(err)
-		return
-	}
+// maxSummariseAttempts is the number of times summarising an interaction may fail before it is
+// skipped for good.
+const maxSummariseAttempts = 3
 
-	for _, si := range up {
-		ss, err := models.GetScoutSummaryByUUID(db, si.ScoutUUID)
+// updateUnprocessed summarises every interaction that has not yet been summarised. Interactions
+// that fail are not retried until the next call.
+func updateUnprocessed(db *sql.DB) {
+	since := time.Now()
+	for {
+		more, err := summariseNext(db, since)
 		if err != nil {
-			log.Printf("ERROR: Summarise unable to get scout summary")
+			log.Printf("ERROR: Summarise unable to get unprocessed scout interactions.")
 			log.Print(err)
 			return
 		}
 
-		ss.VisitorCount += 1
-		updateTimeBuckets(db, ss, si)
+		if !more {
+			return
+		}
+	}
+}
+
+// summariseNext summarises the oldest unprocessed interaction in its own transaction, so that a
+// crash leaves it either fully summarised or untouched. An interaction that can't be summarised
+// is rolled back and recorded as a failure rather than stopping the interactions behind it.
+// summariseNext returns false when there is nothing left to summarise.
+func summariseNext(db *sql.DB, since time.Time) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
 
-		err = ss.Update(db)
+	si, err := models.NextUnprocessed(tx, maxSummariseAttempts, since)
+	if err == sql.ErrNoRows {
+		return false, tx.Rollback()
+	} else if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = summarise(tx, si)
+	if err == nil {
+		return true, tx.Commit()
+	}
+
+	log.Printf("ERROR: Summarise unable to summarise scout interaction %d", si.Id)
+	log.Print(err)
+	tx.Rollback()
+
+	return true, models.RecordSummariseFailure(db, si, err, time.Now())
+}
+
+// summarise adds the interaction si to the summaries of its scout and marks it as processed.
+func summarise(tx models.Queryer, si *models.ScoutInteraction) error {
+	f, err := models.GetScoutFrame(tx, si.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	p, err := models.GetScoutPrivacy(tx, si.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	ss, err := models.GetScoutSummaryForUpdate(tx, si.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	if w, h := ss.Grid(); w != f.WBuckets || h != f.HBuckets {
+		return errors.New("The summary doesn't match the grid of the scout, resummarise it")
+	}
+
+	ss.VisitorCount += 1
+	updateTimeBuckets(ss, si, f, p)
+
+	err = ss.Update(tx)
+	if err != nil {
+		return err
+	}
+
+	err = updateHourly(tx, si, f, p, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	err = updateZones(tx, si)
+	if err != nil {
+		return err
+	}
+
+	err = updateTripwires(tx, si, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	err = updateMetrics(tx, si, f)
+	if err != nil {
+		return err
+	}
+
+	return si.MarkProcessed(tx)
+}
+
+// updateHourly adds the interaction si to the summaries of the hours it spent in the scene, using
+// the frame f and privacy p of the scout. The visitor is counted in the hour they entered, and the
+// time spent in view is shared between the hours it was spent in. Only the hours between from and
+// to are updated, a zero time leaves that end open.
+func updateHourly(db models.Queryer, si *models.ScoutInteraction, f models.Frame, p models.Privacy,
+	from time.Time, to time.Time) error {
+	first := si.EnteredAt.UTC().Truncate(time.Hour)
+	last := first
+	if n := len(si.WaypointTimes); n > 0 {
+		last = si.EnteredAt.Add(seconds(si.WaypointTimes[n-1])).UTC().Truncate(time.Hour)
+	}
+
+	hours := map[time.Time]*models.HourlySummary{}
+	for hr := first; !hr.After(last); hr = hr.Add(time.Hour) {
+		if (!from.IsZero() && hr.Before(from)) || (!to.IsZero() && !hr.Before(to)) {
+			continue
+		}
+
+		hs, err := models.GetHourlySummary(db, si.ScoutUUID, hr, f)
 		if err != nil {
-			log.Printf("ERROR: Summarise unable to update scout summary")
-			log.Print(err)
+			return err
+		}
+
+		if w, h := hs.Grid(); w != f.WBuckets || h != f.HBuckets {
+			return errors.New("The hourly summary doesn't match the grid of the scout, resummarise it")
+		}
+		hours[hr] = hs
+	}
+
+	if hs, ok := hours[first]; ok {
+		hs.VisitorCount += 1
+	}
+
+	addTimeBuckets(si, f, p, time.Hour, func(hr time.Time) *models.ScoutSummary {
+		if hs, ok := hours[hr]; ok {
+			return &hs.ScoutSummary
 		}
 
-		err = si.MarkProcessed(db)
+		return nil
+	})
+
+	for _, hs := range hours {
+		err := hs.Save(db)
 		if err != nil {
-			log.Printf("ERROR: Summarise unable to make scout interaction as processed")
-			log.Print(err)
-			return
+			return err
 		}
 	}
+
+	return nil
 }
 
-func maxTravelTime(a models.Waypoint, b models.Waypoint) float32 {
-	travelD := vec.Vec{(b.XPixels - a.XPixels), (b.YPixels - a.YPixels)}
-	travelG := float32(travelD[1]) / float32(travelD[0])
+// seconds converts the number of seconds t into a duration.
+func seconds(t float32) time.Duration {
+	return time.Duration(float64(t) * float64(time.Second))
+}
+
+// bucketShares works out how the time taken by an interaction to travel from a to b is shared
+// between the buckets of frame f, in proportion to how much of each bucket is covered by the
+// area the interaction swept across. It calls share with each bucket touched by the swept area
+// and its share, the shares of the buckets within the frame add up to one.
+func bucketShares(a models.Waypoint, b models.Waypoint, f models.Frame, share func(i int, j int, s float64)) {
+	frame := vec.AABB{vec.Vec{0, 0}, vec.Vec{f.Width, f.Height}}
+	swept := vec.SweptPolygon(a, b)
+	total := swept.OverlapArea(&frame)
+	pa := vec.Vec{a.XPixels, a.YPixels}
+	pb := vec.Vec{b.XPixels, b.YPixels}
+
+	swept.Buckets(f.BucketW(), f.BucketH(), f.WBuckets, f.HBuckets, func(i int, j int) {
+		bucket := vec.AABBFromIndex(i, j, f.BucketW(), f.BucketH())
+		if total > 0.0 {
+			share(i, j, swept.OverlapArea(&bucket)/total)
+			return
+		}
+
+		// The interaction has no size, so share the time along the path it took instead.
+		if pa == pb {
+			if pa[0]/f.BucketW() == i && pa[1]/f.BucketH() == j {
+				share(i, j, 1.0)
+			}
+			return
+		}
 
-	x := float32(configuration.FrameW) / float32(configuration.WBuckets)
-	y := x * travelG
-	bucketD := vec.Vec{int(x), int(y)}
+		box := vec.Polygon{bucket.Min, vec.Vec{bucket.Max[0], bucket.Min[1]}, bucket.Max,
+			vec.Vec{bucket.Min[0], bucket.Max[1]}}
+		s := 0.0
+		for _, inside := range box.ClipSegment(pa, pb) {
+			s += inside[1] - inside[0]
+		}
+		share(i, j, s)
+	})
+}
 
-	// Make sure that we don't overallocate time for the bucket, when the
-	// travelD is shorter than the bucket itself, the maximum multiplication
-	// value for the maxTravel time is 1.0.
-	f := vec.MinF(float32(1.0), float32(bucketD.Length()/travelD.Length()))
-	return (b.T - a.T) * f
+// updateTimeBuckets adds the time the interaction si spent in each bucket to the summary ss,
+// along with a visitor to each bucket it passed through, clipped to the privacy p. The buckets of
+// ss break up a frame the size of f.
+func updateTimeBuckets(ss *models.ScoutSummary, si *models.ScoutInteraction, f models.Frame,
+	p models.Privacy) {
+	f.WBuckets, f.HBuckets = ss.Grid()
+	addTimeBuckets(si, f, p, 0, func(time.Time) *models.ScoutSummary {
+		return ss
+	})
 }
 
-func updateTimeBuckets(db *sql.DB, ss *models.ScoutSummary, si *models.ScoutInteraction) {
-	var intersected [configuration.HBuckets][configuration.WBuckets]bool
+// addTimeBuckets adds the time the interaction si spent in each bucket of the frame f to the
+// summary of the period it was spent in, along with a visitor to the period they first passed
+// through each bucket. The periods are split long, and summary returns the summary of the period
+// starting at its argument, or nil to leave that period out. A split of zero puts everything in
+// a single period.
+//
+// While the privacy p adds noise, the time of a long interaction is scaled down to the time
+// sensitivity of p, and the visitor is only counted in the first buckets they reached, up to the
+// bucket sensitivity of p.
+func addTimeBuckets(si *models.ScoutInteraction, f models.Frame, p models.Privacy, split time.Duration,
+	summary func(period time.Time) *models.ScoutSummary) {
+	if !f.Valid() {
+		return
+	}
+	visited := models.NewIntBuckets(f.WBuckets, f.HBuckets)
 
-	// For each segment in an interaction.
-	for k := 0; k < (len(si.Waypoints) - 1); k++ {
-		// Generate a shaft AABB from the two waypoints.
-		wpA := models.Waypoint{si.Waypoints[k][0], si.Waypoints[k][1],
-			si.WaypointWidths[k][0], si.WaypointWidths[k][1], si.WaypointTimes[k]}
-		wpB := models.Waypoint{si.Waypoints[k+1][0], si.Waypoints[k+1][1],
-			si.WaypointWidths[k+1][0], si.WaypointWidths[k+1][1], si.WaypointTimes[k+1]}
-
-		s := vec.ShaftFromWaypoints(wpA, wpB, configuration.FrameW, configuration.FrameH)
-
-		// Work out maximum travel time that can be spent in a bucket.
-		mt := maxTravelTime(wpA, wpB)
-
-		// For each of the buckets, see if it intersects the shaft AABB and if it does
-		// increment the bucket time by the maximum travel time.
-		for i := 0; i < configuration.WBuckets; i++ {
-			for j := 0; j < configuration.HBuckets; j++ {
-				bucket := vec.AABBFromIndex(i, j, configuration.BucketW, configuration.BucketH)
-				// TODO: Possibly improve time estimate by working out how much of
-				// the bucket overlaps the shaft. Use it as a ratio between 0 and 1
-				// to multiply max time.
-				//
-				// At the moment we allocate mt (the maximum possible travel time)
-				// to the bucket once per interaction. Even if more than one segment
-				// intersects this bucket.
-				if s.Intersects(&bucket) {
-					if !intersected[i][j] {
-						ss.VisitTimeBuckets[i][j] += mt
-						ss.VisitorBuckets[i][j] += 1
-						intersected[i][j] = true
-					}
+	maxT, maxB := p.Clip()
+	scale := 1.0
+	if n := len(si.WaypointTimes); maxT > 0.0 && n > 0 {
+		if total := float64(si.WaypointTimes[n-1] - si.WaypointTimes[0]); total > maxT {
+			scale = maxT / total
+		}
+	}
+	counted := 0
+
+	// Share the time between the buckets the part covers. A bucket crossed by more than one part
+	// gets the time from each of them, but the visitor is only counted once.
+	add := func(ss *models.ScoutSummary, wpA models.Waypoint, wpB models.Waypoint) {
+		dt := float64(wpB.T-wpA.T) * scale
+
+		bucketShares(wpA, wpB, f, func(i int, j int, s float64) {
+			if s <= 0.0 {
+				return
+			}
+
+			if ss != nil {
+				ss.VisitTimeBuckets[i][j] += float32(s * dt)
+			}
+			if visited[i][j] == 0 {
+				if ss != nil && (maxB == 0 || counted < maxB) {
+					ss.VisitorBuckets[i][j] += 1
 				}
+				visited[i][j] = 1
+				counted++
+			}
+		})
+	}
+
+	// Break each segment of the interaction where it crosses from one period into the next.
+	offset := func(t time.Time) float32 {
+		return float32(t.Sub(si.EnteredAt).Seconds())
+	}
+	for k := 0; k < (len(si.Waypoints) - 1); k++ {
+		wpA := waypoint(si, k)
+		wpB := waypoint(si, k+1)
+
+		var period time.Time
+		if split > 0 {
+			period = si.EnteredAt.Add(seconds(wpA.T)).UTC().Truncate(split)
+			for next := period.Add(split); offset(next) < wpB.T; next = next.Add(split) {
+				mid := between(wpA, wpB, offset(next))
+				add(summary(period), wpA, mid)
+				wpA, period = mid, next
 			}
 		}
+
+		add(summary(period), wpA, wpB)
+	}
+}
+
+// between returns the waypoint at t seconds into the interaction, on the way from a to b.
+func between(a models.Waypoint, b models.Waypoint, t float32) models.Waypoint {
+	s := float64(t-a.T) / float64(b.T-a.T)
+	lerp := func(p int, q int) int {
+		return p + int(math.Floor(s*float64(q-p)+0.5))
 	}
+
+	return models.Waypoint{lerp(a.XPixels, b.XPixels), lerp(a.YPixels, b.YPixels),
+		lerp(a.HalfWidthPixels, b.HalfWidthPixels), lerp(a.HalfHeightPixels, b.HalfHeightPixels), t}
 }
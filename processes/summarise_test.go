@@ -19,10 +19,13 @@ package processes
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/MeasureTheFuture/scout/configuration"
 	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"math/rand"
 	"os"
 	"testing"
 	"time"
@@ -40,16 +43,129 @@ func cleaner() {
 	_, err = db.Exec(`DELETE FROM scout_interactions`)
 	Ω(err).Should(BeNil())
 
+	_, err = db.Exec(`DELETE FROM prunes`)
+	Ω(err).Should(BeNil())
+
 	_, err = db.Exec(`DELETE FROM scout_logs`)
 	Ω(err).Should(BeNil())
 
 	_, err = db.Exec(`DELETE FROM scout_healths`)
 	Ω(err).Should(BeNil())
 
+	_, err = db.Exec(`DELETE FROM tripwire_counts`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM tripwires`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zone_visits`)
+	Ω(err).Should(BeNil())
+
+	_, err = db.Exec(`DELETE FROM zones`)
+	Ω(err).Should(BeNil())
+
 	_, err = db.Exec(`DELETE FROM scouts`)
 	Ω(err).Should(BeNil())
 }
 
+// shareGrid collects the shares of each bucket from bucketShares into a grid.
+func shareGrid(a models.Waypoint, b models.Waypoint, f models.Frame) [][]float64 {
+	result := make([][]float64, f.WBuckets)
+	for i := range result {
+		result[i] = make([]float64, f.HBuckets)
+	}
+
+	bucketShares(a, b, f, func(i int, j int, s float64) {
+		result[i][j] += s
+	})
+
+	return result
+}
+
+// testedShares works out the share of every bucket by testing each of them in turn, which is
+// what bucketShares did before it rasterised the swept area.
+func testedShares(a models.Waypoint, b models.Waypoint, f models.Frame) [][]float64 {
+	result := make([][]float64, f.WBuckets)
+	frame := vec.AABB{vec.Vec{0, 0}, vec.Vec{f.Width, f.Height}}
+	swept := vec.SweptPolygon(a, b)
+	total := swept.OverlapArea(&frame)
+
+	for i := range result {
+		result[i] = make([]float64, f.HBuckets)
+		for j := range result[i] {
+			bucket := vec.AABBFromIndex(i, j, f.BucketW(), f.BucketH())
+			if total > 0.0 {
+				result[i][j] = swept.OverlapArea(&bucket) / total
+				continue
+			}
+
+			pa := vec.Vec{a.XPixels, a.YPixels}
+			pb := vec.Vec{b.XPixels, b.YPixels}
+			if pa == pb {
+				if pa[0]/f.BucketW() == i && pa[1]/f.BucketH() == j {
+					result[i][j] = 1.0
+				}
+				continue
+			}
+
+			box := vec.Polygon{bucket.Min, vec.Vec{bucket.Max[0], bucket.Min[1]}, bucket.Max,
+				vec.Vec{bucket.Min[0], bucket.Max[1]}}
+			for _, inside := range box.ClipSegment(pa, pb) {
+				result[i][j] += inside[1] - inside[0]
+			}
+		}
+	}
+
+	return result
+}
+
+// randomWaypoint returns a waypoint somewhere in (or just off) the frame f.
+func randomWaypoint(r *rand.Rand, f models.Frame, maxHalfSize int) models.Waypoint {
+	return models.Waypoint{r.Intn(f.Width+100) - 50, r.Intn(f.Height+100) - 50,
+		r.Intn(maxHalfSize + 1), r.Intn(maxHalfSize + 1), 0.0}
+}
+
+// walk returns an interaction that wanders across the frame f in n steps.
+func walk(f models.Frame, n int) *models.ScoutInteraction {
+	r := rand.New(rand.NewSource(1))
+	si := &models.ScoutInteraction{-1, "", float32(n), models.Path{}, models.Path{}, models.RealArray{},
+		false, time.Time{}}
+
+	x, y := f.Width/2, f.Height/2
+	for k := 0; k < n; k++ {
+		x = vec.Max(0, vec.Min(f.Width, x+r.Intn(41)-20))
+		y = vec.Max(0, vec.Min(f.Height, y+r.Intn(41)-20))
+		si.Waypoints = append(si.Waypoints, [2]int{x, y})
+		si.WaypointWidths = append(si.WaypointWidths, [2]int{40 + r.Intn(10), 90 + r.Intn(20)})
+		si.WaypointTimes = append(si.WaypointTimes, float32(k)*0.2)
+	}
+
+	return si
+}
+
+// BenchmarkUpdateTimeBuckets compares summarising a long interaction against testing every
+// bucket for each segment, on grids from the default to one bucket per 10 pixels.
+func BenchmarkUpdateTimeBuckets(b *testing.B) {
+	for _, f := range []models.Frame{models.DefaultFrame, models.Frame{1280, 720, 64, 36},
+		models.Frame{1280, 720, 128, 72}} {
+		si := walk(f, 300)
+
+		b.Run(fmt.Sprintf("%dx%d/rasterised", f.WBuckets, f.HBuckets), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				updateTimeBuckets(models.NewScoutSummary("", f), si, f, models.DefaultPrivacy)
+			}
+		})
+
+		b.Run(fmt.Sprintf("%dx%d/tested", f.WBuckets, f.HBuckets), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for k := 0; k < len(si.Waypoints)-1; k++ {
+					testedShares(waypoint(si, k), waypoint(si, k+1), f)
+				}
+			}
+		})
+	}
+}
+
 func TestSummarise(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "summarise process Suite")
@@ -68,9 +184,14 @@ var _ = Describe("Summarise Process", func() {
 
 	Context("updateUnprocessed", func() {
 		It("should ignore proccessed interactions", func() {
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1", 8080,
-				true, "foo", "calibrating", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -87,9 +208,14 @@ var _ = Describe("Summarise Process", func() {
 		})
 
 		It("should increment the visitor count", func() {
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1", 8080,
-				true, "foo", "calibrating", &models.ScoutSummary{},
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
@@ -117,47 +243,426 @@ var _ = Describe("Summarise Process", func() {
 			Ω(err).Should(BeNil())
 			Ω(si2.Processed).Should(BeTrue())
 		})
-	})
 
-	Context("maxTravelTime", func() {
-		It("should return the max travel time for a bucket", func() {
-			wpA := models.Waypoint{0, 0, 10, 10, 0.0}
-			wpB := models.Waypoint{0, 192, 10, 10, 1.0}
-			wpC := models.Waypoint{0, 25, 10, 10, 1.0}
+		It("should add visits to the zones of the scout", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			z := models.Zone{-1, s.UUID, "Help desk",
+				models.Path{[2]int{100, 100}, [2]int{200, 100}, [2]int{200, 200}, [2]int{100, 200}}, 0, 0.0, 0.0}
+			err = z.Insert(db)
+			Ω(err).Should(BeNil())
 
-			Ω(maxTravelTime(wpA, wpB)).Should(BeNumerically("~", float32(0.33), 0.03))
-			Ω(maxTravelTime(wpA, wpC)).Should(BeNumerically("~", float32(1.00), 0.03))
+			et := time.Now().UTC().Round(15 * time.Minute)
+			si := &models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			updateUnprocessed(db)
+			z2, err := models.GetZoneById(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(z2.VisitorCount).Should(Equal(int64(1)))
+			Ω(z2.DwellTime).Should(BeNumerically("~", float32(1.0), 0.001))
+
+			v, err := models.GetZoneVisits(db, z.Id)
+			Ω(err).Should(BeNil())
+			Ω(len(v)).Should(Equal(1))
+			Ω(v[0].InteractionId).Should(Equal(si.Id))
+			Ω(v[0].FirstEnteredAt).Should(Equal(et.Add(time.Second)))
 		})
-	})
 
-	Context("updateTimeBuckets", func() {
-		PIt("it should update the travel times for the buckets in a scout summary", func() {
-			ss := &models.ScoutSummary{}
-			s := models.Scout{"59ef7180-f6b2-4129-99bf-970eb4312b4b", "192.168.0.1", 8080,
-				true, "foo", "calibrating", ss,
-				2.0, 2, 2, 2, 2, 2.0, 0, 2.0, 0.2, 0.3, 1, 4.0}
+		It("should store the metrics of each interaction", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
 			err := s.Insert(db)
 			Ω(err).Should(BeNil())
 
+			et := time.Now().UTC().Round(15 * time.Minute)
+			si := &models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			updateUnprocessed(db)
+			m, err := models.GetInteractionMetrics(db, s.UUID, models.MetricsQuery{})
+			Ω(err).Should(BeNil())
+			Ω(len(m)).Should(Equal(1))
+			Ω(m[0].InteractionId).Should(Equal(si.Id))
+			Ω(m[0].PathLength).Should(BeNumerically("~", 300.0, 0.001))
+			Ω(m[0].EntryEdge).Should(Equal(models.LEFT))
+		})
+
+		It("should summarise each interaction in the hour it entered", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+			for _, et := range []time.Time{t.Add(15 * time.Minute), t.Add(45 * time.Minute), t.Add(75 * time.Minute)} {
+				si := &models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+					models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, et}
+				err = si.Insert(db)
+				Ω(err).Should(BeNil())
+			}
+
+			updateUnprocessed(db)
+			hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+			Ω(err).Should(BeNil())
+			Ω(len(hl)).Should(Equal(2))
+			Ω(hl[0].Hour).Should(Equal(t))
+			Ω(hl[0].VisitorCount).Should(Equal(int64(2)))
+			Ω(hl[1].Hour).Should(Equal(t.Add(time.Hour)))
+			Ω(hl[1].VisitorCount).Should(Equal(int64(1)))
+
+			ss, err := models.GetScoutSummaryByUUID(db, s.UUID)
+			Ω(err).Should(BeNil())
+			h, err := models.GetHeatmap(db, s.UUID, models.HeatmapQuery{})
+			Ω(err).Should(BeNil())
+			Ω(h).Should(Equal(ss))
+		})
+
+		It("should summarise each interaction in the hour of its exact entry time", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			et := time.Date(2016, 5, 12, 10, 53, 20, 0, time.UTC)
+			si := &models.ScoutInteraction{-1, s.UUID, 3.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{3, 4}, [2]int{3, 4}}, models.RealArray{0.0, 3.0}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			updateUnprocessed(db)
+			hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+			Ω(err).Should(BeNil())
+			Ω(len(hl)).Should(Equal(1))
+			Ω(hl[0].Hour).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+			Ω(hl[0].VisitorCount).Should(Equal(int64(1)))
+		})
+
+		It("should share the time of an interaction between the hours it spent in view", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			et := time.Date(2016, 5, 12, 10, 59, 59, 0, time.UTC)
+			si := &models.ScoutInteraction{-1, s.UUID, 2.0, models.Path{[2]int{32, 18}, [2]int{96, 18}},
+				models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 2.0}, false, et}
+			err = si.Insert(db)
+			Ω(err).Should(BeNil())
+
+			updateUnprocessed(db)
+			hl, err := models.GetHourlySummaries(db, s.UUID, time.Time{}, time.Time{})
+			Ω(err).Should(BeNil())
+			Ω(len(hl)).Should(Equal(2))
+			Ω(hl[0].Hour).Should(Equal(time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)))
+			Ω(hl[0].VisitorCount).Should(Equal(int64(1)))
+			Ω(hl[0].VisitTimeBuckets[0][0]).Should(BeNumerically("~", 1.0, 0.0001))
+			Ω(hl[1].Hour).Should(Equal(time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)))
+			Ω(hl[1].VisitorCount).Should(Equal(int64(0)))
+			Ω(hl[1].VisitTimeBuckets[1][0]).Should(BeNumerically("~", 1.0, 0.0001))
+
+			ss, err := models.GetScoutSummaryByUUID(db, s.UUID)
+			Ω(err).Should(BeNil())
+			h, err := models.GetHeatmap(db, s.UUID, models.HeatmapQuery{})
+			Ω(err).Should(BeNil())
+			Ω(h).Should(Equal(ss))
+		})
+
+		It("should record interactions that can't be summarised and carry on with the rest", func() {
+			s := models.Scout{UUID: "59ef7180-f6b2-4129-99bf-970eb4312b4b",
+				IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err := s.Insert(db)
+			Ω(err).Should(BeNil())
+
+			s2 := models.Scout{UUID: "6a0d2a7e-1c39-4b8e-9d7a-3f5e2b1c8d90",
+				IpAddress: "192.168.0.2", Port: 8080, Authorised: true, Name: "bar", State: "calibrating",
+				Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2, ForegroundThresh: 2,
+				GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0, SimplifyEpsilon: 2.0,
+				MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			err = s2.Insert(db)
+			Ω(err).Should(BeNil())
+
+			// Without a summary, the interactions of the first scout can't be summarised.
+			_, err = db.Exec(`DELETE FROM scout_summaries WHERE scout_uuid = $1`, s.UUID)
+			Ω(err).Should(BeNil())
+
 			et := time.Now().UTC().Round(15 * time.Minute)
 			si := &models.ScoutInteraction{-1, s.UUID, 0.2, models.Path{[2]int{1, 2}},
-				models.Path{[2]int{0, 0}, [2]int{0, 25}}, models.RealArray{0.0, 1.0}, false, et}
+				models.Path{[2]int{3, 4}}, models.RealArray{0.1}, false, et}
 			err = si.Insert(db)
 			Ω(err).Should(BeNil())
 
-			updateTimeBuckets(db, ss, si)
+			si2 := &models.ScoutInteraction{-1, s2.UUID, 0.2, models.Path{[2]int{1, 2}},
+				models.Path{[2]int{3, 4}}, models.RealArray{0.1}, false, et}
+			err = si2.Insert(db)
+			Ω(err).Should(BeNil())
+
+			updateUnprocessed(db)
+			ss, err := models.GetScoutSummaryByUUID(db, s2.UUID)
+			Ω(err).Should(BeNil())
+			Ω(ss.VisitorCount).Should(Equal(int64(1)))
+
+			si, err = models.GetScoutInteractionById(db, si.Id)
+			Ω(err).Should(BeNil())
+			Ω(si.Processed).Should(BeFalse())
 
-			ssA, err := models.GetScoutSummaryByUUID(db, s.UUID)
+			fl, err := models.GetSummariseFailures(db, s.UUID)
 			Ω(err).Should(BeNil())
-			Ω(ssA.VisitorCount).Should(Equal(int64(1)))
+			Ω(len(fl)).Should(Equal(1))
+			Ω(fl[0].InteractionId).Should(Equal(si.Id))
+			Ω(fl[0].Attempts).Should(Equal(int64(1)))
+
+			updateUnprocessed(db)
+			fl, err = models.GetSummariseFailures(db, s.UUID)
+			Ω(err).Should(BeNil())
+			Ω(fl[0].Attempts).Should(Equal(int64(2)))
+		})
+	})
+
+	Context("bucketShares", func() {
+		It("should share the time of a segment in proportion to the area swept over each bucket", func() {
+			// Moves diagonally from bucket (0, 0) to bucket (1, 1), sweeping a hexagon that covers
+			// all of both buckets, along with half of buckets (1, 0) and (0, 1).
+			shares := shareGrid(models.Waypoint{32, 18, 32, 18, 0.0}, models.Waypoint{96, 54, 32, 18, 3.0}, models.DefaultFrame)
+
+			Ω(shares[0][0]).Should(BeNumerically("~", 1.0/3.0, 0.0001))
+			Ω(shares[1][1]).Should(BeNumerically("~", 1.0/3.0, 0.0001))
+			Ω(shares[1][0]).Should(BeNumerically("~", 1.0/6.0, 0.0001))
+			Ω(shares[0][1]).Should(BeNumerically("~", 1.0/6.0, 0.0001))
+			Ω(shares[2][0]).Should(Equal(0.0))
+			Ω(shares[0][2]).Should(Equal(0.0))
+		})
+
+		It("should only share the time between buckets within the frame", func() {
+			// Half of the interaction is off the left edge of the frame.
+			shares := shareGrid(models.Waypoint{0, 18, 32, 18, 0.0}, models.Waypoint{0, 18, 32, 18, 2.0}, models.DefaultFrame)
+			Ω(shares[0][0]).Should(BeNumerically("~", 1.0, 0.0001))
+		})
+
+		It("should share the time along the path of interactions without a size", func() {
+			shares := shareGrid(models.Waypoint{10, 10, 0, 0, 0.0}, models.Waypoint{74, 10, 0, 0, 1.0}, models.DefaultFrame)
+			Ω(shares[0][0]).Should(BeNumerically("~", 54.0/64.0, 0.0001))
+			Ω(shares[1][0]).Should(BeNumerically("~", 10.0/64.0, 0.0001))
+
+			shares = shareGrid(models.Waypoint{70, 40, 0, 0, 0.0}, models.Waypoint{70, 40, 0, 0, 1.0}, models.DefaultFrame)
+			Ω(shares[1][1]).Should(Equal(1.0))
+		})
+
+		It("should share the time the same as testing every bucket", func() {
+			r := rand.New(rand.NewSource(1))
+			for _, f := range []models.Frame{models.DefaultFrame, models.Frame{640, 480, 64, 48}} {
+				for k := 0; k < 200; k++ {
+					a := randomWaypoint(r, f, 100)
+					b := randomWaypoint(r, f, 100)
+					if k%4 == 0 {
+						a.HalfWidthPixels, a.HalfHeightPixels, b.HalfWidthPixels, b.HalfHeightPixels = 0, 0, 0, 0
+					}
+
+					expected := testedShares(a, b, f)
+					shares := shareGrid(a, b, f)
+					for i := range expected {
+						for j := range expected[i] {
+							Ω(shares[i][j]).Should(BeNumerically("~", expected[i][j], 1e-9))
+						}
+					}
+				}
+			}
+		})
+	})
+
+	Context("updateTimeBuckets", func() {
+		It("should update the travel times for the buckets in a scout summary", func() {
+			// Moves right across the whole of bucket (0, 0) and bucket (1, 0) in two seconds.
+			ss := models.NewScoutSummary("", models.DefaultFrame)
+			si := &models.ScoutInteraction{-1, "", 2.0, models.Path{[2]int{32, 18}, [2]int{96, 18}},
+				models.Path{[2]int{32, 18}, [2]int{32, 18}}, models.RealArray{0.0, 2.0}, false, time.Time{}}
+			updateTimeBuckets(ss, si, models.DefaultFrame, models.DefaultPrivacy)
 
-			tBuckets := models.Buckets{}
+			tBuckets := models.NewBuckets(20, 20)
 			tBuckets[0][0] = 1.0
-			vBuckets := models.IntBuckets{}
+			tBuckets[1][0] = 1.0
+			vBuckets := models.NewIntBuckets(20, 20)
 			vBuckets[0][0] = 1
+			vBuckets[1][0] = 1
+
+			Ω(ss.VisitTimeBuckets).Should(Equal(tBuckets))
+			Ω(ss.VisitorBuckets).Should(Equal(vBuckets))
+		})
+
+		It("should accumulate the time of each segment that crosses a bucket", func() {
+			// Moves right in two seconds, then back again in two seconds and stays for three.
+			ss := models.NewScoutSummary("", models.DefaultFrame)
+			si := &models.ScoutInteraction{-1, "", 7.0,
+				models.Path{[2]int{32, 18}, [2]int{96, 18}, [2]int{32, 18}, [2]int{32, 18}},
+				models.Path{[2]int{32, 18}, [2]int{32, 18}, [2]int{32, 18}, [2]int{32, 18}},
+				models.RealArray{0.0, 2.0, 4.0, 7.0}, false, time.Time{}}
+			updateTimeBuckets(ss, si, models.DefaultFrame, models.DefaultPrivacy)
+
+			Ω(ss.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 5.0, 0.0001))
+			Ω(ss.VisitTimeBuckets[1][0]).Should(BeNumerically("~", 2.0, 0.0001))
+			Ω(ss.VisitorBuckets[0][0]).Should(Equal(1))
+			Ω(ss.VisitorBuckets[1][0]).Should(Equal(1))
+		})
+
+		It("should use the grid of the summary", func() {
+			// Moves right across a 640x480 frame broken into 4x2 buckets of 160x240.
+			ss := models.NewScoutSummary("", models.Frame{640, 480, 4, 2})
+			si := &models.ScoutInteraction{-1, "", 4.0, models.Path{[2]int{0, 100}, [2]int{640, 100}},
+				models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 4.0}, false, time.Time{}}
+			updateTimeBuckets(ss, si, models.Frame{640, 480, 20, 20}, models.DefaultPrivacy)
+
+			for i := 0; i < 4; i++ {
+				Ω(ss.VisitTimeBuckets[i][0]).Should(BeNumerically("~", 1.0, 0.0001))
+				Ω(ss.VisitorBuckets[i][0]).Should(Equal(1))
+				Ω(ss.VisitorBuckets[i][1]).Should(Equal(0))
+			}
+		})
+	})
+
+	Context("clipping", func() {
+		// Walks right across the middle of the first 10 buckets in 100 seconds.
+		si := &models.ScoutInteraction{-1, "", 100.0, models.Path{[2]int{32, 18}, [2]int{672, 18}},
+			models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 100.0}, false, time.Time{}}
+
+		It("should leave interactions as they are while no noise is added", func() {
+			ss := models.NewScoutSummary("", models.DefaultFrame)
+			updateTimeBuckets(ss, si, models.DefaultFrame, models.Privacy{0, 15, 0.0, 0.0, 10.0, 3})
+
+			visitors, total := 0, 0.0
+			for i := range ss.VisitorBuckets {
+				visitors += ss.VisitorBuckets[i][0]
+				total += float64(ss.VisitTimeBuckets[i][0])
+			}
+			Ω(visitors).Should(Equal(11))
+			Ω(total).Should(BeNumerically("~", 100.0, 0.001))
+		})
+
+		It("should clip the time and buckets of a visitor to the sensitivities of the noise", func() {
+			ss := models.NewScoutSummary("", models.DefaultFrame)
+			updateTimeBuckets(ss, si, models.DefaultFrame, models.Privacy{0, 15, 1.0, 10.0, 10.0, 3})
+
+			visitors, total := 0, 0.0
+			for i := range ss.VisitorBuckets {
+				visitors += ss.VisitorBuckets[i][0]
+				total += float64(ss.VisitTimeBuckets[i][0])
+			}
+			Ω(visitors).Should(Equal(3))
+			Ω(ss.VisitorBuckets[0][0]).Should(Equal(1))
+			Ω(ss.VisitorBuckets[1][0]).Should(Equal(1))
+			Ω(ss.VisitorBuckets[2][0]).Should(Equal(1))
+			Ω(total).Should(BeNumerically("~", 10.0, 0.001))
+		})
+	})
+
+	Context("addTimeBuckets", func() {
+		hourly := func(si *models.ScoutInteraction) map[time.Time]*models.ScoutSummary {
+			result := map[time.Time]*models.ScoutSummary{}
+			addTimeBuckets(si, models.DefaultFrame, models.DefaultPrivacy, time.Hour, func(hr time.Time) *models.ScoutSummary {
+				if _, ok := result[hr]; !ok {
+					result[hr] = models.NewScoutSummary("", models.DefaultFrame)
+				}
+
+				return result[hr]
+			})
+
+			return result
+		}
+
+		It("should put an interaction that entered at :53 in the hour it entered", func() {
+			et := time.Date(2016, 5, 12, 10, 53, 20, 0, time.UTC)
+			si := &models.ScoutInteraction{-1, "", 2.0, models.Path{[2]int{32, 18}, [2]int{96, 18}},
+				models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 2.0}, false, et}
+
+			hs := hourly(si)
+			Ω(len(hs)).Should(Equal(1))
+			ss, ok := hs[time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)]
+			Ω(ok).Should(BeTrue())
+			Ω(ss.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 1.0, 0.0001))
+			Ω(ss.VisitTimeBuckets[1][0]).Should(BeNumerically("~", 1.0, 0.0001))
+		})
+
+		It("should share the time of an interaction between the hours it spans", func() {
+			// Moves right from the middle of bucket (0, 0) to the middle of bucket (1, 0) in two
+			// seconds, crossing into the next hour and the next bucket after one second.
+			et := time.Date(2016, 5, 12, 10, 59, 59, 0, time.UTC)
+			si := &models.ScoutInteraction{-1, "", 2.0, models.Path{[2]int{32, 18}, [2]int{96, 18}},
+				models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 2.0}, false, et}
+
+			hs := hourly(si)
+			Ω(len(hs)).Should(Equal(2))
+
+			a := hs[time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)]
+			Ω(a.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 1.0, 0.0001))
+			Ω(a.VisitTimeBuckets[1][0]).Should(BeNumerically("~", 0.0, 0.0001))
+			Ω(a.VisitorBuckets[0][0]).Should(Equal(1))
+			Ω(a.VisitorBuckets[1][0]).Should(Equal(0))
+
+			b := hs[time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)]
+			Ω(b.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 0.0, 0.0001))
+			Ω(b.VisitTimeBuckets[1][0]).Should(BeNumerically("~", 1.0, 0.0001))
+			Ω(b.VisitorBuckets[0][0]).Should(Equal(0))
+			Ω(b.VisitorBuckets[1][0]).Should(Equal(1))
+		})
+
+		It("should count a visitor in each bucket once across the hours", func() {
+			// Stays in bucket (0, 0) from one hour into the next.
+			et := time.Date(2016, 5, 12, 10, 59, 58, 0, time.UTC)
+			si := &models.ScoutInteraction{-1, "", 4.0, models.Path{[2]int{30, 18}, [2]int{34, 18}},
+				models.Path{[2]int{0, 0}, [2]int{0, 0}}, models.RealArray{0.0, 4.0}, false, et}
 
-			Ω(ssA.VisitTimeBuckets).Should(Equal(tBuckets))
-			Ω(ssA.VisitorBuckets).Should(Equal(vBuckets))
+			hs := hourly(si)
+			a := hs[time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)]
+			b := hs[time.Date(2016, 5, 12, 11, 0, 0, 0, time.UTC)]
+			Ω(a.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 2.0, 0.0001))
+			Ω(b.VisitTimeBuckets[0][0]).Should(BeNumerically("~", 2.0, 0.0001))
+			Ω(a.VisitorBuckets[0][0]).Should(Equal(1))
+			Ω(b.VisitorBuckets[0][0]).Should(Equal(0))
 		})
 	})
 })
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	"time"
+)
+
+// crossing is a single crossing of a tripwire.
+type crossing struct {
+	At time.Time // When the tripwire was crossed.
+	In bool      // Was the tripwire crossed from left to right.
+}
+
+// crossTripwire returns each of the times the interaction si crossed the tripwire p->q.
+// Waypoints that lie on the tripwire keep the side the interaction was on before reaching it.
+func crossTripwire(p vec.Vec, q vec.Vec, si *models.ScoutInteraction) []crossing {
+	var result []crossing
+
+	from := 0
+	for k := 0; k < (len(si.Waypoints) - 1); k++ {
+		a := vec.Vec{si.Waypoints[k][0], si.Waypoints[k][1]}
+		b := vec.Vec{si.Waypoints[k+1][0], si.Waypoints[k+1][1]}
+		if s := vec.Side(p, q, a); s != 0 {
+			from = s
+		}
+
+		t, left, ok := vec.Crosses(a, b, p, q, from)
+		if !ok {
+			continue
+		}
+
+		dt := si.WaypointTimes[k+1] - si.WaypointTimes[k]
+		ct := si.WaypointTimes[k] + dt*float32(t)
+		at := si.EnteredAt.Add(time.Duration(float64(ct) * float64(time.Second)))
+		result = append(result, crossing{at, left})
+	}
+
+	return result
+}
+
+// updateTripwires counts the crossings made by the interaction si of each tripwire of its scout.
+// Only crossings between from and to are counted, a zero time leaves that end open.
+func updateTripwires(db models.Queryer, si *models.ScoutInteraction, from time.Time, to time.Time) error {
+	tripwires, err := models.GetTripwires(db, si.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, tw := range tripwires {
+		if len(tw.Segment) != 2 {
+			continue
+		}
+
+		p := vec.Vec{tw.Segment[0][0], tw.Segment[0][1]}
+		q := vec.Vec{tw.Segment[1][0], tw.Segment[1][1]}
+		for _, c := range crossTripwire(p, q, si) {
+			if (!from.IsZero() && c.At.Before(from)) || (!to.IsZero() && !c.At.Before(to)) {
+				continue
+			}
+
+			err = tw.AddCrossing(db, c.At, c.In)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
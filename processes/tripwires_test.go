@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestTripwires(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "tripwires Suite")
+}
+
+var _ = Describe("Tripwires", func() {
+	// A door across the middle of the frame.
+	p := vec.Vec{100, 200}
+	q := vec.Vec{300, 200}
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	Context("crossTripwire", func() {
+		It("should not count interactions that miss the tripwire", func() {
+			si := models.ScoutInteraction{4, "", 2.0, models.Path{[2]int{0, 300}, [2]int{50, 100}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}}, models.RealArray{0.0, 2.0}, false, t}
+
+			Ω(crossTripwire(p, q, &si)).Should(BeEmpty())
+		})
+
+		It("should count the direction and time of each crossing", func() {
+			si := models.ScoutInteraction{4, "", 6.0,
+				models.Path{[2]int{200, 300}, [2]int{200, 100}, [2]int{150, 300}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}},
+				models.RealArray{0.0, 2.0, 6.0}, false, t}
+
+			Ω(crossTripwire(p, q, &si)).Should(Equal([]crossing{
+				crossing{t.Add(time.Second), true},
+				crossing{t.Add(4 * time.Second), false}}))
+		})
+
+		It("should only count a crossing through a waypoint on the tripwire once", func() {
+			// Touches the tripwire and turns back, then walks through it at a waypoint on it.
+			si := models.ScoutInteraction{4, "", 8.0,
+				models.Path{[2]int{200, 300}, [2]int{200, 200}, [2]int{220, 300}, [2]int{250, 200}, [2]int{250, 100}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}},
+				models.RealArray{0.0, 2.0, 4.0, 6.0, 8.0}, false, t}
+
+			Ω(crossTripwire(p, q, &si)).Should(Equal([]crossing{crossing{t.Add(6 * time.Second), true}}))
+		})
+	})
+})
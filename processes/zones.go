@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	"time"
+)
+
+// visitZone works out how the interaction si passed through the polygon p. ok is false if the
+// interaction never entered the polygon.
+func visitZone(p vec.Polygon, si *models.ScoutInteraction) (v models.ZoneVisit, ok bool) {
+	offset := func(t float32) time.Time {
+		return si.EnteredAt.Add(time.Duration(float64(t) * float64(time.Second)))
+	}
+
+	// An interaction that never moved is either in the zone or not.
+	if len(si.Waypoints) == 1 {
+		if !p.Contains(vec.Vec{si.Waypoints[0][0], si.Waypoints[0][1]}) {
+			return v, false
+		}
+
+		v.InteractionId = si.Id
+		v.FirstEnteredAt = offset(si.WaypointTimes[0])
+		v.LastEnteredAt = v.FirstEnteredAt
+		return v, true
+	}
+
+	// Walk each segment of the interaction, accumulating the time spent inside the zone.
+	lastExit := float32(-1.0)
+	for k := 0; k < (len(si.Waypoints) - 1); k++ {
+		a := vec.Vec{si.Waypoints[k][0], si.Waypoints[k][1]}
+		b := vec.Vec{si.Waypoints[k+1][0], si.Waypoints[k+1][1]}
+		t0 := si.WaypointTimes[k]
+		dt := si.WaypointTimes[k+1] - t0
+
+		for _, inside := range p.ClipSegment(a, b) {
+			enter := t0 + dt*float32(inside[0])
+			exit := t0 + dt*float32(inside[1])
+
+			// Parts that continue on from the previous segment are the same entry.
+			if !ok || enter != lastExit {
+				if !ok {
+					v.FirstEnteredAt = offset(enter)
+				}
+				v.LastEnteredAt = offset(enter)
+				ok = true
+			}
+
+			v.DwellTime += exit - enter
+			lastExit = exit
+		}
+	}
+
+	v.InteractionId = si.Id
+	return v, ok
+}
+
+// updateZones adds the visits made by the interaction si to each of the zones of its scout.
+func updateZones(db models.Queryer, si *models.ScoutInteraction) error {
+	zones, err := models.GetZones(db, si.ScoutUUID)
+	if err != nil {
+		return err
+	}
+
+	for _, z := range zones {
+		v, ok := visitZone(vec.PolygonFromPath(z.Polygon), si)
+		if !ok {
+			continue
+		}
+
+		err = z.AddVisit(db, &v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
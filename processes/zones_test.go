@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package processes
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"github.com/MeasureTheFuture/scout/vec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestZones(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "zones Suite")
+}
+
+var _ = Describe("Zones", func() {
+	square := vec.Polygon{vec.Vec{100, 100}, vec.Vec{200, 100}, vec.Vec{200, 200}, vec.Vec{100, 200}}
+	t := time.Date(2016, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	Context("visitZone", func() {
+		It("should not visit zones the interaction doesn't pass through", func() {
+			si := models.ScoutInteraction{4, "", 2.0, models.Path{[2]int{0, 0}, [2]int{50, 300}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}}, models.RealArray{0.0, 2.0}, false, t}
+
+			_, ok := visitZone(square, &si)
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("should measure the time spent crossing a zone", func() {
+			si := models.ScoutInteraction{4, "", 4.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}}, models.RealArray{0.0, 3.0}, false, t}
+
+			v, ok := visitZone(square, &si)
+			Ω(ok).Should(BeTrue())
+			Ω(v.InteractionId).Should(Equal(int64(4)))
+			Ω(v.DwellTime).Should(BeNumerically("~", 1.0, 0.001))
+			Ω(v.FirstEnteredAt).Should(Equal(t.Add(time.Second)))
+			Ω(v.LastEnteredAt).Should(Equal(t.Add(time.Second)))
+		})
+
+		It("should treat a path that stays within the zone as a single entry", func() {
+			si := models.ScoutInteraction{4, "", 4.0,
+				models.Path{[2]int{0, 150}, [2]int{150, 150}, [2]int{150, 120}, [2]int{300, 120}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}},
+				models.RealArray{0.0, 1.5, 3.5, 5.0}, false, t}
+
+			v, ok := visitZone(square, &si)
+			Ω(ok).Should(BeTrue())
+			Ω(v.DwellTime).Should(BeNumerically("~", 3.0, 0.001))
+			Ω(v.FirstEnteredAt).Should(Equal(t.Add(time.Second)))
+			Ω(v.LastEnteredAt).Should(Equal(t.Add(time.Second)))
+		})
+
+		It("should record the last time an interaction entered the zone", func() {
+			si := models.ScoutInteraction{4, "", 6.0,
+				models.Path{[2]int{0, 150}, [2]int{300, 150}, [2]int{0, 150}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}, [2]int{1, 1}},
+				models.RealArray{0.0, 3.0, 6.0}, false, t}
+
+			v, ok := visitZone(square, &si)
+			Ω(ok).Should(BeTrue())
+			Ω(v.DwellTime).Should(BeNumerically("~", 2.0, 0.001))
+			Ω(v.FirstEnteredAt).Should(Equal(t.Add(time.Second)))
+			Ω(v.LastEnteredAt).Should(Equal(t.Add(4 * time.Second)))
+		})
+
+		It("should time visits from the exact time the interaction entered", func() {
+			et := time.Date(2016, 5, 12, 10, 53, 20, 0, time.UTC)
+			si := models.ScoutInteraction{4, "", 4.0, models.Path{[2]int{0, 150}, [2]int{300, 150}},
+				models.Path{[2]int{1, 1}, [2]int{1, 1}}, models.RealArray{0.0, 3.0}, false, et}
+
+			v, ok := visitZone(square, &si)
+			Ω(ok).Should(BeTrue())
+			Ω(v.FirstEnteredAt).Should(Equal(time.Date(2016, 5, 12, 10, 53, 21, 0, time.UTC)))
+		})
+
+		It("should visit zones that contain a stationary interaction", func() {
+			si := models.ScoutInteraction{4, "", 0.0, models.Path{[2]int{150, 150}},
+				models.Path{[2]int{1, 1}}, models.RealArray{0.5}, false, t}
+
+			v, ok := visitZone(square, &si)
+			Ω(ok).Should(BeTrue())
+			Ω(v.DwellTime).Should(Equal(float32(0.0)))
+			Ω(v.FirstEnteredAt).Should(Equal(t.Add(500 * time.Millisecond)))
+		})
+	})
+})
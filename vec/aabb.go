@@ -62,3 +62,22 @@ func (b *AABB) Intersects(a *AABB) bool {
 	return a.Max[0] >= b.Min[0] && a.Min[0] <= b.Max[0] &&
 		a.Max[1] >= b.Min[1] && a.Min[1] <= b.Max[1]
 }
+
+// Area returns the area of the bounding box in pixels squared.
+func (b *AABB) Area() int {
+	return Max(0, b.Max[0]-b.Min[0]) * Max(0, b.Max[1]-b.Min[1])
+}
+
+// IoU returns the area of the intersection between a and b divided by the area of their
+// union. Boxes that don't overlap return 0.0, identical boxes return 1.0.
+func (b *AABB) IoU(a *AABB) float64 {
+	i := AABB{Vec{Max(a.Min[0], b.Min[0]), Max(a.Min[1], b.Min[1])},
+		Vec{Min(a.Max[0], b.Max[0]), Min(a.Max[1], b.Max[1])}}
+	intersection := i.Area()
+	union := a.Area() + b.Area() - intersection
+	if union <= 0 {
+		return 0.0
+	}
+
+	return float64(intersection) / float64(union)
+}
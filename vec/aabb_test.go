@@ -79,4 +79,27 @@ var _ = Describe("AABB", func() {
 			Ω(d.Intersects(&a)).Should(BeFalse())
 		})
 	})
+
+	Context("Area", func() {
+		It("should return the area of the AABB", func() {
+			a := AABB{Vec{1, 2}, Vec{4, 6}}
+			b := AABB{Vec{1, 2}, Vec{1, 6}}
+
+			Ω(a.Area()).Should(Equal(12))
+			Ω(b.Area()).Should(Equal(0))
+		})
+	})
+
+	Context("IoU", func() {
+		It("should return the intersection over union of two AABBs", func() {
+			a := AABB{Vec{0, 0}, Vec{4, 4}}
+			b := AABB{Vec{2, 0}, Vec{6, 4}}
+			c := AABB{Vec{10, 10}, Vec{12, 12}}
+
+			Ω(a.IoU(&a)).Should(Equal(1.0))
+			Ω(a.IoU(&b)).Should(BeNumerically("~", 8.0/24.0, 0.0001))
+			Ω(b.IoU(&a)).Should(BeNumerically("~", 8.0/24.0, 0.0001))
+			Ω(a.IoU(&c)).Should(Equal(0.0))
+		})
+	})
 })
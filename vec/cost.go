@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+)
+
+// SizeChange returns how much the area of a differs from the area of b, from 0.0 when they
+// are the same size through to 1.0 when one of the boxes is empty.
+func SizeChange(a *AABB, b *AABB) float64 {
+	areaA := a.Area()
+	areaB := b.Area()
+	if Max(areaA, areaB) == 0 {
+		return 0.0
+	}
+
+	return 1.0 - float64(Min(areaA, areaB))/float64(Max(areaA, areaB))
+}
+
+// MatchCost builds the cost of pairing a detected waypoint with the predicted position of an
+// interaction, for a scene captured in frames maxW x maxH pixels. The cost mixes the squared
+// distance between the centroids with penalties for boxes that don't overlap and boxes that
+// change size, weighted by the CentroidWeight, IoUWeight and SizeWeight of the scout. The
+// overlap and size penalties are scaled by GateSqDistance, so a weight of 1.0 on a completely
+// mismatched box is enough to forbid the pairing.
+func MatchCost(s *models.Scout, maxW int, maxH int) models.CostFunc {
+	return func(detected models.Waypoint, predicted models.Waypoint) float64 {
+		a := AABBFromWaypoint(detected, maxW, maxH)
+		b := AABBFromWaypoint(predicted, maxW, maxH)
+
+		dx := float64(detected.XPixels - predicted.XPixels)
+		dy := float64(detected.YPixels - predicted.YPixels)
+		gate := float64(s.GateSqDistance)
+
+		return s.CentroidWeight*(dx*dx+dy*dy) +
+			s.IoUWeight*(1.0-a.IoU(&b))*gate +
+			s.SizeWeight*SizeChange(&a, &b)*gate
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cost", func() {
+	// A large blob and a small blob, with the small blob moving right next to the large one.
+	large := models.Waypoint{100, 100, 60, 60, 0.0}
+	small := models.Waypoint{180, 100, 10, 10, 0.0}
+	largeA := models.Waypoint{140, 100, 60, 60, 0.0}
+	smallA := models.Waypoint{110, 100, 10, 10, 0.0}
+
+	Context("SizeChange", func() {
+		It("should penalise boxes that change size", func() {
+			a := AABB{Vec{0, 0}, Vec{4, 4}}
+			b := AABB{Vec{0, 0}, Vec{2, 2}}
+			c := AABB{Vec{0, 0}, Vec{0, 0}}
+
+			Ω(SizeChange(&a, &a)).Should(Equal(0.0))
+			Ω(SizeChange(&a, &b)).Should(Equal(0.75))
+			Ω(SizeChange(&b, &a)).Should(Equal(0.75))
+			Ω(SizeChange(&a, &c)).Should(Equal(1.0))
+			Ω(SizeChange(&c, &c)).Should(Equal(0.0))
+		})
+	})
+
+	Context("MatchCost", func() {
+		It("should only use the centroid distance with the default weights", func() {
+			s := models.Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				CentroidWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			cost := MatchCost(&s, 1280, 720)
+
+			Ω(cost(large, smallA)).Should(Equal(100.0))
+			Ω(cost(large, largeA)).Should(Equal(1600.0))
+		})
+
+		It("should add the overlap and size penalties scaled by the gate", func() {
+			s := models.Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+				State: "idle", Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+				ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+				SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+				MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+				IoUWeight: 1.0, SizeWeight: 1.0, Masks: models.Masks{}, MaskCoverage: 0.5, DwellSqDistance: 400,
+				DwellDuration: 5.0, Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+			cost := MatchCost(&s, 1280, 720)
+
+			Ω(cost(large, large)).Should(Equal(0.0))
+			Ω(cost(small, models.Waypoint{500, 500, 10, 10, 0.0})).Should(Equal(40000.0))
+			Ω(cost(large, smallA)).Should(BeNumerically("~", 40000.0*(1.0-400.0/14400.0)*2.0, 0.01))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+)
+
+// Mask excludes detections that fall inside the exclusion polygons of a scout.
+type Mask struct {
+	Polygons []Polygon // Polygons are the areas of the frame to exclude.
+	Coverage float64   // Coverage is the fraction of a detection inside the polygons that excludes it.
+}
+
+// NewMask creates a mask from the exclusion polygons of the scout s.
+func NewMask(s *models.Scout) Mask {
+	polygons := make([]Polygon, len(s.Masks))
+	for i, p := range s.Masks {
+		polygons[i] = PolygonFromPath(p)
+	}
+
+	return Mask{polygons, s.MaskCoverage}
+}
+
+// Excludes returns true if the centroid of the waypoint w is inside the mask, or if at least
+// Coverage of the bounding box of w is inside the mask. A Coverage of zero or less only
+// checks the centroid. Overlapping polygons count towards the coverage more than once.
+func (m Mask) Excludes(w models.Waypoint) bool {
+	c := Vec{w.XPixels, w.YPixels}
+	for _, p := range m.Polygons {
+		if p.Contains(c) {
+			return true
+		}
+	}
+
+	b := AABB{Vec{w.XPixels - w.HalfWidthPixels, w.YPixels - w.HalfHeightPixels},
+		Vec{w.XPixels + w.HalfWidthPixels, w.YPixels + w.HalfHeightPixels}}
+	if m.Coverage <= 0.0 || b.Area() == 0 {
+		return false
+	}
+
+	covered := 0.0
+	for _, p := range m.Polygons {
+		covered += p.OverlapArea(&b)
+	}
+
+	return covered/float64(b.Area()) >= m.Coverage
+}
+
+// Filter returns the detected waypoints that are not excluded by the mask.
+func (m Mask) Filter(detected []models.Waypoint) []models.Waypoint {
+	if len(m.Polygons) == 0 {
+		return detected
+	}
+
+	result := []models.Waypoint{}
+	for _, w := range detected {
+		if !m.Excludes(w) {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
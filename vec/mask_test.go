@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mask", func() {
+	s := models.Scout{IpAddress: "192.168.0.1", Port: 8080, Authorised: true, Name: "foo",
+		State: "idle", Summary: &models.ScoutSummary{}, MinArea: 2.0, DilationIterations: 2,
+		ForegroundThresh: 2, GaussianSmooth: 2, MogHistoryLength: 2, MogThreshold: 2.0,
+		SimplifyEpsilon: 2.0, MinDuration: 0.2, IdleDuration: 0.3, ResumeSqDistance: 1, MaxArea: 4.0,
+		MatchStrategy: "optimal", GateSqDistance: 40000, ProcessNoise: 10000.0, MeasurementNoise: 100.0,
+		CentroidWeight: 1.0, MaskCoverage: 0.25, DwellSqDistance: 400, DwellDuration: 5.0,
+		Masks: models.Masks{models.Path{[2]int{0, 0}, [2]int{100, 0}, [2]int{100, 100}, [2]int{0, 100}}},
+		Frame: models.DefaultFrame, Privacy: models.DefaultPrivacy}
+
+	Context("Excludes", func() {
+		It("should exclude detections with a centroid inside the mask", func() {
+			m := NewMask(&s)
+			Ω(m.Excludes(models.Waypoint{50, 50, 10, 10, 0.0})).Should(BeTrue())
+			Ω(m.Excludes(models.Waypoint{95, 50, 40, 40, 0.0})).Should(BeTrue())
+		})
+
+		It("should exclude detections mostly covered by the mask", func() {
+			m := NewMask(&s)
+
+			// 30% of the box is inside the mask.
+			Ω(m.Excludes(models.Waypoint{104, 50, 10, 10, 0.0})).Should(BeTrue())
+
+			// 20% of the box is inside the mask.
+			Ω(m.Excludes(models.Waypoint{106, 50, 10, 10, 0.0})).Should(BeFalse())
+
+			m.Coverage = 0.0
+			Ω(m.Excludes(models.Waypoint{104, 50, 10, 10, 0.0})).Should(BeFalse())
+		})
+
+		It("should not exclude detections outside the mask", func() {
+			m := NewMask(&s)
+			Ω(m.Excludes(models.Waypoint{200, 200, 10, 10, 0.0})).Should(BeFalse())
+		})
+	})
+
+	Context("Filter", func() {
+		It("should drop the excluded detections", func() {
+			m := NewMask(&s)
+			a := models.Waypoint{50, 50, 10, 10, 0.0}
+			b := models.Waypoint{200, 200, 10, 10, 0.0}
+
+			Ω(m.Filter([]models.Waypoint{a, b})).Should(Equal([]models.Waypoint{b}))
+			Ω(Mask{}.Filter([]models.Waypoint{a, b})).Should(Equal([]models.Waypoint{a, b}))
+		})
+	})
+})
@@ -0,0 +1,219 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	"math"
+	"sort"
+)
+
+// Polygon is a closed polygon, the last vertex joins back to the first.
+type Polygon []Vec
+
+// PolygonFromPath creates a polygon from the vertices in the supplied path.
+func PolygonFromPath(p models.Path) Polygon {
+	result := make(Polygon, len(p))
+	for i, v := range p {
+		result[i] = Vec{v[0], v[1]}
+	}
+
+	return result
+}
+
+// containsF returns true if the point (x, y) is inside the polygon.
+func (p Polygon) containsF(x float64, y float64) bool {
+	inside := false
+
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi := float64(p[i][0]), float64(p[i][1])
+		xj, yj := float64(p[j][0]), float64(p[j][1])
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Contains returns true if v is inside the polygon.
+func (p Polygon) Contains(v Vec) bool {
+	return p.containsF(float64(v[0]), float64(v[1]))
+}
+
+// ClipSegment returns the parts of the segment a->b that are inside the polygon. Each part is
+// a pair of distances along the segment, from 0.0 at a through to 1.0 at b.
+func (p Polygon) ClipSegment(a Vec, b Vec) [][2]float64 {
+	ax, ay := float64(a[0]), float64(a[1])
+	dx, dy := float64(b[0]-a[0]), float64(b[1]-a[1])
+
+	// Split the segment everywhere it crosses an edge of the polygon.
+	splits := []float64{0.0, 1.0}
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		ex, ey := float64(p[i][0]-p[j][0]), float64(p[i][1]-p[j][1])
+		denom := dx*ey - dy*ex
+		if denom == 0.0 {
+			continue
+		}
+
+		qx, qy := float64(p[j][0])-ax, float64(p[j][1])-ay
+		t := (qx*ey - qy*ex) / denom
+		u := (qx*dy - qy*dx) / denom
+		if t > 0.0 && t < 1.0 && u >= 0.0 && u <= 1.0 {
+			splits = append(splits, t)
+		}
+	}
+	sort.Float64s(splits)
+
+	// Each piece is either completely inside or outside the polygon, so test the middle.
+	var result [][2]float64
+	for k := 0; k < len(splits)-1; k++ {
+		t0, t1 := splits[k], splits[k+1]
+		tm := (t0 + t1) / 2.0
+		if t1 <= t0 || !p.containsF(ax+dx*tm, ay+dy*tm) {
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1][1] == t0 {
+			result[n-1][1] = t1
+		} else {
+			result = append(result, [2]float64{t0, t1})
+		}
+	}
+
+	return result
+}
+
+// byXY sorts vertices from left to right, and bottom to top where they share an x-coordinate.
+type byXY []Vec
+
+func (v byXY) Len() int      { return len(v) }
+func (v byXY) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v byXY) Less(i, j int) bool {
+	return v[i][0] < v[j][0] || (v[i][0] == v[j][0] && v[i][1] < v[j][1])
+}
+
+// cross returns the cross product of o->a and o->b, which is positive when o, a and b turn
+// anti-clockwise (with y pointing up).
+func cross(o Vec, a Vec, b Vec) int {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+// ConvexHull returns the smallest convex polygon that contains all of the points in pts, using
+// Andrew's monotone chain. Points along the edges of the hull are left out.
+func ConvexHull(pts []Vec) Polygon {
+	sorted := append(byXY{}, pts...)
+	sort.Sort(sorted)
+	if len(sorted) < 3 {
+		return Polygon(sorted)
+	}
+
+	var hull Polygon
+	// Build the lower hull from left to right, then the upper hull from right to left.
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, v := range sorted {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], v) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, v)
+		}
+
+		// The last point of each half is the first point of the other.
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+
+	return hull
+}
+
+// Bounds returns the smallest AABB that contains the polygon.
+func (p Polygon) Bounds() AABB {
+	if len(p) == 0 {
+		return AABB{}
+	}
+
+	result := AABB{p[0], p[0]}
+	for _, v := range p[1:] {
+		result.Min = Vec{Min(result.Min[0], v[0]), Min(result.Min[1], v[1])}
+		result.Max = Vec{Max(result.Max[0], v[0]), Max(result.Max[1], v[1])}
+	}
+
+	return result
+}
+
+// area returns the area enclosed by the vertices in pts using the shoelace formula.
+func area(pts [][2]float64) float64 {
+	result := 0.0
+	for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+		result += pts[j][0]*pts[i][1] - pts[i][0]*pts[j][1]
+	}
+
+	return math.Abs(result) / 2.0
+}
+
+// Area returns the area of the polygon in pixels squared.
+func (p Polygon) Area() float64 {
+	pts := make([][2]float64, len(p))
+	for i, v := range p {
+		pts[i] = [2]float64{float64(v[0]), float64(v[1])}
+	}
+
+	return area(pts)
+}
+
+// clipEdge keeps the parts of the polygon pts that are inside the boundary x = bound (axis 0)
+// or y = bound (axis 1). Keep is the side of the boundary to hold on to, -1 below and 1 above.
+func clipEdge(pts [][2]float64, axis int, bound float64, keep float64) [][2]float64 {
+	var result [][2]float64
+	for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+		a, b := pts[j], pts[i]
+		aIn := (a[axis]-bound)*keep >= 0.0
+		bIn := (b[axis]-bound)*keep >= 0.0
+
+		if aIn != bIn {
+			t := (bound - a[axis]) / (b[axis] - a[axis])
+			result = append(result, [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t})
+		}
+
+		if bIn {
+			result = append(result, b)
+		}
+	}
+
+	return result
+}
+
+// OverlapArea returns the area of the polygon that is inside the bounding box b.
+func (p Polygon) OverlapArea(b *AABB) float64 {
+	pts := make([][2]float64, len(p))
+	for i, v := range p {
+		pts[i] = [2]float64{float64(v[0]), float64(v[1])}
+	}
+
+	// Clip the polygon against each side of the box in turn (Sutherland-Hodgman).
+	pts = clipEdge(pts, 0, float64(b.Min[0]), 1.0)
+	pts = clipEdge(pts, 0, float64(b.Max[0]), -1.0)
+	pts = clipEdge(pts, 1, float64(b.Min[1]), 1.0)
+	pts = clipEdge(pts, 1, float64(b.Max[1]), -1.0)
+
+	return area(pts)
+}
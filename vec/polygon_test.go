@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vec
+
+import (
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Polygon", func() {
+	square := Polygon{Vec{0, 0}, Vec{10, 0}, Vec{10, 10}, Vec{0, 10}}
+
+	// A 'U' shape, open at the top.
+	u := Polygon{Vec{0, 0}, Vec{30, 0}, Vec{30, 20}, Vec{20, 20}, Vec{20, 10}, Vec{10, 10}, Vec{10, 20}, Vec{0, 20}}
+
+	Context("PolygonFromPath", func() {
+		It("should create a polygon from a path", func() {
+			Ω(PolygonFromPath(models.Path{[2]int{0, 0}, [2]int{10, 0}, [2]int{10, 10}, [2]int{0, 10}})).Should(Equal(square))
+		})
+	})
+
+	Context("Contains", func() {
+		It("should return true for points inside the polygon", func() {
+			Ω(square.Contains(Vec{5, 5})).Should(BeTrue())
+			Ω(u.Contains(Vec{5, 15})).Should(BeTrue())
+			Ω(u.Contains(Vec{25, 15})).Should(BeTrue())
+		})
+
+		It("should return false for points outside the polygon", func() {
+			Ω(square.Contains(Vec{15, 5})).Should(BeFalse())
+			Ω(square.Contains(Vec{-1, -1})).Should(BeFalse())
+			Ω(u.Contains(Vec{15, 15})).Should(BeFalse())
+		})
+	})
+
+	Context("ClipSegment", func() {
+		It("should return the whole segment when it is inside the polygon", func() {
+			Ω(square.ClipSegment(Vec{2, 2}, Vec{8, 8})).Should(Equal([][2]float64{[2]float64{0.0, 1.0}}))
+		})
+
+		It("should return nothing when the segment is outside the polygon", func() {
+			Ω(square.ClipSegment(Vec{20, 2}, Vec{28, 8})).Should(BeEmpty())
+		})
+
+		It("should return the part of a segment that crosses the polygon", func() {
+			Ω(square.ClipSegment(Vec{-10, 5}, Vec{10, 5})).Should(Equal([][2]float64{[2]float64{0.5, 1.0}}))
+			Ω(square.ClipSegment(Vec{-5, 5}, Vec{15, 5})).Should(Equal([][2]float64{[2]float64{0.25, 0.75}}))
+		})
+
+		It("should return each part of a segment that enters the polygon more than once", func() {
+			Ω(u.ClipSegment(Vec{-10, 15}, Vec{40, 15})).Should(Equal([][2]float64{
+				[2]float64{0.2, 0.4}, [2]float64{0.6, 0.8}}))
+		})
+	})
+	Context("Bounds", func() {
+		It("should return the extents of the polygon", func() {
+			Ω(u.Bounds()).Should(Equal(AABB{Vec{0, 0}, Vec{30, 20}}))
+		})
+	})
+
+	Context("Area", func() {
+		It("should return the area of the polygon", func() {
+			Ω(square.Area()).Should(BeNumerically("~", 100.0, 0.001))
+			Ω(u.Area()).Should(BeNumerically("~", 500.0, 0.001))
+		})
+	})
+
+	Context("OverlapArea", func() {
+		It("should return the area of the polygon inside a box", func() {
+			b := AABB{Vec{5, 5}, Vec{15, 15}}
+			Ω(square.OverlapArea(&b)).Should(BeNumerically("~", 25.0, 0.001))
+
+			b = AABB{Vec{-5, -5}, Vec{15, 15}}
+			Ω(square.OverlapArea(&b)).Should(BeNumerically("~", 100.0, 0.001))
+
+			b = AABB{Vec{0, 5}, Vec{30, 15}}
+			Ω(u.OverlapArea(&b)).Should(BeNumerically("~", 250.0, 0.001))
+		})
+
+		It("should return zero when the polygon is outside the box", func() {
+			b := AABB{Vec{20, 20}, Vec{30, 30}}
+			Ω(square.OverlapArea(&b)).Should(BeNumerically("~", 0.0, 0.001))
+		})
+	})
+
+	Context("ConvexHull", func() {
+		It("should return the outline of a set of points", func() {
+			h := ConvexHull([]Vec{Vec{0, 0}, Vec{5, 5}, Vec{10, 0}, Vec{10, 10}, Vec{0, 10}, Vec{5, 0}})
+			Ω(h).Should(Equal(Polygon{Vec{0, 0}, Vec{10, 0}, Vec{10, 10}, Vec{0, 10}}))
+		})
+
+		It("should handle points in a line", func() {
+			h := ConvexHull([]Vec{Vec{10, 0}, Vec{0, 0}, Vec{5, 0}})
+			Ω(h).Should(Equal(Polygon{Vec{0, 0}, Vec{10, 0}}))
+			Ω(h.Area()).Should(BeNumerically("~", 0.0, 0.001))
+		})
+	})
+})
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+import (
+	"math"
+)
+
+// floorDiv returns a / b rounded down, for b > 0.
+func floorDiv(a int, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// ceilDiv returns a / b rounded up, for b > 0.
+func ceilDiv(a int, b int) int {
+	return -floorDiv(-a, b)
+}
+
+// touching returns the range of buckets, each size wide, that touch the span from min to max,
+// limited to the n buckets from zero.
+func touching(min int, max int, size int, n int) (int, int) {
+	return Max(0, ceilDiv(min-size, size)), Min(n-1, floorDiv(max, size))
+}
+
+// Buckets calls visit with the index of each bucket that the shaft intersects, for a grid of
+// wBuckets by hBuckets buckets that are bw by bh pixels. The buckets are exactly those that
+// Intersects returns true for, but rather than testing every bucket, the rows that the shaft
+// covers within each column are worked out from the planes of the shaft.
+func (s *Shaft) Buckets(bw int, bh int, wBuckets int, hBuckets int, visit func(i int, j int)) {
+	iMin, iMax := touching(s.Bounds.Min[0], s.Bounds.Max[0], bw, wBuckets)
+	jMin, jMax := touching(s.Bounds.Min[1], s.Bounds.Max[1], bh, hBuckets)
+
+	minD := Vec{s.MinPlane[1][0] - s.MinPlane[0][0], s.MinPlane[1][1] - s.MinPlane[0][1]}
+	maxD := Vec{s.MaxPlane[1][0] - s.MaxPlane[0][0], s.MaxPlane[1][1] - s.MaxPlane[0][1]}
+
+	for i := iMin; i <= iMax; i++ {
+		lo, hi := jMin, jMax
+
+		// A bucket is outside when both of its right corners are left of MinPlane. Along the
+		// right edge of the column, a point is left of the plane when dx * y > c.
+		c := minD[0]*s.MinPlane[0][1] + minD[1]*((i+1)*bw-s.MinPlane[0][0])
+		switch {
+		case minD[0] > 0:
+			hi = Min(hi, floorDiv(c, bh*minD[0]))
+		case minD[0] < 0:
+			lo = Max(lo, ceilDiv(-c, -bh*minD[0])-1)
+		case c < 0:
+			continue
+		}
+
+		// A bucket is outside when both of its left corners are not left of MaxPlane.
+		c = maxD[0]*s.MaxPlane[0][1] + maxD[1]*(i*bw-s.MaxPlane[0][0])
+		switch {
+		case maxD[0] > 0:
+			lo = Max(lo, floorDiv(c, bh*maxD[0]))
+		case maxD[0] < 0:
+			hi = Min(hi, ceilDiv(-c, -bh*maxD[0])-1)
+		case c >= 0:
+			continue
+		}
+
+		for j := lo; j <= hi; j++ {
+			visit(i, j)
+		}
+	}
+}
+
+// Buckets calls visit with the index of each bucket that the convex polygon touches, for a grid
+// of wBuckets by hBuckets buckets that are bw by bh pixels. The rows that the polygon covers are
+// found for each column from the edges that cross it, rather than testing every bucket.
+func (p Polygon) Buckets(bw int, bh int, wBuckets int, hBuckets int, visit func(i int, j int)) {
+	if len(p) == 0 {
+		return
+	}
+
+	bounds := p.Bounds()
+	iMin, iMax := touching(bounds.Min[0], bounds.Max[0], bw, wBuckets)
+
+	for i := iMin; i <= iMax; i++ {
+		xl, xr := float64(i*bw), float64((i+1)*bw)
+		yMin, yMax := math.Inf(1), math.Inf(-1)
+
+		// The part of a convex polygon within a column is bounded by its edges, clipped to
+		// the column.
+		for k, l := 0, len(p)-1; k < len(p); l, k = k, k+1 {
+			a := [2]float64{float64(p[l][0]), float64(p[l][1])}
+			b := [2]float64{float64(p[k][0]), float64(p[k][1])}
+			if a[0] > b[0] {
+				a, b = b, a
+			}
+
+			x0, x1 := math.Max(a[0], xl), math.Min(b[0], xr)
+			if x0 > x1 {
+				continue
+			}
+
+			for _, x := range []float64{x0, x1} {
+				y := a[1]
+				if b[0] > a[0] {
+					y += (b[1] - a[1]) * (x - a[0]) / (b[0] - a[0])
+				}
+
+				yMin, yMax = math.Min(yMin, y), math.Max(yMax, y)
+			}
+		}
+
+		if yMin > yMax {
+			continue
+		}
+
+		jMin, jMax := touching(int(math.Floor(yMin)), int(math.Ceil(yMax)), bh, hBuckets)
+		for j := jMin; j <= jMax; j++ {
+			visit(i, j)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+import (
+	"fmt"
+	"github.com/MeasureTheFuture/scout/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"math/rand"
+	"testing"
+)
+
+// grid is the size of the buckets, and the number of them, that a frame is broken into.
+type grid struct {
+	bw, bh, w, h int
+}
+
+// randomShaft returns a shaft between two random waypoints within (or just off) a frame.
+func randomShaft(r *rand.Rand, w int, h int) Shaft {
+	wp := func() models.Waypoint {
+		return models.Waypoint{r.Intn(w+100) - 50, r.Intn(h+100) - 50, r.Intn(100), r.Intn(100), 0.0}
+	}
+
+	return ShaftFromWaypoints(wp(), wp(), w, h)
+}
+
+// rasterised returns the buckets visited by raster.
+func rasterised(raster func(bw int, bh int, w int, h int, visit func(i int, j int)), g grid) map[[2]int]bool {
+	result := map[[2]int]bool{}
+	raster(g.bw, g.bh, g.w, g.h, func(i int, j int) {
+		Ω(result[[2]int{i, j}]).Should(BeFalse())
+		result[[2]int{i, j}] = true
+	})
+
+	return result
+}
+
+// intersected returns the buckets that the shaft s intersects, by testing each of them.
+func intersected(s *Shaft, g grid) map[[2]int]bool {
+	result := map[[2]int]bool{}
+	for i := 0; i < g.w; i++ {
+		for j := 0; j < g.h; j++ {
+			b := AABBFromIndex(i, j, g.bw, g.bh)
+			if s.Intersects(&b) {
+				result[[2]int{i, j}] = true
+			}
+		}
+	}
+
+	return result
+}
+
+var _ = Describe("Raster", func() {
+	Context("floorDiv and ceilDiv", func() {
+		It("should round towards negative and positive infinity", func() {
+			Ω(floorDiv(7, 2)).Should(Equal(3))
+			Ω(floorDiv(-7, 2)).Should(Equal(-4))
+			Ω(floorDiv(-8, 2)).Should(Equal(-4))
+			Ω(ceilDiv(7, 2)).Should(Equal(4))
+			Ω(ceilDiv(-7, 2)).Should(Equal(-3))
+			Ω(ceilDiv(8, 2)).Should(Equal(4))
+		})
+	})
+
+	Context("Shaft.Buckets", func() {
+		It("should visit the same buckets that the shaft intersects on the shaft fixtures", func() {
+			wpA := models.Waypoint{5, 5, 2, 4, 0.0}
+			wpB := models.Waypoint{2, 3, 2, 2, 0.0}
+			wpC := models.Waypoint{5, 1, 2, 1, 0.0}
+			wpD := models.Waypoint{2, 2, 2, 1, 0.0}
+
+			for _, s := range []Shaft{ShaftFromWaypoints(wpA, wpB, 10, 10), ShaftFromWaypoints(wpB, wpA, 10, 10),
+				ShaftFromWaypoints(wpC, wpD, 10, 10), ShaftFromWaypoints(wpD, wpC, 10, 10)} {
+				for _, g := range []grid{grid{1, 1, 10, 10}, grid{2, 3, 5, 4}, grid{3, 2, 4, 5}} {
+					Ω(rasterised(s.Buckets, g)).Should(Equal(intersected(&s, g)))
+				}
+			}
+		})
+
+		It("should visit the same buckets that the shaft intersects for any shaft", func() {
+			r := rand.New(rand.NewSource(1))
+			for _, g := range []grid{grid{64, 36, 20, 20}, grid{20, 20, 64, 36}, grid{7, 5, 183, 144}} {
+				for k := 0; k < 500; k++ {
+					s := randomShaft(r, g.bw*g.w, g.bh*g.h)
+					Ω(rasterised(s.Buckets, g)).Should(Equal(intersected(&s, g)))
+				}
+			}
+		})
+	})
+
+	Context("Polygon.Buckets", func() {
+		It("should visit every bucket the polygon covers, and only those it touches", func() {
+			r := rand.New(rand.NewSource(1))
+			for _, g := range []grid{grid{64, 36, 20, 20}, grid{20, 20, 64, 36}} {
+				for k := 0; k < 200; k++ {
+					a := models.Waypoint{r.Intn(1380) - 50, r.Intn(820) - 50, r.Intn(100), r.Intn(100), 0.0}
+					b := models.Waypoint{r.Intn(1380) - 50, r.Intn(820) - 50, r.Intn(100), r.Intn(100), 0.0}
+					p := SweptPolygon(a, b)
+					bounds := p.Bounds()
+
+					visited := rasterised(p.Buckets, g)
+					for i := 0; i < g.w; i++ {
+						for j := 0; j < g.h; j++ {
+							bucket := AABBFromIndex(i, j, g.bw, g.bh)
+							if p.OverlapArea(&bucket) > 0.0 {
+								Ω(visited[[2]int{i, j}]).Should(BeTrue())
+							}
+							if visited[[2]int{i, j}] {
+								Ω(bucket.Intersects(&bounds)).Should(BeTrue())
+							}
+						}
+					}
+				}
+			}
+		})
+
+		It("should visit the buckets along a line or around a point", func() {
+			line := Polygon{Vec{10, 10}, Vec{74, 10}}
+			Ω(rasterised(line.Buckets, grid{64, 36, 20, 20})).Should(Equal(map[[2]int]bool{
+				[2]int{0, 0}: true, [2]int{1, 0}: true}))
+
+			point := Polygon{Vec{70, 40}}
+			Ω(rasterised(point.Buckets, grid{64, 36, 20, 20})).Should(Equal(map[[2]int]bool{
+				[2]int{1, 1}: true}))
+		})
+	})
+})
+
+// BenchmarkShaftBuckets compares rasterising shafts against testing every bucket, on grids from
+// the default to one bucket per 10 pixels of a 1280x720 frame.
+func BenchmarkShaftBuckets(b *testing.B) {
+	for _, g := range []grid{grid{64, 36, 20, 20}, grid{20, 20, 64, 36}, grid{10, 10, 128, 72}} {
+		r := rand.New(rand.NewSource(1))
+		shafts := make([]Shaft, 100)
+		for k := range shafts {
+			shafts[k] = randomShaft(r, 1280, 720)
+		}
+
+		b.Run(fmt.Sprintf("%dx%d/rasterised", g.w, g.h), func(b *testing.B) {
+			n := 0
+			for k := 0; k < b.N; k++ {
+				s := shafts[k%len(shafts)]
+				s.Buckets(g.bw, g.bh, g.w, g.h, func(i int, j int) { n++ })
+			}
+		})
+
+		b.Run(fmt.Sprintf("%dx%d/tested", g.w, g.h), func(b *testing.B) {
+			n := 0
+			for k := 0; k < b.N; k++ {
+				s := shafts[k%len(shafts)]
+				for i := 0; i < g.w; i++ {
+					for j := 0; j < g.h; j++ {
+						bucket := AABBFromIndex(i, j, g.bw, g.bh)
+						if s.Intersects(&bucket) {
+							n++
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+// side returns which side of the line p->q that v lies on. Points to the left are
+// positive, points to the right are negative and points on the line are zero.
+func side(p Vec, q Vec, v Vec) int {
+	return (q[0]-p[0])*(v[1]-p[1]) - (q[1]-p[1])*(v[0]-p[0])
+}
+
+// Side returns 1 when v lies to the left of the line p->q, -1 when it lies to the right and 0
+// when it lies on the line.
+func Side(p Vec, q Vec, v Vec) int {
+	s := side(p, q, v)
+	if s > 0 {
+		return 1
+	} else if s < 0 {
+		return -1
+	}
+
+	return 0
+}
+
+// Crosses works out if the segment a->b crosses the line segment p->q, coming from the side
+// of p->q given by from (see Side). from is the side of a, or when a lies on p->q, the side the
+// path was on before it reached the line. When it does, t is the distance along a->b of the
+// crossing, from 0.0 at a through to 1.0 at b, and left is true if a->b crosses from the left
+// of p->q to its right. Points that lie on p->q are on neither side: a segment that ends on the
+// line doesn't cross it, so a path that touches the line and turns back is never counted, and a
+// path through a point on the line is counted once, as it leaves for the other side.
+func Crosses(a Vec, b Vec, p Vec, q Vec, from int) (t float64, left bool, ok bool) {
+	to := Side(p, q, b)
+	if from == 0 || to == 0 || to == from {
+		return 0.0, false, false
+	}
+
+	// The ends of p->q must be on either side of a->b (or touching it).
+	sp := side(a, b, p)
+	sq := side(a, b, q)
+	if (sp > 0 && sq > 0) || (sp < 0 && sq < 0) {
+		return 0.0, false, false
+	}
+
+	sa := side(p, q, a)
+	sb := side(p, q, b)
+	return float64(sa) / float64(sa-sb), from > 0, true
+}
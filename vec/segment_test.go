@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2016 Clinton Freeman
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package vec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Segment", func() {
+	// A horizontal line, with 'left' being below it in image coordinates.
+	p := Vec{0, 10}
+	q := Vec{20, 10}
+
+	Context("Crosses", func() {
+		It("should detect a segment crossing from the left", func() {
+			t, left, ok := Crosses(Vec{5, 20}, Vec{5, 0}, p, q, 1)
+			Ω(ok).Should(BeTrue())
+			Ω(left).Should(BeTrue())
+			Ω(t).Should(BeNumerically("~", 0.5, 0.0001))
+		})
+
+		It("should detect a segment crossing from the right", func() {
+			t, left, ok := Crosses(Vec{5, 5}, Vec{5, 25}, p, q, -1)
+			Ω(ok).Should(BeTrue())
+			Ω(left).Should(BeFalse())
+			Ω(t).Should(BeNumerically("~", 0.25, 0.0001))
+		})
+
+		It("should ignore segments that don't cross", func() {
+			_, _, ok := Crosses(Vec{5, 5}, Vec{15, 5}, p, q, -1)
+			Ω(ok).Should(BeFalse())
+
+			// Crosses the line beyond the end of the segment.
+			_, _, ok = Crosses(Vec{30, 0}, Vec{30, 20}, p, q, -1)
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("should not count a path that touches the line and turns back", func() {
+			_, _, ok := Crosses(Vec{5, 20}, Vec{5, 10}, p, q, 1)
+			Ω(ok).Should(BeFalse())
+
+			_, _, ok = Crosses(Vec{5, 10}, Vec{5, 20}, p, q, 1)
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("should count a path through a point on the line once", func() {
+			_, _, ok := Crosses(Vec{5, 20}, Vec{5, 10}, p, q, 1)
+			Ω(ok).Should(BeFalse())
+
+			t, left, ok := Crosses(Vec{5, 10}, Vec{5, 0}, p, q, 1)
+			Ω(ok).Should(BeTrue())
+			Ω(left).Should(BeTrue())
+			Ω(t).Should(BeNumerically("~", 0.0, 0.0001))
+		})
+
+		It("should not count a path around the end of the line", func() {
+			_, _, ok := Crosses(Vec{30, 10}, Vec{30, 0}, p, q, 1)
+			Ω(ok).Should(BeFalse())
+		})
+	})
+})
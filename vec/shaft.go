@@ -67,6 +67,21 @@ func ShaftFromWaypoints(a models.Waypoint, b models.Waypoint, maxW int, maxH int
 	}
 }
 
+// SweptPolygon returns the area covered by the box around a waypoint as it travels from a to b,
+// which is the convex hull of the boxes around a and b.
+func SweptPolygon(a models.Waypoint, b models.Waypoint) Polygon {
+	var corners []Vec
+	for _, w := range []models.Waypoint{a, b} {
+		corners = append(corners,
+			Vec{w.XPixels - w.HalfWidthPixels, w.YPixels - w.HalfHeightPixels},
+			Vec{w.XPixels + w.HalfWidthPixels, w.YPixels - w.HalfHeightPixels},
+			Vec{w.XPixels + w.HalfWidthPixels, w.YPixels + w.HalfHeightPixels},
+			Vec{w.XPixels - w.HalfWidthPixels, w.YPixels + w.HalfHeightPixels})
+	}
+
+	return ConvexHull(corners)
+}
+
 func isLeft(line *[2]Vec, p Vec) bool {
 	return ((line[1][0]-line[0][0])*(p[1]-line[0][1]) -
 		(line[1][1]-line[0][1])*(p[0]-line[0][0])) > 0
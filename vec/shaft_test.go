@@ -96,6 +96,22 @@ var _ = Describe("Shaft", func() {
 		})
 	})
 
+	Context("SweptPolygon", func() {
+		It("should cover the boxes at each end and the area between them", func() {
+			a := models.Waypoint{32, 18, 32, 18, 0.0}
+			b := models.Waypoint{96, 54, 32, 18, 1.0}
+
+			p := SweptPolygon(a, b)
+			Ω(p).Should(Equal(Polygon{Vec{0, 0}, Vec{64, 0}, Vec{128, 36}, Vec{128, 72}, Vec{64, 72}, Vec{0, 36}}))
+			Ω(p.Area()).Should(BeNumerically("~", 6912.0, 0.001))
+		})
+
+		It("should be the box of a waypoint that doesn't move", func() {
+			a := models.Waypoint{10, 10, 5, 5, 0.0}
+			Ω(SweptPolygon(a, a).Area()).Should(BeNumerically("~", 100.0, 0.001))
+		})
+	})
+
 	Context("isLeft", func() {
 		It("Should return true if a point is left of a line", func() {
 			l := &[2]Vec{Vec{7, 1}, Vec{4, 3}}